@@ -41,10 +41,22 @@ type Claims struct {
 	UserName    string              `json:"preferred_username"`
 	Email       string              `json:"email"`
 	RealmAccess map[string][]string `json:"realm_access"`
+	Roles       []string            `json:"roles,omitempty"`
 	Audience    Audience            `json:"aud,omitempty"`
 	jwt.StandardClaims
 }
 
+// Roles from both keycloak style realm_access and top level roles claim
+func (cl *Claims) roles() []string {
+	roles := append([]string{}, cl.RealmAccess["roles"]...)
+	for _, r := range cl.Roles {
+		if !overlap(roles, []string{r}) {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
+
 func overlap(s1 []string, s2 []string) bool {
 	for _, x := range s1 {
 		for _, y := range s2 {
@@ -86,6 +98,10 @@ func Authorize(strategy AuthStrategy) echo.MiddlewareFunc {
 				return c.JSON(http.StatusInternalServerError, err.Error())
 			}
 
+			// expose claims to handlers, sub is used to audit which user submitted a job
+			c.Request().Header.Set("X-ProcessAPI-User-ID", claims.Subject)
+			c.Set("claims", claims)
+
 			return next(c)
 		}
 	}
@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+// OIDCAuthStrategy implements AuthStrategy for a generic OIDC provider.
+// Tokens are validated against the keys published at a JWKS URL,
+// and optionally against the expected issuer and audience.
+type OIDCAuthStrategy struct {
+	PublicKeys      map[string]*rsa.PublicKey
+	Mutex           sync.RWMutex
+	JWKSURL         string
+	Issuer          string
+	Audience        string
+	ServiceRoleName string
+}
+
+// NewOIDCAuthStrategy creates a new instance of OIDCAuthStrategy and
+// starts a background process to refresh the public keys periodically.
+func NewOIDCAuthStrategy() (*OIDCAuthStrategy, error) {
+	strategy := &OIDCAuthStrategy{
+		PublicKeys:      make(map[string]*rsa.PublicKey),
+		JWKSURL:         os.Getenv("OIDC_JWKS_URL"),
+		Issuer:          os.Getenv("OIDC_ISSUER"),
+		Audience:        os.Getenv("OIDC_AUDIENCE"),
+		ServiceRoleName: os.Getenv("AUTH_SERVICE_ROLE"),
+	}
+
+	if strategy.JWKSURL == "" {
+		return nil, errors.New("env variable OIDC_JWKS_URL not set")
+	}
+
+	err := strategy.LoadPublicKeys()
+	if err != nil {
+		return nil, err
+	}
+	go strategy.refreshKeysPeriodically(24 * time.Hour)
+	return strategy, nil
+}
+
+// refreshKeysPeriodically runs in a goroutine and periodically refreshes
+// the public keys used for token validation.
+func (oas *OIDCAuthStrategy) refreshKeysPeriodically(duration time.Duration) {
+	for {
+		err := oas.LoadPublicKeys()
+		if err != nil {
+			log.Errorf("Error refreshing public keys: %v\n", err)
+			time.Sleep(10 * time.Minute) // Retry after a delay in case of failure
+			continue
+		}
+		time.Sleep(duration)
+	}
+}
+
+// LoadPublicKeys fetches the RSA public keys from the JWKS URL.
+// This method is thread-safe and can be called concurrently.
+func (oas *OIDCAuthStrategy) LoadPublicKeys() error {
+	r, err := http.Get(oas.JWKSURL)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	var target map[string][]PublicKey
+	if err = json.NewDecoder(r.Body).Decode(&target); err != nil {
+		return err
+	}
+
+	newKeys := make(map[string]*rsa.PublicKey)
+	for _, key := range target["keys"] {
+		if key.Kty != "RSA" {
+			continue
+		}
+		pk, err := rsaPublicKey(key)
+		if err != nil {
+			log.Warnf("Skipping public key %s: %s", key.Kid, err.Error())
+			continue
+		}
+		newKeys[key.Kid] = pk
+	}
+
+	oas.Mutex.Lock()
+	defer oas.Mutex.Unlock()
+	oas.PublicKeys = newKeys
+	return nil
+}
+
+// Build RSA public key from the base64url encoded modulus and exponent of a JWK
+func rsaPublicKey(key PublicKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(key.N)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode modulus: %s", err.Error())
+	}
+	e, err := base64.RawURLEncoding.DecodeString(key.E)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode exponent: %s", err.Error())
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
+
+func (oas *OIDCAuthStrategy) getPublicKey(kid string) *rsa.PublicKey {
+	oas.Mutex.RLock()
+	defer oas.Mutex.RUnlock()
+
+	return oas.PublicKeys[kid]
+}
+
+// ValidateToken verifies the signature, expiry, issuer and audience of the token
+func (oas *OIDCAuthStrategy) ValidateToken(tokenString string) (*Claims, error) {
+	var claims Claims
+	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		pk := oas.getPublicKey(kid)
+		if pk == nil {
+			return nil, fmt.Errorf("public key not found")
+		}
+		return pk, nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT: %v", err)
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid JWT")
+	}
+
+	if oas.Issuer != "" && claims.Issuer != oas.Issuer {
+		return nil, fmt.Errorf("invalid JWT issuer")
+	}
+	if oas.Audience != "" && !overlap(claims.Audience, []string{oas.Audience}) {
+		return nil, fmt.Errorf("invalid JWT audience")
+	}
+
+	return &claims, nil
+}
+
+// Validate X-ProcessAPI-User-Email header against user from claims.
+// If header is not provided, it is set from claims.
+func (oas *OIDCAuthStrategy) ValidateUser(c echo.Context, claims *Claims) (err error) {
+	if oas.ServiceRoleName != "" && overlap(claims.roles(), []string{oas.ServiceRoleName}) {
+		// assume provided header is correct
+		return nil
+	}
+
+	email := c.Request().Header.Get("X-ProcessAPI-User-Email")
+	if email == "" {
+		c.Request().Header.Set("X-ProcessAPI-User-Email", claims.Email)
+	} else if email != claims.Email {
+		return fmt.Errorf("invalid X-ProcessAPI-User-Email header")
+	}
+
+	return nil
+}
+
+// Set user roles to API Header
+func (oas *OIDCAuthStrategy) SetUserRolesHeader(c echo.Context, claims *Claims) (err error) {
+	c.Request().Header.Set("X-ProcessAPI-User-Roles", strings.Join(claims.roles(), ","))
+	return nil
+}
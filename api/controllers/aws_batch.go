@@ -7,7 +7,6 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/batch"
 )
@@ -68,14 +67,14 @@ func getResourceRequirement(resourceRequirements []*batch.ResourceRequirement, n
 	return ""
 }
 
-func NewAWSBatchController(accessKey, secretAccessKey, region string) (*AWSBatchController, error) {
-	sess, err := session.NewSession(&aws.Config{
-		Credentials: credentials.NewStaticCredentialsFromCreds(credentials.Value{
-			AccessKeyID:     accessKey,
-			SecretAccessKey: secretAccessKey,
-		}),
-		Region: aws.String(region)},
-	)
+// Credentials are resolved with the default AWS credential chain, env variables, shared config, or the IAM role of the instance or task.
+// Empty region falls back to the region resolved by the SDK.
+func NewAWSBatchController(region string) (*AWSBatchController, error) {
+	cfg := aws.Config{}
+	if region != "" {
+		cfg.Region = aws.String(region)
+	}
+	sess, err := session.NewSession(&cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -83,10 +82,17 @@ func NewAWSBatchController(accessKey, secretAccessKey, region string) (*AWSBatch
 	return &AWSBatchController{batch.New(sess)}, nil
 }
 
+// Check if Batch API is reachable with the configured credentials
+func (c *AWSBatchController) Ping(ctx context.Context) error {
+	_, err := c.client.DescribeJobQueuesWithContext(ctx, &batch.DescribeJobQueuesInput{MaxResults: aws.Int64(1)})
+	return err
+}
+
 // returns the job id and an error
+// vcpus and memory (MiB) override the job definition resources when greater than 0
 func (c *AWSBatchController) JobCreate(ctx context.Context,
 	jobDef, jobName, jobQueue string, commandOverride []string,
-	envVars map[string]string) (string, error) {
+	envVars map[string]string, vcpus float32, memory int, priority *int) (string, error) {
 
 	envs := make([]*batch.KeyValuePair, len(envVars))
 	var i int
@@ -100,12 +106,27 @@ func (c *AWSBatchController) JobCreate(ctx context.Context,
 		Environment: envs,
 	}
 
+	if vcpus > 0 {
+		overrides.ResourceRequirements = append(overrides.ResourceRequirements, &batch.ResourceRequirement{
+			Type: aws.String(batch.ResourceTypeVcpu), Value: aws.String(strconv.FormatFloat(float64(vcpus), 'f', -1, 32)),
+		})
+	}
+	if memory > 0 {
+		overrides.ResourceRequirements = append(overrides.ResourceRequirements, &batch.ResourceRequirement{
+			Type: aws.String(batch.ResourceTypeMemory), Value: aws.String(strconv.Itoa(memory)),
+		})
+	}
+
 	input := &batch.SubmitJobInput{
 		JobDefinition:      aws.String(jobDef),
 		JobName:            aws.String(jobName),
 		JobQueue:           aws.String(jobQueue),
 		ContainerOverrides: overrides,
 	}
+	// only used by job queues with a fair share scheduling policy
+	if priority != nil {
+		input.SchedulingPriorityOverride = aws.Int64(int64(*priority))
+	}
 
 	output, err := c.client.SubmitJobWithContext(ctx, input)
 	if err != nil {
@@ -156,6 +177,20 @@ func (c *AWSBatchController) JobMonitor(batchID string) (string, string, error)
 	}
 }
 
+// Get the name of the job from Batch
+func (c *AWSBatchController) GetJobName(batchID string) (string, error) {
+	input := &batch.DescribeJobsInput{Jobs: aws.StringSlice([]string{batchID})}
+	output, err := c.client.DescribeJobs(input)
+	if err != nil {
+		return "", err
+	}
+	if len(output.Jobs) == 0 {
+		return "", fmt.Errorf("no such job: %s", batchID)
+	}
+
+	return aws.StringValue(output.Jobs[0].JobName), nil
+}
+
 // combines JobTerminate and JobCancel by managing calls for you based on job status
 func (c *AWSBatchController) JobKill(jobID string) (string, error) {
 	input := &batch.DescribeJobsInput{Jobs: aws.StringSlice([]string{jobID})}
@@ -199,6 +234,8 @@ func (c *AWSBatchController) JobKill(jobID string) (string, error) {
 }
 
 // for jobs with the following statuses: "STARTING", "jobs.RUNNING"
+// Batch sends SIGTERM to the container and SIGKILL after the ECS stop timeout (30 seconds by default),
+// the grace period can't be set per call, it is configured through ECS_CONTAINER_STOP_TIMEOUT of the compute environment instances.
 func (c *AWSBatchController) JobTerminate(jobID, reason string) (string, error) {
 	input := &batch.TerminateJobInput{
 		JobId:  aws.String(jobID),
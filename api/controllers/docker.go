@@ -1,8 +1,10 @@
 package controllers
 
 import (
-	"bufio"
+	"bytes"
 	"context"
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -21,6 +23,9 @@ import (
 
 const DOCKER_NETWORK = "process_api_net"
 
+// Label of job containers, its value is the job ID
+const JobIDLabel = "process-api.job-id"
+
 type DockerController struct {
 	cli *client.Client
 }
@@ -55,7 +60,8 @@ func NewDockerController() (*DockerController, error) {
 }
 
 // returns container id, error
-func (c *DockerController) ContainerRun(ctx context.Context, image string, command []string, volumes []VolumeMount, envVars map[string]string, resources DockerResources) (string, error) {
+// user is the user the container runs as, empty means the user declared by the image
+func (c *DockerController) ContainerRun(ctx context.Context, image string, command []string, volumes []VolumeMount, envVars map[string]string, resources DockerResources, user string, labels map[string]string) (string, error) {
 	hostConfig := container.HostConfig{
 		Resources: container.Resources(resources),
 	}
@@ -65,6 +71,15 @@ func (c *DockerController) ContainerRun(ctx context.Context, image string, comma
 	mounts := make([]mount.Mount, len(volumes))
 
 	for i, volume := range volumes {
+		if volume.BindSource != "" {
+			mounts[i] = mount.Mount{
+				Type:     mount.TypeBind,
+				Source:   volume.BindSource,
+				Target:   volume.HostPath,
+				ReadOnly: volume.ReadOnly,
+			}
+			continue
+		}
 		mount := mount.Mount{
 			Type:   mount.TypeVolume,
 			Source: volume.Volume.Name,
@@ -94,11 +109,14 @@ func (c *DockerController) ContainerRun(ctx context.Context, image string, comma
 		},
 	}
 
+	// without a TTY stdout and stderr are kept as separate streams in container logs
 	resp, err := c.cli.ContainerCreate(ctx, &container.Config{
-		Tty:   true,
-		Image: image,
-		Cmd:   command,
-		Env:   envs,
+		Tty:    false,
+		Image:  image,
+		Cmd:    command,
+		Env:    envs,
+		User:   user,
+		Labels: labels,
 	}, &hostConfig, netConfig, nil, "")
 	// log.Info("Container Create response", resp)
 	if err != nil {
@@ -116,30 +134,109 @@ func (c *DockerController) ContainerRun(ctx context.Context, image string, comma
 	return resp.ID, nil
 }
 
+// Check if docker daemon is reachable
+func (c *DockerController) Ping(ctx context.Context) error {
+	_, err := c.cli.Ping(ctx)
+	return err
+}
+
 func (c *DockerController) Version() string {
 	return c.cli.ClientVersion()
 }
 
-// returns container logs as string, error
-func (c *DockerController) ContainerLog(ctx context.Context, id string) ([]string, error) {
+// ContainerLogs are the log lines of a container split by stream.
+// Combined has the lines of both streams in the order they were written.
+type ContainerLogs struct {
+	Combined []string
+	Stdout   []string
+	Stderr   []string
+}
 
+// returns container logs, error
+func (c *DockerController) ContainerLog(ctx context.Context, id string) (ContainerLogs, error) {
 	reader, err := c.cli.ContainerLogs(ctx, id, types.ContainerLogsOptions{
 		ShowStdout: true,
 		ShowStderr: true})
 
 	if err != nil {
-		return nil, err
+		return ContainerLogs{}, err
 	}
+	defer reader.Close()
 
-	scanner := bufio.NewScanner(reader)
-	var logs []string
+	return readContainerLogs(reader)
+}
+
+// Time the last log line of a container was written, zero time if the container has not written any logs
+func (c *DockerController) ContainerLastLogTime(ctx context.Context, id string) (time.Time, error) {
+	reader, err := c.cli.ContainerLogs(ctx, id, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Timestamps: true,
+		Tail:       "1"})
 
-	for scanner.Scan() {
-		logs = append(logs, scanner.Text())
+	if err != nil {
+		return time.Time{}, err
 	}
+	defer reader.Close()
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	logs, err := readContainerLogs(reader)
+	if err != nil || len(logs.Combined) == 0 {
+		return time.Time{}, err
+	}
+
+	// lines are prefixed with their timestamp when timestamps are requested
+	ts, _, _ := strings.Cut(logs.Combined[len(logs.Combined)-1], " ")
+	return time.Parse(time.RFC3339Nano, ts)
+}
+
+// Read logs of a container.
+// Containers run without a TTY, so logs are multiplexed in frames of an 8 byte header followed by the payload.
+// First byte of the header is the stream, 1 for stdout and 2 for stderr, last 4 bytes are the big endian payload size.
+func readContainerLogs(reader io.Reader) (ContainerLogs, error) {
+	var logs ContainerLogs
+
+	addLine := func(stream byte, line string) {
+		logs.Combined = append(logs.Combined, line)
+		if stream == 1 {
+			logs.Stdout = append(logs.Stdout, line)
+		} else {
+			logs.Stderr = append(logs.Stderr, line)
+		}
+	}
+
+	// incomplete last line of each stream, lines can be split across frames
+	partial := make(map[byte][]byte)
+	header := make([]byte, 8)
+	for {
+		_, err := io.ReadFull(reader, header)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return logs, err
+		}
+
+		payload := make([]byte, binary.BigEndian.Uint32(header[4:]))
+		if _, err = io.ReadFull(reader, payload); err != nil {
+			return logs, err
+		}
+
+		buf := append(partial[header[0]], payload...)
+		for {
+			i := bytes.IndexByte(buf, '\n')
+			if i == -1 {
+				break
+			}
+			addLine(header[0], string(bytes.TrimSuffix(buf[:i], []byte("\r"))))
+			buf = buf[i+1:]
+		}
+		partial[header[0]] = buf
+	}
+
+	for _, stream := range []byte{1, 2} {
+		if len(partial[stream]) > 0 {
+			addLine(stream, string(partial[stream]))
+		}
 	}
 
 	return logs, nil
@@ -156,12 +253,84 @@ func (c *DockerController) ContainerWait(ctx context.Context, id string) (int64,
 	}
 }
 
+// Resource usage of a container
+type ContainerUsage struct {
+	// Highest memory usage sampled, in bytes
+	PeakMemory uint64
+	// Total CPU time consumed by all cores, in nanoseconds
+	CPUTime uint64
+}
+
+// Follow resource usage of a container until it stops or ctx is done.
+// Docker samples stats about every second, so peaks shorter than that can be missed.
+func (c *DockerController) ContainerUsage(ctx context.Context, id string) (ContainerUsage, error) {
+	var usage ContainerUsage
+	resp, err := c.cli.ContainerStats(ctx, id, true)
+	if err != nil {
+		return usage, err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var s types.StatsJSON
+		if err := dec.Decode(&s); err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return usage, nil
+			}
+			return usage, err
+		}
+
+		// max_usage is only reported by cgroup v1 hosts, stats of a stopped container are all zero
+		mem := s.MemoryStats.Usage
+		if s.MemoryStats.MaxUsage > mem {
+			mem = s.MemoryStats.MaxUsage
+		}
+		if mem > usage.PeakMemory {
+			usage.PeakMemory = mem
+		}
+		if s.CPUStats.CPUUsage.TotalUsage > usage.CPUTime {
+			usage.CPUTime = s.CPUStats.CPUUsage.TotalUsage
+		}
+	}
+}
+
 func (c *DockerController) ContainerRemove(ctx context.Context, containerID string) error {
 	return c.cli.ContainerRemove(ctx, containerID, types.ContainerRemoveOptions{
 		Force: true,
 	})
 }
 
+// ExitedContainers returns IDs of exited containers that have the label, with the time each container finished
+func (c *DockerController) ExitedContainers(ctx context.Context, label string) (map[string]time.Time, error) {
+	containers, err := c.cli.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", label), filters.Arg("status", "exited")),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	exited := make(map[string]time.Time, len(containers))
+	for _, ctr := range containers {
+		info, err := c.cli.ContainerInspect(ctx, ctr.ID)
+		if err != nil {
+			return nil, fmt.Errorf("error getting container details: %v", err)
+		}
+		finished, err := time.Parse(time.RFC3339Nano, info.State.FinishedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing stop time: %v", err)
+		}
+		exited[ctr.ID] = finished
+	}
+	return exited, nil
+}
+
+// Send SIGTERM to the container and SIGKILL after timeout seconds if it has not stopped
+func (c *DockerController) ContainerStop(ctx context.Context, containerID string, timeout int) error {
+	return c.cli.ContainerStop(ctx, containerID, container.StopOptions{Timeout: &timeout})
+}
+
 func (c *DockerController) ContainerKill(ctx context.Context, containerID string) (err error) {
 	err = c.cli.ContainerKill(ctx, containerID, "KILL")
 	// to do ignore error if container is already killed
@@ -208,6 +377,9 @@ func (c *DockerController) EnsureImage(ctx context.Context, image string, verbos
 type VolumeMount struct {
 	HostPath string
 	Volume   *volumetypes.Volume
+	// Directory on the docker host bind mounted at HostPath, Volume is ignored when set
+	BindSource string
+	ReadOnly   bool
 }
 
 func (c *DockerController) FindVolume(name string) (*volumetypes.Volume, error) {
@@ -269,9 +441,15 @@ func (c *DockerController) GetImageDigest(imageURI string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	// Get the digest from the image inspect response
-	imageDigest := imageInspect.ID
-	return imageDigest, nil
+	// Prefer the registry digest, it identifies the image across hosts.
+	// Locally built images do not have one, their image ID is used instead
+	if len(imageInspect.RepoDigests) > 0 {
+		// repo digests are in the form repository@sha256:...
+		if i := strings.LastIndex(imageInspect.RepoDigests[0], "@"); i != -1 {
+			return imageInspect.RepoDigests[0][i+1:], nil
+		}
+	}
+	return imageInspect.ID, nil
 }
 
 // Get job execution times
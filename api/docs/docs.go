@@ -43,6 +43,38 @@ const docTemplate = `{
                 }
             }
         },
+        "/batches/{batchID}": {
+            "get": {
+                "description": "Status summary of all jobs in a batch",
+                "consumes": [
+                    "*/*"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Batch Summary",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "example: 44d9ca0e-2ca7-4013-907f-a8ccc60da3b4",
+                        "name": "batchID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.batchResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/conformance": {
             "get": {
                 "description": "[Conformance Specification](https://docs.ogc.org/is/18-062r2/18-062r2.html#sc_conformance_classes)",
@@ -67,6 +99,41 @@ const docTemplate = `{
                 }
             }
         },
+        "/execution": {
+            "post": {
+                "description": "Executes the process configured with DEFAULT_PROCESS, see /processes/{processID}/execution",
+                "consumes": [
+                    "application/json",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "processes"
+                ],
+                "summary": "Execute Default Process",
+                "parameters": [
+                    {
+                        "description": "example: {inputs: {text:Hello World!}} (add double quotes for all strings in the payload)",
+                        "name": "inputs",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.jobResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/jobs": {
             "get": {
                 "description": "[Job List Specification](https://docs.ogc.org/is/18-062r2/18-062r2.html#sc_retrieve_job_results)",
@@ -80,6 +147,14 @@ const docTemplate = `{
                     "jobs"
                 ],
                 "summary": "Summary of all (active) Jobs",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "only accepted and running jobs, served from memory instead of the database",
+                        "name": "active",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -100,7 +175,8 @@ const docTemplate = `{
                     "*/*"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/yaml"
                 ],
                 "tags": [
                     "jobs"
@@ -113,6 +189,12 @@ const docTemplate = `{
                         "name": "jobID",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "html, json or yaml",
+                        "name": "f",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -144,10 +226,32 @@ const docTemplate = `{
                         }
                     }
                 }
+            },
+            "head": {
+                "description": "Status of a job in the X-Job-Status header without a body, 404 if the job is not found",
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Job Status Check",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "example: 44d9ca0e-2ca7-4013-907f-a8ccc60da3b4",
+                        "name": "jobID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": ""
+                    }
+                }
             }
         },
         "/jobs/{jobID}/logs": {
             "get": {
+                "description": "Logs can be fetched incrementally using stream, since and limit query parameters.\nProcess logs are streamed as server-sent events when Accept header is text/event-stream.",
                 "consumes": [
                     "*/*"
                 ],
@@ -165,6 +269,24 @@ const docTemplate = `{
                         "name": "jobID",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "container, api, stdout or stderr, default is all",
+                        "name": "stream",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "line offset or RFC3339 timestamp",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "maximum number of log entries per stream",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -208,11 +330,335 @@ const docTemplate = `{
                         }
                     }
                 }
+            },
+            "head": {
+                "description": "Responds 200 without a body if results of a job are available, 404 otherwise. Status of the job is in the X-Job-Status header",
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Job Results Check",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "example: 44d9ca0e-2ca7-4013-907f-a8ccc60da3b4",
+                        "name": "jobID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/jobs/{jobID}/results/{outputID}": {
+            "get": {
+                "description": "Streams an output of a successful job stored in the storage bucket. Supports the Range header, partial content is returned with 206.",
+                "consumes": [
+                    "*/*"
+                ],
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Job Result Download",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "example: 44d9ca0e-2ca7-4013-907f-a8ccc60da3b4",
+                        "name": "jobID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id of the output",
+                        "name": "outputID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ex: bytes=0-1023",
+                        "name": "Range",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "206": {
+                        "description": "Partial Content",
+                        "schema": {
+                            "type": "file"
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics": {
+            "get": {
+                "description": "Metrics of active jobs and storage write retries in Prometheus text format",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "info"
+                ],
+                "summary": "Metrics",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/processes": {
+            "get": {
+                "description": "[Process List Specification](https://docs.ogc.org/is/18-062r2/18-062r2.html#sc_process_list)",
+                "consumes": [
+                    "*/*"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "processes"
+                ],
+                "summary": "List Available Processes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "comma separated keywords, processes having all of them are listed",
+                        "name": "keyword",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/processes/{processID}": {
+            "get": {
+                "description": "[Process Description Specification](https://docs.ogc.org/is/18-062r2/18-062r2.html#sc_process_description)",
+                "consumes": [
+                    "*/*"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "processes"
+                ],
+                "summary": "Describe Process Information",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "example: pyecho",
+                        "name": "processID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "conformance profile the description is tailored to, 'ogc-process-description' (default) or 'core'",
+                        "name": "profile",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/processes.processDescription"
+                        }
+                    }
+                }
+            }
+        },
+        "/processes/{processID}/definition": {
+            "get": {
+                "description": "Definition of a process as loaded, including host and config that are not part of the description. Admins only.\nValues of secret-like keys, secret references and passwords of URLs are redacted.",
+                "consumes": [
+                    "*/*"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/yaml"
+                ],
+                "tags": [
+                    "processes"
+                ],
+                "summary": "Process Definition",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "pyecho",
+                        "name": "processID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (default) or yaml",
+                        "name": "f",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/processes/{processID}/execution": {
+            "post": {
+                "description": "[Execute Process Specification](https://docs.ogc.org/is/18-062r2/18-062r2.html#sc_create_job)",
+                "consumes": [
+                    "application/json",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "processes"
+                ],
+                "summary": "Execute Process",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "pyecho",
+                        "name": "processID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "example: {inputs: {text:Hello World!}} (add double quotes for all strings in the payload)",
+                        "name": "inputs",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "stream process logs of sync processes as NDJSON, followed by the results document",
+                        "name": "stream",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.jobResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/processes/{processID}/execution/batch": {
+            "post": {
+                "description": "Create one asynchronous job per element of inputs. Jobs of the batch can be summarized through /batches/{batchID}",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "processes"
+                ],
+                "summary": "Execute Process in Batch",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "pyecho",
+                        "name": "processID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "example: {inputs: [{text:Hello}, {text:World}]}",
+                        "name": "inputs",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.batchResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/processes/{processID}/inputs/schema": {
+            "get": {
+                "description": "JSON Schema of the inputs of a process, can be used to generate forms",
+                "consumes": [
+                    "*/*"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "processes"
+                ],
+                "summary": "Process Inputs Schema",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "pyecho",
+                        "name": "processID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
             }
         },
-        "/processes": {
+        "/providers": {
             "get": {
-                "description": "[Process List Specification](https://docs.ogc.org/is/18-062r2/18-062r2.html#sc_process_list)",
+                "description": "Job providers supported by this server and whether each is configured and reachable",
                 "consumes": [
                     "*/*"
                 ],
@@ -220,9 +666,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "processes"
+                    "info"
                 ],
-                "summary": "List Available Processes",
+                "summary": "List Providers",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -234,74 +680,41 @@ const docTemplate = `{
                 }
             }
         },
-        "/processes/{processID}": {
+        "/stats": {
             "get": {
-                "description": "[Process Description Specification](https://docs.ogc.org/is/18-062r2/18-062r2.html#sc_process_description)",
-                "consumes": [
-                    "*/*"
-                ],
+                "description": "Counts by status, failure rates and durations per process of jobs updated within a time window",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "processes"
+                    "info"
                 ],
-                "summary": "Describe Process Information",
+                "summary": "Job Statistics",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "example: pyecho",
-                        "name": "processID",
-                        "in": "path",
-                        "required": true
+                        "description": "duration of the window, e.g. 1h, 24h (default 24h)",
+                        "name": "window",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/processes.processDescription"
+                            "$ref": "#/definitions/jobs.JobStats"
                         }
-                    }
-                }
-            }
-        },
-        "/processes/{processID}/execution": {
-            "post": {
-                "description": "[Execute Process Specification](https://docs.ogc.org/is/18-062r2/18-062r2.html#sc_create_job)",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "processes"
-                ],
-                "summary": "Execute Process",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "pyecho",
-                        "name": "processID",
-                        "in": "path",
-                        "required": true
                     },
-                    {
-                        "description": "example: {inputs: {text:Hello World!}} (add double quotes for all strings in the payload)",
-                        "name": "inputs",
-                        "in": "body",
-                        "required": true,
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/handlers.errResponse"
                         }
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/handlers.jobResponse"
+                            "$ref": "#/definitions/handlers.errResponse"
                         }
                     }
                 }
@@ -309,16 +722,96 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "handlers.batchResponse": {
+            "type": "object",
+            "properties": {
+                "batchID": {
+                    "type": "string"
+                },
+                "jobs": {},
+                "message": {
+                    "type": "string"
+                },
+                "processID": {
+                    "type": "string"
+                },
+                "statusCounts": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.errResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "type": {
+                    "description": "OGC exception type URI of the error, see exceptions.go",
+                    "type": "string"
+                }
+            }
+        },
         "handlers.jobResponse": {
             "type": "object",
             "properties": {
+                "cacheHit": {
+                    "description": "CacheHit jobs reuse results of a previous job with the same inputs, the process was not executed",
+                    "type": "boolean"
+                },
+                "commandOverride": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "expiresAt": {
+                    "description": "Results are no longer available after ExpiresAt",
+                    "type": "string"
+                },
+                "image": {
+                    "description": "Image and command the job ran with, secrets redacted. Only set for operators, see JobStatusCommand",
+                    "type": "string"
+                },
+                "integrity": {
+                    "description": "Digests of results clients can verify results with",
+                    "$ref": "#/definitions/jobs.ResultsIntegrity"
+                },
                 "jobID": {
                     "type": "string"
                 },
+                "links": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.link"
+                    }
+                },
                 "message": {
                     "type": "string"
                 },
+                "outputLocation": {
+                    "description": "Storage location results are written to, when requested through outputPrefix",
+                    "type": "string"
+                },
                 "outputs": {},
+                "outputsByReference": {
+                    "description": "OutputsByReference is set when outputs of a sync execution exceed the maximum inline size and are returned as references",
+                    "type": "boolean"
+                },
+                "partial": {
+                    "description": "Partial outputs are intermediate results of a running job, these are not final",
+                    "type": "boolean"
+                },
+                "priority": {
+                    "description": "Scheduling priority of the job, higher priority jobs are started first",
+                    "type": "integer"
+                },
                 "processID": {
                     "type": "string"
                 },
@@ -334,22 +827,78 @@ const docTemplate = `{
                 }
             }
         },
+        "handlers.link": {
+            "type": "object",
+            "properties": {
+                "href": {
+                    "type": "string"
+                },
+                "rel": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "jobs.DurationStats": {
+            "type": "object",
+            "properties": {
+                "avg": {
+                    "type": "number"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "max": {
+                    "type": "number"
+                },
+                "p50": {
+                    "type": "number"
+                },
+                "p90": {
+                    "type": "number"
+                },
+                "p99": {
+                    "type": "number"
+                }
+            }
+        },
         "jobs.JobLogs": {
             "type": "object",
             "properties": {
-                "container_logs": {
+                "jobID": {
+                    "type": "string"
+                },
+                "processID": {
+                    "type": "string"
+                },
+                "process_logs": {
                     "type": "array",
                     "items": {
                         "$ref": "#/definitions/jobs.LogEntry"
                     }
                 },
-                "jobID": {
-                    "type": "string"
+                "server_logs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/jobs.LogEntry"
+                    }
                 },
-                "processID": {
+                "status": {
                     "type": "string"
                 },
-                "server_logs": {
+                "stderr": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/jobs.LogEntry"
+                    }
+                },
+                "stdout": {
+                    "description": "Stdout and Stderr of the process as separate streams, process logs have both streams combined.\nOnly available for docker and subprocess jobs.",
                     "type": "array",
                     "items": {
                         "$ref": "#/definitions/jobs.LogEntry"
@@ -360,6 +909,14 @@ const docTemplate = `{
         "jobs.JobRecord": {
             "type": "object",
             "properties": {
+                "archived": {
+                    "description": "Archived jobs have been moved out of the jobs table, they are not listed but can still be fetched by ID",
+                    "type": "boolean"
+                },
+                "expiresAt": {
+                    "description": "Results of successful jobs are no longer retained after ExpiresAt, nil means they never expire",
+                    "type": "string"
+                },
                 "host": {
                     "type": "string"
                 },
@@ -372,9 +929,23 @@ const docTemplate = `{
                 "processID": {
                     "type": "string"
                 },
+                "requestedOutputs": {
+                    "description": "Outputs requested in the execution request, results only include these, empty means all outputs",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "status": {
                     "type": "string"
                 },
+                "submitter": {
+                    "type": "string"
+                },
+                "submitterID": {
+                    "description": "SubmitterID is the ` + "`" + `sub` + "`" + ` claim of the token used to submit the job",
+                    "type": "string"
+                },
                 "type": {
                     "type": "string",
                     "default": "process"
@@ -384,6 +955,29 @@ const docTemplate = `{
                 }
             }
         },
+        "jobs.JobStats": {
+            "type": "object",
+            "properties": {
+                "counts": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "processes": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/jobs.ProcessStats"
+                    }
+                },
+                "since": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
         "jobs.LogEntry": {
             "type": "object",
             "properties": {
@@ -398,9 +992,64 @@ const docTemplate = `{
                 }
             }
         },
+        "jobs.ProcessStats": {
+            "type": "object",
+            "properties": {
+                "counts": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "duration": {
+                    "$ref": "#/definitions/jobs.DurationStats"
+                },
+                "failureRate": {
+                    "description": "Failed jobs out of successful and failed jobs, dismissed jobs are not counted",
+                    "type": "number"
+                }
+            }
+        },
+        "jobs.ResultsIntegrity": {
+            "type": "object",
+            "properties": {
+                "outputs": {
+                    "description": "Digest of each output, when results are an object keyed by output ID",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "publicKey": {
+                    "description": "Base64 encoded public key the signature can be verified with",
+                    "type": "string"
+                },
+                "sha256": {
+                    "type": "string"
+                },
+                "signature": {
+                    "description": "Base64 encoded Ed25519 signature of SHA256, set if a signing key is configured",
+                    "type": "string"
+                }
+            }
+        },
         "processes.Info": {
             "type": "object",
             "properties": {
+                "aliases": {
+                    "description": "Former IDs of a renamed process, these resolve to this process",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "deprecated": {
+                    "description": "Deprecated processes can still be executed, execution responses signal it with Deprecation and Sunset headers",
+                    "type": "boolean"
+                },
+                "deprecationMessage": {
+                    "type": "string"
+                },
                 "description": {
                     "type": "string"
                 },
@@ -413,12 +1062,22 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
+                "keywords": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "outputTransmission": {
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
                 },
+                "sunset": {
+                    "description": "Date after which a deprecated process may be removed, in the form YYYY-MM-DD",
+                    "type": "string"
+                },
                 "title": {
                     "type": "string"
                 },
@@ -438,6 +1097,13 @@ const docTemplate = `{
         "processes.Inputs": {
             "type": "object",
             "properties": {
+                "conflicts": {
+                    "description": "IDs of inputs that can not be provided together with this input",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "description": {
                     "type": "string"
                 },
@@ -450,9 +1116,28 @@ const docTemplate = `{
                 "maxOccurs": {
                     "type": "integer"
                 },
+                "mediaType": {
+                    "description": "Expected media type of staged href inputs",
+                    "type": "string"
+                },
                 "minOccurs": {
                     "type": "integer"
                 },
+                "requires": {
+                    "description": "IDs of inputs that must be provided when this input is provided",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "serverEnv": {
+                    "description": "Env variable the value of the input is read from, these inputs are set by the server and not described to clients",
+                    "type": "string"
+                },
+                "stage": {
+                    "description": "Stage href inputs by downloading them when the job starts, the process receives path of the downloaded file",
+                    "type": "boolean"
+                },
                 "title": {
                     "type": "string"
                 }
@@ -481,6 +1166,14 @@ const docTemplate = `{
                 "dataType": {
                     "type": "string"
                 },
+                "defaultValue": {},
+                "supportedCRS": {
+                    "description": "CRSs accepted for bbox inputs in addition to CRS84 and EPSG:4326",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "valueDefinition": {
                     "$ref": "#/definitions/processes.ValueDefinition"
                 }
@@ -489,7 +1182,7 @@ const docTemplate = `{
         "processes.Output": {
             "type": "object",
             "properties": {
-                "formats": {
+                "transmissionMode": {
                     "type": "array",
                     "items": {
                         "type": "string"
@@ -506,13 +1199,17 @@ const docTemplate = `{
                 "id": {
                     "type": "string"
                 },
-                "inputID": {
-                    "description": "json omit",
+                "inputId": {
                     "type": "string"
                 },
                 "output": {
                     "$ref": "#/definitions/processes.Output"
                 },
+                "schema": {
+                    "description": "JSON Schema results of successful jobs are checked against, see outputValidation of the process config",
+                    "type": "object",
+                    "additionalProperties": true
+                },
                 "title": {
                     "type": "string"
                 }
@@ -546,8 +1243,11 @@ const docTemplate = `{
         "processes.processDescription": {
             "type": "object",
             "properties": {
-                "image": {
-                    "type": "string"
+                "command": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
                 "info": {
                     "$ref": "#/definitions/processes.Info"
@@ -564,14 +1264,15 @@ const docTemplate = `{
                         "$ref": "#/definitions/processes.Link"
                     }
                 },
-                "maxResources": {
-                    "$ref": "#/definitions/processes.Resources"
-                },
                 "outputs": {
                     "type": "array",
                     "items": {
                         "$ref": "#/definitions/processes.Outputs"
                     }
+                },
+                "resources": {
+                    "description": "Resources jobs get by default, these inform clients about the expected cost of the process",
+                    "$ref": "#/definitions/processes.Resources"
                 }
             }
         }
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"app/utils"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+const redactedValue = "[REDACTED]"
+
+// Inputs are not logged for request bodies larger than this, so that large bodies are not buffered twice
+const maxLoggedBodySize = 1 << 20
+
+// Routes whose request body has inputs, the batch route has an array of inputs
+var inputRoutes = []string{
+	"/processes/:processID/execution",
+	"/processes/:processID/execution/batch",
+	"/execution",
+}
+
+// AccessLogConfig configures the access log middleware
+type AccessLogConfig struct {
+	// Log inputs of execution requests
+	LogInputs bool
+	// Input keys whose values are masked, matched case insensitively at any nesting level
+	RedactKeys []string
+}
+
+// AccessLog logs a structured entry for every request with method, path, status, duration and request id.
+// Inputs of execution requests are included when enabled, with values of redacted keys masked.
+// Request id is set by the RequestID middleware, which must run before this middleware.
+func AccessLog(cfg AccessLogConfig) echo.MiddlewareFunc {
+	redact := make(map[string]bool, len(cfg.RedactKeys))
+	for _, k := range cfg.RedactKeys {
+		if k = strings.TrimSpace(k); k != "" {
+			redact[strings.ToLower(k)] = true
+		}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+
+			var inputs interface{}
+			if cfg.LogInputs && c.Request().Method == echo.POST && utils.StringInSlice(c.Path(), inputRoutes) {
+				inputs = readInputs(c, redact)
+			}
+
+			err := next(c)
+			if err != nil {
+				// let the error handler write the response so that status is final
+				c.Error(err)
+			}
+
+			req := c.Request()
+			fields := log.Fields{
+				"request_id":  c.Response().Header().Get(echo.HeaderXRequestID),
+				"method":      req.Method,
+				"path":        req.URL.Path,
+				"route":       c.Path(),
+				"status":      c.Response().Status,
+				"duration_ms": time.Since(start).Milliseconds(),
+				"remote_ip":   c.RealIP(),
+			}
+			if inputs != nil {
+				fields["inputs"] = inputs
+			}
+			log.WithFields(fields).Info("access")
+
+			return nil
+		}
+	}
+}
+
+// Read inputs from a JSON execution request body, restoring the body for the handler.
+// Returns nil if body is not JSON or is larger than maxLoggedBodySize.
+func readInputs(c echo.Context, redact map[string]bool) interface{} {
+	if !strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		return nil
+	}
+
+	req := c.Request()
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxLoggedBodySize+1))
+	// the rest of a large body is left unread for the handler
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), req.Body), req.Body}
+	if err != nil || len(body) > maxLoggedBodySize {
+		return nil
+	}
+
+	var doc struct {
+		Inputs interface{} `json:"inputs"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil
+	}
+	return redactValue(doc.Inputs, redact)
+}
+
+// Mask values of redacted keys in nested objects and arrays
+func redactValue(v interface{}, redact map[string]bool) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			if redact[strings.ToLower(k)] {
+				val[k] = redactedValue
+				continue
+			}
+			val[k] = redactValue(item, redact)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = redactValue(item, redact)
+		}
+		return val
+	default:
+		return v
+	}
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"app/controllers"
+	"app/jobs"
+	"app/utils"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+// ProviderJobKillHandler sends kill signal directly to the provider for the given provider ID.
+// This is a break-glass tool for orphaned jobs, whose entry in active jobs has been lost.
+// If the job record can be identified it is marked as dismissed in the database.
+func (rh *RESTHandler) ProviderJobKillHandler(c echo.Context) error {
+
+	if rh.Config.AuthLevel > 0 {
+		roles := strings.Split(c.Request().Header.Get("X-ProcessAPI-User-Roles"), ",")
+
+		// non-admins are not allowed
+		if !utils.StringInSlice(rh.Config.AdminRoleName, roles) {
+			return c.JSON(http.StatusForbidden, errResponse{Message: "Forbidden"})
+		}
+	}
+
+	providerID := c.Param("providerID")
+	providerType := c.QueryParam("type")
+
+	switch providerType {
+	case "aws-batch":
+		bc, err := controllers.NewAWSBatchController(os.Getenv("AWS_REGION"))
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errResponse{Message: err.Error()})
+		}
+
+		// Batch job names are rendered from AWS_BATCH_JOB_NAME_TEMPLATE, which always contains the job ID
+		jobName, err := bc.GetJobName(providerID)
+		if err != nil {
+			return c.JSON(http.StatusNotFound, errResponse{Message: err.Error()})
+		}
+		jobID := jobs.JobIDFromBatchJobName(jobName)
+
+		if j, ok := rh.ActiveJobs.Jobs[jobID]; ok {
+			err = (*j).Kill()
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, errResponse{Message: err.Error()})
+			}
+			return c.JSON(http.StatusOK, jobResponse{ProcessID: (*j).ProcessID(), Type: "process", JobID: jobID, Status: (*j).CurrentStatus(), Message: fmt.Sprintf("job %s dismissed", jobID)})
+		}
+
+		_, err = bc.JobKill(providerID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errResponse{Message: err.Error()})
+		}
+
+		reconciled, err := jobs.DismissJobRecord(rh.DB, jobID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errResponse{Message: fmt.Sprintf("kill signal sent to provider job %s, but could not update job record. Error: %s", providerID, err.Error())})
+		}
+		if !reconciled {
+			return c.JSON(http.StatusOK, map[string]string{"message": fmt.Sprintf("kill signal sent to provider job %s, no active job record found", providerID)})
+		}
+		return c.JSON(http.StatusOK, jobResponse{Type: "process", JobID: jobID, Status: jobs.DISMISSED, Message: fmt.Sprintf("kill signal sent to provider job %s, job %s dismissed", providerID, jobID)})
+
+	default:
+		return c.JSON(http.StatusBadRequest, errResponse{Message: "Invalid option for query parameter 'type'. Valid options are 'aws-batch'."})
+	}
+}
+
+type providerStatus struct {
+	Type      string `json:"type"`
+	Ready     bool   `json:"ready"`
+	Message   string `json:"message,omitempty"`
+	Processes int    `json:"processes"`
+}
+
+// @Summary List Providers
+// @Description Job providers supported by this server and whether each is configured and reachable
+// @Tags info
+// @Accept */*
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /providers [get]
+// Does not produce HTML
+func (rh *RESTHandler) ProvidersHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	processCount := make(map[string]int)
+	for _, p := range rh.ProcessList.Processes() {
+		processCount[p.Host.Type]++
+	}
+
+	providers := []providerStatus{
+		{Type: "docker", Ready: true},
+		{Type: "aws-batch", Ready: true},
+		{Type: "subprocess", Ready: true},
+	}
+
+	for i := range providers {
+		pr := &providers[i]
+		pr.Processes = processCount[pr.Type]
+
+		var err error
+		switch pr.Type {
+		case "docker":
+			var dc *controllers.DockerController
+			dc, err = controllers.NewDockerController()
+			if err == nil {
+				err = dc.Ping(ctx)
+			}
+		case "aws-batch":
+			var bc *controllers.AWSBatchController
+			bc, err = controllers.NewAWSBatchController(os.Getenv("AWS_REGION"))
+			if err == nil {
+				err = bc.Ping(ctx)
+			}
+		}
+
+		if err != nil {
+			pr.Ready = false
+			pr.Message = err.Error()
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"providers": providers})
+}
+
+type providerJobsResponse struct {
+	Provider string        `json:"provider"`
+	Queue    string        `json:"queue,omitempty"`
+	Total    int           `json:"total"`
+	Jobs     []jobResponse `json:"jobs"`
+}
+
+// Provider type and queue of a job, queue is empty for local providers
+func jobProvider(j jobs.Job) (string, string) {
+	switch job := j.(type) {
+	case *jobs.AWSBatchJob:
+		return "aws-batch", job.JobQueue
+	case *jobs.DockerJob:
+		return "docker", ""
+	case *jobs.SubprocessJob:
+		return "subprocess", ""
+	default:
+		return "", ""
+	}
+}
+
+// ProviderJobsHandler lists accepted and running jobs on a provider, optionally limited to a queue with the `queue` query parameter.
+// On DELETE the listed jobs are dismissed, this allows draining a provider before maintenance.
+// Returns a summary of affected jobs.
+func (rh *RESTHandler) ProviderJobsHandler(c echo.Context) error {
+
+	if rh.Config.AuthLevel > 0 {
+		roles := strings.Split(c.Request().Header.Get("X-ProcessAPI-User-Roles"), ",")
+
+		// non-admins are not allowed
+		if !utils.StringInSlice(rh.Config.AdminRoleName, roles) {
+			return c.JSON(http.StatusForbidden, errResponse{Message: "Forbidden"})
+		}
+	}
+
+	providerType := c.Param("providerType")
+	if !utils.StringInSlice(providerType, []string{"aws-batch", "docker", "subprocess"}) {
+		return c.JSON(http.StatusBadRequest, errResponse{Message: "Invalid provider type. Valid options are 'aws-batch', 'docker' or 'subprocess'."})
+	}
+	queue := c.QueryParam("queue")
+	if queue != "" && providerType != "aws-batch" {
+		return c.JSON(http.StatusBadRequest, errResponse{Message: "'queue' can only be used with 'aws-batch' provider"})
+	}
+
+	var matched []jobs.Job
+	for _, j := range rh.ActiveJobs.Unfinished() {
+		pt, q := jobProvider(j)
+		if pt == providerType && (queue == "" || q == queue) {
+			matched = append(matched, j)
+		}
+	}
+
+	resp := providerJobsResponse{Provider: providerType, Queue: queue, Total: len(matched), Jobs: make([]jobResponse, len(matched))}
+	dismiss := c.Request().Method == http.MethodDelete
+
+	var wg sync.WaitGroup
+	for i, j := range matched {
+		resp.Jobs[i] = jobResponse{ProcessID: j.ProcessID(), Type: "process", JobID: j.JobID(), Status: j.CurrentStatus(), LastUpdate: j.LastUpdate()}
+		if !dismiss {
+			continue
+		}
+
+		// kill signals are sent concurrently, local jobs may wait for a stop timeout
+		wg.Add(1)
+		go func(i int, j jobs.Job) {
+			defer wg.Done()
+			if err := j.Kill(); err != nil {
+				resp.Jobs[i].Message = fmt.Sprintf("could not dismiss job: %s", err.Error())
+				return
+			}
+			resp.Jobs[i].Status = j.CurrentStatus()
+			resp.Jobs[i].Message = fmt.Sprintf("job %s dismissed", j.JobID())
+		}(i, j)
+	}
+	wg.Wait()
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// Seconds clients are asked to wait before retrying sync executions rejected while the scheduler is paused
+const pausedRetryAfter = 60
+
+// SchedulerHandler reports whether new jobs are started.
+// POST /admin/scheduler/pause holds new jobs in accepted status, for example during maintenance,
+// and POST /admin/scheduler/resume starts them in order of priority. Async submissions are accepted while paused,
+// sync executions are rejected with 503 so that requests are not held open until the scheduler is resumed.
+// AWS Batch jobs are held before they are submitted to AWS Batch.
+func (rh *RESTHandler) SchedulerHandler(c echo.Context) error {
+
+	if rh.Config.AuthLevel > 0 {
+		roles := strings.Split(c.Request().Header.Get("X-ProcessAPI-User-Roles"), ",")
+
+		// non-admins are not allowed
+		if !utils.StringInSlice(rh.Config.AdminRoleName, roles) {
+			return c.JSON(http.StatusForbidden, errResponse{Message: "Forbidden"})
+		}
+	}
+
+	switch c.Param("action") {
+	case "":
+		return c.JSON(http.StatusOK, jobs.GetSchedulerState())
+	case "pause":
+		log.Info("Job scheduler paused.")
+		return c.JSON(http.StatusOK, jobs.PauseScheduler())
+	case "resume":
+		log.Info("Job scheduler resumed.")
+		return c.JSON(http.StatusOK, jobs.ResumeScheduler())
+	default:
+		return c.JSON(http.StatusNotFound, errResponse{Message: "Invalid scheduler action. Valid options are 'pause' or 'resume'."})
+	}
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"app/jobs"
+	"app/processes"
+	"app/utils"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// batchRunRequestBody provides one set of inputs per job for batch execution
+type batchRunRequestBody struct {
+	Inputs  []map[string]interface{} `json:"inputs"`
+	EnvVars map[string]string        `json:"env"`
+	// Overrides default resources of the process for all jobs of the batch
+	Resources *processes.Resources `json:"resources"`
+	// Overrides default priority of the process for all jobs of the batch
+	Priority *int `json:"priority"`
+	// Custom fields written to the metadata document of all jobs of the batch
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+// batchResponse store response of batch endpoints
+type batchResponse struct {
+	BatchID      string         `json:"batchID"`
+	ProcessID    string         `json:"processID,omitempty"`
+	Total        int            `json:"total"`
+	StatusCounts map[string]int `json:"statusCounts,omitempty"`
+	Jobs         interface{}    `json:"jobs"`
+	Message      string         `json:"message,omitempty"`
+}
+
+// @Summary Execute Process in Batch
+// @Description Create one asynchronous job per element of inputs. Jobs of the batch can be summarized through /batches/{batchID}
+// @Tags processes
+// @Accept json
+// @Produce json
+// @Param processID path string true "pyecho"
+// @Param inputs body string true "example: {inputs: [{text:Hello}, {text:World}]}"
+// @Success 201 {object} batchResponse
+// @Router /processes/{processID}/execution/batch [post]
+// Does not produce HTML
+func (rh *RESTHandler) BatchExecution(c echo.Context) error {
+	processID := c.Param("processID")
+
+	p, _, err := rh.ProcessList.Get(processID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionNoSuchProcess, Message: "'processID' incorrect"})
+	}
+	setDeprecationHeaders(c, p)
+
+	if rh.Config.AuthLevel > 0 {
+		roles := strings.Split(c.Request().Header.Get("X-ProcessAPI-User-Roles"), ",")
+
+		// admins are allowed to execute all processes, else you need to have a role with same name as processId
+		if !utils.StringInSlice(rh.Config.AdminRoleName, roles) && !utils.StringInSlice(p.Info.ID, roles) {
+			return c.JSON(http.StatusForbidden, errResponse{Message: "Forbidden"})
+		}
+	}
+
+	var params batchRunRequestBody
+	err = bindJSONRequest(c, &params)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Message: err.Error()})
+	}
+
+	if len(params.Inputs) == 0 {
+		return c.JSON(http.StatusBadRequest, errResponse{Message: "'inputs' must be a non empty array in the body of the request"})
+	}
+	for i, inputs := range params.Inputs {
+		if inputs == nil {
+			return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: fmt.Sprintf("inputs %d: must be an object", i)})
+		}
+		err = p.ApplyServerInputs(inputs)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: fmt.Sprintf("inputs %d: %s", i, err.Error())})
+		}
+		p.DecodeInputNumbers(inputs)
+	}
+
+	err = p.VerifyEnvOverrides(params.EnvVars)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: err.Error()})
+	}
+
+	_, err = p.ResolveResources(params.Resources)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: err.Error()})
+	}
+
+	_, err = p.ResolvePriority(params.Priority)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: err.Error()})
+	}
+
+	err = jobs.ValidateCustomMetadata(params.Metadata, rh.Config.MaxJobMetadataSize)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: err.Error()})
+	}
+
+	// verify all elements before creating any job
+	for i, inputs := range params.Inputs {
+		err = rh.verifyInputLimits(inputs)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: fmt.Sprintf("inputs %d: %s", i, err.Error())})
+		}
+		err = p.NormalizeQualifiedInputs(inputs)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: fmt.Sprintf("inputs %d: %s", i, err.Error())})
+		}
+		err = p.NormalizeBBoxInputs(inputs)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: fmt.Sprintf("inputs %d: %s", i, err.Error())})
+		}
+		err = p.VerifyInputs(inputs)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: fmt.Sprintf("inputs %d: %s", i, err.Error())})
+		}
+		err = p.VerifySafeInputs(inputs)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: fmt.Sprintf("inputs %d: %s", i, err.Error())})
+		}
+		if err = p.CallValidationHook(inputs); err != nil {
+			return validationHookError(c, fmt.Errorf("inputs %d: %w", i, err))
+		}
+	}
+
+	if ok, err := rh.checkJobQuota(c, len(params.Inputs)); !ok {
+		return err
+	}
+
+	batchID := uuid.New().String()
+	submitter := c.Request().Header.Get("X-ProcessAPI-User-Email")
+	submitterID := c.Request().Header.Get("X-ProcessAPI-User-ID")
+
+	created := make([]jobResponse, 0, len(params.Inputs))
+	jobIDs := make([]string, 0, len(params.Inputs))
+	var errs []string
+	for i, inputs := range params.Inputs {
+		jobID, err := rh.newJobID(p.Info.ID)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("inputs %d: %s", i, err.Error()))
+			continue
+		}
+
+		downloads, err := rh.hrefDownloads(p, inputs, jobID)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("inputs %d: %s", i, err.Error()))
+			continue
+		}
+
+		j, err := rh.newJob(p, runRequestBody{Inputs: inputs, EnvVars: params.EnvVars, Resources: params.Resources, Priority: params.Priority, Metadata: params.Metadata, RequestID: c.Response().Header().Get(echo.HeaderXRequestID), Downloads: downloads}, jobID, submitter, submitterID)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("inputs %d: %s", i, err.Error()))
+			continue
+		}
+
+		err = j.Create()
+		if err != nil {
+			// failed jobs are still part of the batch so that the failure is visible in its summary
+			rh.recordCreateFailure(p, j, submitterID, err)
+			created = append(created, jobResponse{ProcessID: p.Info.ID, Type: "process", JobID: jobID, Status: jobs.FAILED, Message: fmt.Sprintf("submission error %s", err.Error())})
+			jobIDs = append(jobIDs, jobID)
+			continue
+		}
+		rh.recordSubmission(jobID, p, nil)
+		rh.ActiveJobs.Add(&j)
+
+		created = append(created, jobResponse{ProcessID: p.Info.ID, Type: "process", JobID: jobID, Status: j.CurrentStatus()})
+		jobIDs = append(jobIDs, jobID)
+	}
+
+	resp := batchResponse{BatchID: batchID, ProcessID: p.Info.ID, Total: len(created), Jobs: created}
+	if len(errs) > 0 {
+		resp.Message = strings.Join(errs, "; ")
+	}
+
+	if len(jobIDs) == 0 {
+		return c.JSON(http.StatusInternalServerError, resp)
+	}
+
+	err = rh.DB.AddBatch(batchID, jobIDs)
+	if err != nil {
+		resp.Message = fmt.Sprintf("jobs created but could not record batch. Error: %s", err.Error())
+		return c.JSON(http.StatusInternalServerError, resp)
+	}
+
+	return c.JSON(http.StatusCreated, resp)
+}
+
+// @Summary Batch Summary
+// @Description Status summary of all jobs in a batch
+// @Tags jobs
+// @Accept */*
+// @Produce json
+// @Param batchID path string true "example: 44d9ca0e-2ca7-4013-907f-a8ccc60da3b4"
+// @Success 200 {object} batchResponse
+// @Router /batches/{batchID} [get]
+// Does not produce HTML
+func (rh *RESTHandler) BatchStatusHandler(c echo.Context) error {
+	batchID := c.Param("batchID")
+
+	records, err := rh.DB.GetBatchJobs(batchID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, errResponse{Message: err.Error()})
+	}
+
+	resp := batchResponse{BatchID: batchID, StatusCounts: make(map[string]int)}
+	readable := records[:0]
+	for _, r := range records {
+		if !rh.jobReadable(c, r.Submitter, r.SubmitterID) {
+			continue
+		}
+		readable = append(readable, r)
+		resp.StatusCounts[r.Status]++
+		resp.ProcessID = r.ProcessID
+	}
+
+	if len(readable) == 0 {
+		return c.JSON(http.StatusNotFound, errResponse{Message: fmt.Sprintf("%s batch id not found", batchID)})
+	}
+
+	resp.Total = len(readable)
+	resp.Jobs = readable
+	return c.JSON(http.StatusOK, resp)
+}
@@ -3,13 +3,17 @@ package handlers
 import (
 	"app/jobs"
 	pr "app/processes"
+	"app/utils"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -26,9 +30,65 @@ type Template struct {
 
 // Render the named template with the data
 func (t Template) Render(w io.Writer, name string, data interface{}, c echo.Context) error {
+	if t.templates == nil {
+		return errors.New("html rendering is disabled, no templates are loaded")
+	}
 	return t.templates.ExecuteTemplate(w, name, data)
 }
 
+// Check if all the named templates are defined
+func (t Template) Defines(names []string) bool {
+	if t.templates == nil {
+		return false
+	}
+	for _, n := range names {
+		if t.templates.Lookup(n) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Parse the templates matching pattern. Templates are optional so that JSON only deployments do not need the views,
+// nil is returned if no file matches and HTML rendering is disabled.
+func loadTemplates(pattern string, funcMap template.FuncMap) *template.Template {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		log.Fatalf("Error reading html templates: %s", err.Error())
+	}
+	if len(files) == 0 {
+		log.Warnf("No html templates found at %s, HTML rendering is disabled", pattern)
+		return nil
+	}
+	return template.Must(template.New("").Funcs(funcMap).ParseFiles(files...))
+}
+
+const conformanceClassPrefix = "http://www.opengis.net/spec/ogcapi-processes-1/1.0/conf/"
+
+// Templates needed to render all resources as HTML, required by the html conformance class
+var htmlRenderNames = []string{"landing", "conformance", "processes", "process", "jobs", "jobStatus", "jobResults", "error"}
+
+// Build the list of conformance classes from the features available in this deployment.
+// callback and oas30 classes are not implemented, so they are never advertised.
+func (rh *RESTHandler) conformanceClasses() []string {
+	classes := []string{
+		"http://schemas.opengis.net/ogcapi/processes/part1/1.0/openapi/schemas/",
+		conformanceClassPrefix + "ogc-process-description",
+		conformanceClassPrefix + "core",
+		conformanceClassPrefix + "json",
+	}
+
+	if rh.T.Defines(htmlRenderNames) {
+		classes = append(classes, conformanceClassPrefix+"html")
+	}
+
+	classes = append(classes,
+		conformanceClassPrefix+"job-list",
+		conformanceClassPrefix+"dismiss",
+	)
+	return classes
+}
+
 // Config holds the configuration settings for the REST API server.
 type Config struct {
 	// Only settings that are typically environment-specific and can be loaded from
@@ -39,6 +99,50 @@ type Config struct {
 	AuthLevel       int
 	AdminRoleName   string
 	ServiceRoleName string
+
+	// Directory where file inputs of multipart execution requests are staged
+	InputsDir string
+	// Path of InputsDir on the docker host, differs from InputsDir when the API itself runs in a container
+	InputsHostDir string
+	// Maximum size in bytes of a single file part of multipart execution requests
+	MaxInputPartSize int64
+	// Maximum number of distinct inputs of an execution request
+	MaxInputs int
+	// Maximum nesting depth of objects and arrays in an input value
+	MaxInputDepth int
+	// Maximum size in bytes of the custom metadata of an execution request, as JSON
+	MaxJobMetadataSize int
+	// Duration after which jobs in a terminated status are moved out of active jobs
+	TerminalJobRetention time.Duration
+	// Template for AWS Batch job names with {apiName}, {processID} and {jobID} placeholders
+	BatchJobNameTemplate string
+	// Output prefixes clients can write results to directly, other prefixes are namespaced under the job ID
+	OutputPrefixAllowlist []string
+	// Public base URL of the API used to build absolute links, links are relative if empty
+	ExternalURL string
+	// Limits of a single log stream connection, 0 means no limit
+	LogStreamMaxLines    int
+	LogStreamMaxSize     int64
+	LogStreamMaxDuration time.Duration
+	// Process executed by POST /execution, the route is not available if empty
+	DefaultProcess string
+	// Outputs of sync executions larger than this are returned by reference instead of inline, 0 means no limit
+	MaxSyncResultsSize int64
+	// External registry processes are loaded from instead of plugins directory, and interval they are synced at
+	ProcessRegistryURL          string
+	ProcessRegistrySyncInterval time.Duration
+	// Terminated jobs older than JobArchiveAfter, or beyond the JobArchiveMaxJobs most recent jobs, are archived, 0 disables either
+	JobArchiveAfter   time.Duration
+	JobArchiveMaxJobs int
+	// Exited job containers, such as containers kept for failed jobs, are removed once they finished longer than this ago, 0 keeps them
+	FailedContainerTTL time.Duration
+	// Pull missing images of docker processes at startup, so that first jobs do not wait for the pull
+	PrepullImages bool
+	// Include image and command of jobs in status documents, for admins only if authorization is enabled
+	JobStatusCommand bool
+	// Maximum jobs a submitter can submit within JobQuotaWindow, 0 means no quota
+	JobQuota       int
+	JobQuotaWindow time.Duration
 }
 
 // RESTHandler encapsulates the operational components and dependencies necessary for handling
@@ -56,6 +160,8 @@ type RESTHandler struct {
 	ActiveJobs   *jobs.ActiveJobs
 	ProcessList  *pr.ProcessList
 	Config       *Config
+
+	logsCache *logsCache
 }
 
 // Pretty print a JSON
@@ -80,26 +186,200 @@ func NewRESTHander() *RESTHandler {
 		Name:        apiName,
 		Title:       "process-api",
 		Description: "ogc process api written in Golang for use with cloud service controllers to manage asynchronous requests",
-		ConformsTo: []string{
-			"http://schemas.opengis.net/ogcapi/processes/part1/1.0/openapi/schemas/",
-			"http://www.opengis.net/spec/ogcapi-processes-1/1.0/conf/ogc-process-description",
-			"http://www.opengis.net/spec/ogcapi-processes-1/1.0/conf/core",
-			"http://www.opengis.net/spec/ogcapi-processes-1/1.0/conf/json",
-			"http://www.opengis.net/spec/ogcapi-processes-1/1.0/conf/html",
-			"http://www.opengis.net/spec/ogcapi-processes-1/1.0/conf/job-list",
-			"http://www.opengis.net/spec/ogcapi-processes-1/1.0/conf/dismiss",
-		},
 		Config: &Config{
 			AdminRoleName:   os.Getenv("AUTH_ADMIN_ROLE"),
 			ServiceRoleName: os.Getenv("AUTH_SERVICE_ROLE"),
 		},
 	}
 
+	// In ephemeral mode job records and storage live only in memory, so no external services are needed
+	ephemeral, err := strconv.ParseBool(resolveValue("EPHEMERAL", "false"))
+	if err != nil {
+		log.Fatalf("Error parsing EPHEMERAL: %s", err.Error())
+	}
+
 	dbType, exist := os.LookupEnv("DB_SERVICE")
-	if !exist {
+	if ephemeral {
+		dbType = "memory"
+	} else if !exist {
 		log.Fatal("env variable DB_SERVICE not set")
 	}
 
+	config.Config.InputsDir = os.Getenv("TMP_JOB_INPUTS_DIR")
+	config.Config.InputsHostDir = os.Getenv("TMP_JOB_INPUTS_HOST_DIR")
+	if config.Config.InputsHostDir == "" {
+		config.Config.InputsHostDir = config.Config.InputsDir
+	}
+	maxPartSizeMB, err := strconv.Atoi(resolveValue("MAX_INPUT_PART_SIZE_MB", "100"))
+	if err != nil {
+		log.Fatalf("Error converting MAX_INPUT_PART_SIZE_MB to number: %s", err.Error())
+	}
+	config.Config.MaxInputPartSize = int64(maxPartSizeMB) * 1024 * 1024
+
+	var hrefHosts []string
+	for _, host := range strings.Split(resolveValue("STAGE_HREF_ALLOWED_HOSTS", ""), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hrefHosts = append(hrefHosts, host)
+		}
+	}
+	jobs.SetHrefAllowedHosts(hrefHosts)
+
+	config.Config.MaxInputs, err = strconv.Atoi(resolveValue("MAX_INPUTS", "100"))
+	if err != nil {
+		log.Fatalf("Error converting MAX_INPUTS to number: %s", err.Error())
+	}
+
+	config.Config.MaxInputDepth, err = strconv.Atoi(resolveValue("MAX_INPUT_DEPTH", "10"))
+	if err != nil {
+		log.Fatalf("Error converting MAX_INPUT_DEPTH to number: %s", err.Error())
+	}
+
+	config.Config.MaxJobMetadataSize, err = strconv.Atoi(resolveValue("MAX_JOB_METADATA_SIZE", "16384"))
+	if err != nil {
+		log.Fatalf("Error converting MAX_JOB_METADATA_SIZE to number: %s", err.Error())
+	}
+
+	config.Config.TerminalJobRetention, err = time.ParseDuration(resolveValue("TERMINAL_JOB_RETENTION", "1h"))
+	if err != nil {
+		log.Fatalf("Error parsing TERMINAL_JOB_RETENTION: %s", err.Error())
+	}
+
+	config.Config.JobArchiveAfter, err = time.ParseDuration(resolveValue("JOB_ARCHIVE_AFTER", "0"))
+	if err != nil {
+		log.Fatalf("Error parsing JOB_ARCHIVE_AFTER: %s", err.Error())
+	}
+
+	config.Config.JobArchiveMaxJobs, err = strconv.Atoi(resolveValue("JOB_ARCHIVE_MAX_JOBS", "0"))
+	if err != nil {
+		log.Fatalf("Error converting JOB_ARCHIVE_MAX_JOBS to number: %s", err.Error())
+	}
+
+	config.Config.FailedContainerTTL, err = time.ParseDuration(resolveValue("FAILED_CONTAINER_TTL", "24h"))
+	if err != nil {
+		log.Fatalf("Error parsing FAILED_CONTAINER_TTL: %s", err.Error())
+	}
+
+	config.Config.PrepullImages, err = strconv.ParseBool(resolveValue("PREPULL_IMAGES", "false"))
+	if err != nil {
+		log.Fatalf("Error parsing PREPULL_IMAGES: %s", err.Error())
+	}
+
+	config.Config.JobStatusCommand, err = strconv.ParseBool(resolveValue("JOB_STATUS_COMMAND", "false"))
+	if err != nil {
+		log.Fatalf("Error parsing JOB_STATUS_COMMAND: %s", err.Error())
+	}
+
+	config.Config.JobQuota, err = strconv.Atoi(resolveValue("JOB_QUOTA", "0"))
+	if err != nil {
+		log.Fatalf("Error converting JOB_QUOTA to number: %s", err.Error())
+	}
+	config.Config.JobQuotaWindow, err = time.ParseDuration(resolveValue("JOB_QUOTA_WINDOW", "24h"))
+	if err != nil || config.Config.JobQuotaWindow <= 0 {
+		log.Fatal("JOB_QUOTA_WINDOW must be a positive duration")
+	}
+
+	if err := jobs.SetJobIDFormat(resolveValue("JOB_ID_FORMAT", "{uuid}")); err != nil {
+		log.Fatalf("Invalid JOB_ID_FORMAT: %s", err.Error())
+	}
+
+	config.Config.BatchJobNameTemplate = resolveValue("AWS_BATCH_JOB_NAME_TEMPLATE", "{apiName}_{jobID}")
+	if !strings.Contains(config.Config.BatchJobNameTemplate, "{jobID}") {
+		log.Fatal("AWS_BATCH_JOB_NAME_TEMPLATE must contain {jobID}")
+	}
+
+	config.Config.ExternalURL = strings.TrimRight(os.Getenv("API_URL_PUBLIC"), "/")
+
+	for _, prefix := range strings.Split(resolveValue("OUTPUT_PREFIX_ALLOWLIST", ""), ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			config.Config.OutputPrefixAllowlist = append(config.Config.OutputPrefixAllowlist, prefix)
+		}
+	}
+
+	logsCacheTTL, err := time.ParseDuration(resolveValue("LOGS_CACHE_TTL", "5s"))
+	if err != nil {
+		log.Fatalf("Error parsing LOGS_CACHE_TTL: %s", err.Error())
+	}
+	maxLogFetches, err := strconv.Atoi(resolveValue("MAX_CONCURRENT_LOG_FETCHES", "10"))
+	if err != nil || maxLogFetches < 1 {
+		log.Fatal("MAX_CONCURRENT_LOG_FETCHES must be a positive integer")
+	}
+	config.logsCache = newLogsCache(logsCacheTTL, maxLogFetches)
+
+	storageWorkers, err := strconv.Atoi(resolveValue("STORAGE_WORKERS", "10"))
+	if err != nil || storageWorkers < 1 {
+		log.Fatal("STORAGE_WORKERS must be a positive integer")
+	}
+	jobs.SetStorageWorkers(storageWorkers)
+
+	compressResults, err := strconv.ParseBool(resolveValue("COMPRESS_RESULTS", "false"))
+	if err != nil {
+		log.Fatalf("Error parsing COMPRESS_RESULTS: %s", err.Error())
+	}
+	jobs.SetCompressResults(compressResults)
+
+	if seed := os.Getenv("RESULTS_SIGNING_KEY"); seed != "" {
+		if err := jobs.SetResultsSigningKey(seed); err != nil {
+			log.Fatalf("Invalid RESULTS_SIGNING_KEY: %s", err.Error())
+		}
+	}
+
+	registryTimeout, err := time.ParseDuration(resolveValue("IMAGE_REGISTRY_TIMEOUT", "10s"))
+	if err != nil {
+		log.Fatalf("Error parsing IMAGE_REGISTRY_TIMEOUT: %s", err.Error())
+	}
+	registryInsecure, err := strconv.ParseBool(resolveValue("IMAGE_REGISTRY_INSECURE_SKIP_VERIFY", "false"))
+	if err != nil {
+		log.Fatalf("Error parsing IMAGE_REGISTRY_INSECURE_SKIP_VERIFY: %s", err.Error())
+	}
+	err = jobs.SetRegistryClientOptions(jobs.RegistryClientOptions{
+		Timeout:            registryTimeout,
+		ProxyURL:           os.Getenv("IMAGE_REGISTRY_PROXY"),
+		CAFile:             os.Getenv("IMAGE_REGISTRY_CA_FILE"),
+		InsecureSkipVerify: registryInsecure,
+	})
+	if err != nil {
+		log.Fatalf("Invalid image registry client configuration: %s", err.Error())
+	}
+	if registryInsecure {
+		log.Warn("IMAGE_REGISTRY_INSECURE_SKIP_VERIFY is set, certificates of image registries are not verified")
+	}
+
+	resultsTTL, err := time.ParseDuration(resolveValue("RESULTS_TTL", "0"))
+	if err != nil {
+		log.Fatalf("Error parsing RESULTS_TTL: %s", err.Error())
+	}
+	jobs.SetResultsTTL(resultsTTL)
+
+	cloudWatchMaxEvents, err := strconv.Atoi(resolveValue("CLOUDWATCH_LOGS_MAX_EVENTS", "100000"))
+	if err != nil || cloudWatchMaxEvents < 0 {
+		log.Fatal("CLOUDWATCH_LOGS_MAX_EVENTS must be a non negative integer")
+	}
+	cloudWatchMaxSizeMB, err := strconv.Atoi(resolveValue("CLOUDWATCH_LOGS_MAX_SIZE_MB", "100"))
+	if err != nil || cloudWatchMaxSizeMB < 0 {
+		log.Fatal("CLOUDWATCH_LOGS_MAX_SIZE_MB must be a non negative integer")
+	}
+	jobs.SetCloudWatchLogsLimits(cloudWatchMaxEvents, cloudWatchMaxSizeMB*1024*1024)
+
+	config.Config.LogStreamMaxLines, err = strconv.Atoi(resolveValue("LOG_STREAM_MAX_LINES", "10000"))
+	if err != nil {
+		log.Fatalf("Error converting LOG_STREAM_MAX_LINES to number: %s", err.Error())
+	}
+	logStreamMaxSizeMB, err := strconv.Atoi(resolveValue("LOG_STREAM_MAX_SIZE_MB", "10"))
+	if err != nil {
+		log.Fatalf("Error converting LOG_STREAM_MAX_SIZE_MB to number: %s", err.Error())
+	}
+	config.Config.LogStreamMaxSize = int64(logStreamMaxSizeMB) * 1024 * 1024
+	config.Config.LogStreamMaxDuration, err = time.ParseDuration(resolveValue("LOG_STREAM_MAX_DURATION", "30m"))
+	if err != nil {
+		log.Fatalf("Error parsing LOG_STREAM_MAX_DURATION: %s", err.Error())
+	}
+
+	maxSyncResultsSizeMB, err := strconv.Atoi(resolveValue("MAX_SYNC_RESULTS_SIZE_MB", "10"))
+	if err != nil {
+		log.Fatalf("Error converting MAX_SYNC_RESULTS_SIZE_MB to number: %s", err.Error())
+	}
+	config.Config.MaxSyncResultsSize = int64(maxSyncResultsSizeMB) * 1024 * 1024
+
 	db, err := jobs.NewDatabase(dbType)
 	if err != nil {
 		log.Fatalf(err.Error())
@@ -114,19 +394,9 @@ func NewRESTHander() *RESTHandler {
 	}
 
 	config.T = Template{
-		templates: template.Must(template.New("").Funcs(funcMap).ParseGlob("views/*.html")),
-	}
-
-	stType, exist := os.LookupEnv("STORAGE_SERVICE")
-	if !exist {
-		log.Fatal("env variable STORAGE_SERVICE not set")
-	}
-
-	stSvc, err := NewStorageService(stType)
-	if err != nil {
-		log.Fatal(err)
+		templates: loadTemplates("views/*.html", funcMap),
 	}
-	config.StorageSvc = stSvc
+	config.ConformsTo = config.conformanceClasses()
 
 	// Create local logs directory if not exist
 	localLogsDir, exist := os.LookupEnv("TMP_JOB_LOGS_DIR")
@@ -138,6 +408,17 @@ func NewRESTHander() *RESTHandler {
 		log.Fatalf(err.Error())
 	}
 
+	// Failed storage writes of metadata and logs are persisted here and retried, empty disables retries
+	retryDir := resolveValue("STORAGE_RETRY_DIR", filepath.Join(localLogsDir, "storage_retries"))
+	maxRetryAttempts, err := strconv.Atoi(resolveValue("STORAGE_RETRY_MAX_ATTEMPTS", "10"))
+	if err != nil || maxRetryAttempts < 1 {
+		log.Fatal("STORAGE_RETRY_MAX_ATTEMPTS must be a positive integer")
+	}
+	err = jobs.SetStorageRetries(retryDir, maxRetryAttempts)
+	if err != nil {
+		log.Fatalf("Error creating STORAGE_RETRY_DIR: %s", err.Error())
+	}
+
 	// Setup Active Jobs that will store all jobs currently in process
 	ac := jobs.ActiveJobs{}
 	ac.Jobs = make(map[string]*jobs.Job)
@@ -149,16 +430,73 @@ func NewRESTHander() *RESTHandler {
 	}
 
 	// Create local logs directory if not exist
-	pluginsDir := os.Getenv("PLUGINS_DIR") // We already know this env variable exist because it is being checked in plguinsInit function
-	processList, err := pr.LoadProcesses(pluginsDir)
-	if err != nil {
-		log.Fatal(err)
+	config.Config.ProcessRegistryURL = os.Getenv("PROCESS_REGISTRY_URL")
+	config.Config.ProcessRegistrySyncInterval, err = time.ParseDuration(resolveValue("PROCESS_REGISTRY_SYNC_INTERVAL", "5m"))
+	if err != nil || config.Config.ProcessRegistrySyncInterval <= 0 {
+		log.Fatal("PROCESS_REGISTRY_SYNC_INTERVAL must be a positive duration")
+	}
+
+	// Storage is optional for deployments of local processes only, operations that need it then fail with ErrStorageUnconfigured
+	stType := os.Getenv("STORAGE_SERVICE")
+	if ephemeral {
+		stType = "memory"
+	}
+
+	var processList *pr.ProcessList
+	if config.Config.ProcessRegistryURL != "" {
+		processList, err = pr.LoadRegistryProcesses(config.Config.ProcessRegistryURL, stType != "")
+		if err != nil {
+			log.Warnf("Could not load processes from registry, using plugins directory until the registry is available. Error: %s", err.Error())
+		}
+	}
+	if processList == nil {
+		pluginsDir := os.Getenv("PLUGINS_DIR") // We already know this env variable exist because it is being checked in plguinsInit function
+		processList, err = pr.LoadProcesses(pluginsDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	config.ProcessList = processList
+
+	if stType == "" {
+		for _, p := range processList.Processes() {
+			if p.RequiresStorage() {
+				log.Fatalf("env variable STORAGE_SERVICE not set, process %s requires a storage service", p.Info.ID)
+			}
+		}
+		log.Warn("STORAGE_SERVICE not set, job metadata is not persisted and logs are kept on local disk only")
+	} else {
+		stSvc, err := NewStorageService(stType)
+		if err != nil {
+			log.Fatal(err)
+		}
+		config.StorageSvc = stSvc
+	}
+
+	config.Config.DefaultProcess = os.Getenv("DEFAULT_PROCESS")
+	if config.Config.DefaultProcess != "" {
+		if _, _, err := processList.Get(config.Config.DefaultProcess); err != nil {
+			log.Fatalf("DEFAULT_PROCESS %s is not an available process", config.Config.DefaultProcess)
+		}
 	}
-	config.ProcessList = &processList
 
 	return &config
 }
 
+// Absolute URL of a path on this API, relative path is returned if external URL is not configured
+func (rh *RESTHandler) externalURL(path string) string {
+	return rh.Config.ExternalURL + path
+}
+
+// Checks if there's an environment variable for this configuration,
+// if yes, return the env value, if not, return the default value.
+func resolveValue(envVar string, defaultValue string) string {
+	if value, exists := os.LookupEnv(envVar); exists {
+		return value
+	}
+	return defaultValue
+}
+
 // This routine sequentially updates status.
 // So that order of status updates received is preserved.
 func (rh *RESTHandler) StatusUpdateRoutine() {
@@ -175,12 +513,117 @@ func (rh *RESTHandler) JobCompletionRoutine() {
 	}
 }
 
+// This routine retries failed storage writes of job metadata and logs that are due every 10 seconds.
+func (rh *RESTHandler) StorageRetryRoutine() {
+	for {
+		time.Sleep(10 * time.Second)
+		jobs.RetryStorageWrites(rh.StorageSvc)
+	}
+}
+
+// This routine replaces processes with processes of the external registry every ProcessRegistrySyncInterval.
+func (rh *RESTHandler) ProcessRegistryRoutine() {
+	rh.ProcessList.SyncRegistry(rh.Config.ProcessRegistryURL, rh.Config.ProcessRegistrySyncInterval, rh.StorageSvc != nil)
+}
+
+// This routine moves terminated jobs out of the jobs table every hour, based on JobArchiveAfter and JobArchiveMaxJobs.
+// Archived jobs are not listed but can still be fetched by ID.
+func (rh *RESTHandler) JobArchiveRoutine() {
+	for {
+		var before time.Time
+		if rh.Config.JobArchiveAfter > 0 {
+			before = time.Now().Add(-rh.Config.JobArchiveAfter)
+		}
+		n, err := rh.DB.ArchiveJobs(before, rh.Config.JobArchiveMaxJobs)
+		if err != nil {
+			log.Errorf("could not archive jobs: %s", err.Error())
+		} else if n > 0 {
+			log.Infof("archived %d jobs", n)
+		}
+		time.Sleep(time.Hour)
+	}
+}
+
+// This routine removes jobs from active jobs that have been terminated for longer than TerminalJobRetention,
+// even if their closing routine never completed. These jobs remain available through the database.
+func (rh *RESTHandler) TerminalJobsPurgeRoutine() {
+	interval := rh.Config.TerminalJobRetention / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	for {
+		time.Sleep(interval)
+		n := rh.ActiveJobs.PurgeTerminated(rh.Config.TerminalJobRetention)
+		if n > 0 {
+			log.Infof("removed %d terminated jobs from active jobs", n)
+		}
+	}
+}
+
+// This routine checks running jobs against maxRunningTime of their process every minute.
+// Stuck jobs are either failed or a warning is logged once, based on stuckAction of the process.
+func (rh *RESTHandler) StuckJobsRoutine() {
+	warned := make(map[string]bool)
+
+	for {
+		time.Sleep(time.Minute)
+
+		running := make(map[string]bool)
+		for _, j := range rh.ActiveJobs.Running() {
+			running[j.JobID()] = true
+
+			// the current process is used so that changes of maxRunningTime apply to running jobs,
+			// jobs of a removed process use the process at submission
+			p, _, err := rh.ProcessList.Get(j.ProcessID())
+			if err != nil {
+				jr, ok, _ := rh.DB.GetJob(j.JobID())
+				if !ok || jr.Process == nil {
+					continue
+				}
+				p = snapshotProcess(jr.ProcessID, *jr.Process)
+			}
+			if p.Config.MaxRunningTime == nil {
+				continue
+			}
+			maxRunningTime := time.Duration(*p.Config.MaxRunningTime) * time.Second
+			if time.Since(j.LastUpdate()) <= maxRunningTime {
+				continue
+			}
+
+			msg := fmt.Sprintf("job %s has been running for longer than maxRunningTime of %s", j.JobID(), maxRunningTime)
+			if p.Config.StuckAction == "fail" {
+				log.Errorf("%s, failing it", msg)
+				jobs.FailJob(j, fmt.Sprintf("Job has been running for longer than maxRunningTime of %s.", maxRunningTime))
+				continue
+			}
+
+			if !warned[j.JobID()] {
+				log.Warn(msg)
+				j.LogMessage(fmt.Sprintf("Job has been running for longer than maxRunningTime of %s.", maxRunningTime), log.WarnLevel)
+				warned[j.JobID()] = true
+			}
+		}
+
+		// forget jobs that are no longer running
+		for jid := range warned {
+			if !running[jid] {
+				delete(warned, jid)
+			}
+		}
+	}
+}
+
 // Constructor to create storage service based on the type provided
 func NewStorageService(providerType string) (*s3.S3, error) {
 
 	switch providerType {
 	case "minio":
+		// MinIO ignores the region, but the SDK requires one to sign requests
 		region := os.Getenv("MINIO_S3_REGION")
+		if region == "" {
+			region = "us-east-1"
+		}
 		accessKeyID := os.Getenv("MINIO_ACCESS_KEY_ID")
 		secretAccessKey := os.Getenv("MINIO_SECRET_ACCESS_KEY")
 		endpoint := os.Getenv("MINIO_S3_ENDPOINT")
@@ -200,19 +643,29 @@ func NewStorageService(providerType string) (*s3.S3, error) {
 		return s3.New(sess), nil
 
 	case "aws-s3":
-		region := os.Getenv("AWS_REGION")
-		accessKeyID := os.Getenv("AWS_ACCESS_KEY_ID")
-		secretAccessKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
-
-		sess, err := session.NewSession(&aws.Config{
-			Region:      aws.String(region),
-			Credentials: credentials.NewStaticCredentials(accessKeyID, secretAccessKey, ""),
-		})
+		// credentials are resolved with the default AWS credential chain so that IAM roles work without static keys
+		cfg := aws.Config{}
+		if region := os.Getenv("AWS_REGION"); region != "" {
+			cfg.Region = aws.String(region)
+		}
+		// custom endpoint for S3-compatible stores, these usually need path-style addressing as well
+		if endpoint := os.Getenv("AWS_S3_ENDPOINT"); endpoint != "" {
+			cfg.Endpoint = aws.String(endpoint)
+		}
+		pathStyle, err := strconv.ParseBool(resolveValue("AWS_S3_FORCE_PATH_STYLE", "false"))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing AWS_S3_FORCE_PATH_STYLE: %s", err.Error())
+		}
+		cfg.S3ForcePathStyle = aws.Bool(pathStyle)
+		sess, err := session.NewSession(&cfg)
 		if err != nil {
 			return nil, fmt.Errorf("error creating s3 session: %s", err.Error())
 		}
 		return s3.New(sess), nil
 
+	case "memory":
+		return utils.NewMemoryS3()
+
 	default:
 		return nil, fmt.Errorf("unsupported storage provider type")
 	}
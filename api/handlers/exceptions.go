@@ -0,0 +1,10 @@
+package handlers
+
+// OGC API - Processes exception types, set as the type of error responses so that clients can recognize the failure.
+// https://docs.ogc.org/is/18-062r2/18-062r2.html
+const (
+	exceptionNoSuchProcess         = "http://www.opengis.net/def/exceptions/ogcapi-processes-1/1.0/no-such-process"
+	exceptionNoSuchJob             = "http://www.opengis.net/def/exceptions/ogcapi-processes-1/1.0/no-such-job"
+	exceptionResultNotReady        = "http://www.opengis.net/def/exceptions/ogcapi-processes-1/1.0/result-not-ready"
+	exceptionInvalidParameterValue = "http://www.opengis.net/def/exceptions/ogcapi-processes-1/1.0/invalid-parameter-value"
+)
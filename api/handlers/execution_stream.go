@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"app/jobs"
+	"app/processes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Stream process logs of a sync job as NDJSON while it runs, each log entry is a line. Once the job is finished
+// the results document is written as the last line. The status code is sent before the job finishes, so an
+// unsuccessful job is only reported through the status of the results document.
+// If the client disconnects the job keeps running, its results can be fetched through the results route.
+func (rh *RESTHandler) streamSyncExecution(c echo.Context, p processes.Process, j jobs.Job, outputIDs []string, transmissionModes map[string]string, resp jobResponse) error {
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, mimeNDJSON)
+	res.Header().Set("Cache-Control", "no-cache")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	done := make(chan struct{})
+	go func() {
+		j.WaitForRunCompletion()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(logStreamInterval)
+	defer ticker.Stop()
+
+	enc := json.NewEncoder(res)
+	offset := 0
+	writeLogs := func(logs []jobs.LogEntry) error {
+		for ; offset < len(logs); offset++ {
+			// encoder terminates every entry with a newline
+			if err := enc.Encode(logs[offset]); err != nil {
+				return err
+			}
+		}
+		res.Flush()
+		return nil
+	}
+
+	for running := true; running; {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-done:
+			running = false
+		case <-ticker.C:
+			logs, err := rh.fetchJobLogs(j.JobID())
+			if err != nil {
+				continue
+			}
+			if err = writeLogs(logs.ProcessLogs); err != nil {
+				return nil
+			}
+		}
+	}
+
+	// cached logs may miss the last lines, so final logs are fetched directly
+	if logs, err := jobs.FetchLogs(rh.StorageSvc, j.JobID(), false); err == nil {
+		if err = writeLogs(logs.ProcessLogs); err != nil {
+			return nil
+		}
+	}
+
+	resp.Status = j.CurrentStatus()
+	if resp.Status != jobs.SUCCESSFUL {
+		resp.Message = "job unsuccessful. Call logs route for details"
+		return enc.Encode(resp)
+	}
+
+	outputs, err := rh.fetchSyncOutputs(p, j.JobID())
+	if err == nil {
+		err = rh.setSyncOutputs(p, outputs, outputIDs, transmissionModes, &resp)
+	}
+	if err != nil {
+		resp.Message = "error fetching results. Error: " + err.Error()
+	}
+	return enc.Encode(resp)
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"app/controllers"
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// This routine removes exited job containers every hour once they finished longer than FailedContainerTTL ago.
+// Containers of jobs that finished normally are removed when the job closes, so these are mostly containers kept for
+// failed jobs of processes with keepFailedContainers.
+func (rh *RESTHandler) FailedContainersCleanupRoutine() {
+	for {
+		time.Sleep(time.Hour)
+		if !rh.hasDockerProcesses() {
+			continue
+		}
+
+		c, err := controllers.NewDockerController()
+		if err != nil {
+			log.Errorf("could not clean up failed containers: %s", err.Error())
+			continue
+		}
+		exited, err := c.ExitedContainers(context.TODO(), controllers.JobIDLabel)
+		if err != nil {
+			log.Errorf("could not list exited containers: %s", err.Error())
+			continue
+		}
+
+		removed := 0
+		for id, finished := range exited {
+			if time.Since(finished) < rh.Config.FailedContainerTTL {
+				continue
+			}
+			if err = c.ContainerRemove(context.TODO(), id); err != nil {
+				log.Errorf("could not remove container %s: %s", id, err.Error())
+				continue
+			}
+			removed++
+		}
+		if removed > 0 {
+			log.Infof("removed %d exited job containers", removed)
+		}
+	}
+}
+
+func (rh *RESTHandler) hasDockerProcesses() bool {
+	for _, p := range rh.ProcessList.Processes() {
+		if p.Host.Type == "docker" {
+			return true
+		}
+	}
+	return false
+}
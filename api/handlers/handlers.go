@@ -9,43 +9,69 @@ package handlers
 
 import (
 	"app/jobs"
+	"app/processes"
 	"app/utils"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
 )
 
 // base error
 type errResponse struct {
-	HTTPStatus int    `json:"-"`
-	Message    string `json:"message"`
+	HTTPStatus int `json:"-" yaml:"-"`
+	// OGC exception type URI of the error, see exceptions.go
+	Type    string `json:"type,omitempty" yaml:"type,omitempty"`
+	Message string `json:"message" yaml:"message"`
 }
 
 // jobResponse store response of different job endpoints
 type jobResponse struct {
-	Type       string      `default:"process" json:"type,omitempty"`
-	JobID      string      `json:"jobID"`
-	LastUpdate time.Time   `json:"updated,omitempty"`
-	Status     string      `json:"status,omitempty"`
-	ProcessID  string      `json:"processID,omitempty"`
-	Message    string      `json:"message,omitempty"`
-	Outputs    interface{} `json:"outputs,omitempty"`
+	Type       string      `default:"process" json:"type,omitempty" yaml:"type,omitempty"`
+	JobID      string      `json:"jobID" yaml:"jobID"`
+	LastUpdate time.Time   `json:"updated,omitempty" yaml:"updated,omitempty"`
+	Status     string      `json:"status,omitempty" yaml:"status,omitempty"`
+	ProcessID  string      `json:"processID,omitempty" yaml:"processID,omitempty"`
+	Message    string      `json:"message,omitempty" yaml:"message,omitempty"`
+	Outputs    interface{} `json:"outputs,omitempty" yaml:"outputs,omitempty"`
+	Links      []link      `json:"links,omitempty" yaml:"links,omitempty"`
+	// Partial outputs are intermediate results of a running job, these are not final
+	Partial bool `json:"partial,omitempty" yaml:"partial,omitempty"`
+	// Storage location results are written to, when requested through outputPrefix
+	OutputLocation string `json:"outputLocation,omitempty" yaml:"outputLocation,omitempty"`
+	// Scheduling priority of the job, higher priority jobs are started first
+	Priority *int `json:"priority,omitempty" yaml:"priority,omitempty"`
+	// CacheHit jobs reuse results of a previous job with the same inputs, the process was not executed
+	CacheHit bool `json:"cacheHit,omitempty" yaml:"cacheHit,omitempty"`
+	// OutputsByReference is set when outputs of a sync execution exceed the maximum inline size and are returned as references
+	OutputsByReference bool `json:"outputsByReference,omitempty" yaml:"outputsByReference,omitempty"`
+	// Digests of results clients can verify results with
+	Integrity *jobs.ResultsIntegrity `json:"integrity,omitempty" yaml:"integrity,omitempty"`
+	// Results are no longer available after ExpiresAt
+	ExpiresAt *time.Time `json:"expiresAt,omitempty" yaml:"expiresAt,omitempty"`
+	// Image and command the job ran with, secrets redacted. Only set for operators, see JobStatusCommand
+	Image           string   `json:"image,omitempty" yaml:"image,omitempty"`
+	CommandOverride []string `json:"commandOverride,omitempty" yaml:"commandOverride,omitempty"`
 }
 
 type link struct {
-	Href  string `json:"href"`
-	Rel   string `json:"rel,omitempty"`
-	Type  string `json:"type,omitempty"`
-	Title string `json:"title,omitempty"`
+	Href  string `json:"href" yaml:"href"`
+	Rel   string `json:"rel,omitempty" yaml:"rel,omitempty"`
+	Type  string `json:"type,omitempty" yaml:"type,omitempty"`
+	Title string `json:"title,omitempty" yaml:"title,omitempty"`
 }
 
 // StatusText returns a text for the HTTP status code. It returns the empty
@@ -54,6 +80,30 @@ func (er errResponse) GetHTTPStatusText() string {
 	return http.StatusText(er.HTTPStatus)
 }
 
+// Check if the requesting user can access the job submitted by submitter with submitterID.
+// Admins can access all jobs, other users can only access the jobs they submitted.
+// Submitters are matched by the recorded user ID, jobs recorded without one are matched by email.
+// Handlers should respond with not found for jobs that can't be accessed, so that existence of the job is not leaked.
+func (rh *RESTHandler) jobReadable(c echo.Context, submitter, submitterID string) bool {
+	owner := rh.jobOwner(c)
+	return owner == nil || owner.Owns(submitter, submitterID)
+}
+
+// Caller whose jobs are readable, nil if all jobs are readable because auth is disabled or the caller is an admin
+func (rh *RESTHandler) jobOwner(c echo.Context) *jobs.Owner {
+	if rh.Config.AuthLevel > 0 {
+		roles := strings.Split(c.Request().Header.Get("X-ProcessAPI-User-Roles"), ",")
+		if utils.StringInSlice(rh.Config.AdminRoleName, roles) {
+			return nil
+		}
+		return &jobs.Owner{
+			SubmitterID: c.Request().Header.Get("X-ProcessAPI-User-ID"),
+			Submitter:   c.Request().Header.Get("X-ProcessAPI-User-Email"),
+		}
+	}
+	return nil
+}
+
 var validFormats = []string{"", "json", "html"}
 
 // Check if format query parameter is allowed.
@@ -74,16 +124,21 @@ func prepareResponse(c echo.Context, httpStatus int, renderName string, output i
 	outputFormat := c.QueryParam("f")
 	switch outputFormat {
 	case "html":
+		if !htmlEnabled(c) {
+			return c.JSON(http.StatusNotAcceptable, errResponse{Message: "HTML responses are not available, templates are not loaded. Use 'f=json'."})
+		}
 		return c.Render(httpStatus, renderName, output)
 	case "json":
 		return c.JSON(httpStatus, output)
+	case "yaml":
+		return yamlResponse(c, httpStatus, output)
 	default:
 		accept := c.Request().Header.Get("Accept")
 		if strings.Contains(accept, "application/json") {
 			// Browsers generally send text/html as an accept header
 			// return c.Render(httpStatus, renderName, output)
 			return c.JSON(httpStatus, output)
-		} else if strings.Contains(accept, "text/html") {
+		} else if strings.Contains(accept, "text/html") && htmlEnabled(c) {
 			// Browsers generally send text/html as an accept header
 			return c.Render(httpStatus, renderName, output)
 		} else {
@@ -93,11 +148,123 @@ func prepareResponse(c echo.Context, httpStatus int, renderName string, output i
 	}
 }
 
+// Check if HTML responses can be rendered, templates are not loaded in JSON only deployments
+func htmlEnabled(c echo.Context) bool {
+	t, ok := c.Echo().Renderer.(*Template)
+	return ok && t.templates != nil
+}
+
+// Respond with output serialized as YAML, using the same field names as JSON responses
+func yamlResponse(c echo.Context, httpStatus int, output interface{}) error {
+	b, err := yaml.Marshal(output)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, errResponse{Message: err.Error()})
+	}
+	return c.Blob(httpStatus, "application/yaml", b)
+}
+
 // runRequestBody provides the required inputs for containerized processes
 // specs: https://developer.ogc.org/api/processes/index.html#tag/Execute
 type runRequestBody struct {
-	Inputs  map[string]interface{} `json:"inputs"`
-	EnvVars map[string]string      `json:"environmentVariables"`
+	Inputs  map[string]interface{}   `json:"inputs"`
+	Outputs map[string]outputRequest `json:"outputs"`
+	EnvVars map[string]string        `json:"env"`
+	// Overrides default resources of the process, up to maxResources
+	Resources *processes.Resources `json:"resources"`
+	// Storage key prefix to write results to, namespaced under the job ID unless allowlisted
+	OutputPrefix string `json:"outputPrefix"`
+	// `document` (default) wraps results in a job response, `raw` returns the single output of sync processes directly
+	Response string `json:"response"`
+	// Overrides default priority of the process, higher priority jobs are scheduled first
+	Priority *int `json:"priority"`
+	// Custom fields written to the metadata document of the job
+	Metadata map[string]interface{} `json:"metadata"`
+	// X-Request-ID of the execution request, set by the server
+	RequestID string `json:"-"`
+	// Href inputs downloaded by the job when it starts, set by the server
+	Downloads []jobs.InputDownload `json:"-"`
+}
+
+// outputRequest allows overriding the process default transmission mode per output
+type outputRequest struct {
+	TransmissionMode string `json:"transmissionMode"`
+}
+
+// Sorted IDs of outputs listed in an execution request, nil if no output is listed
+func requestedOutputIDs(requested map[string]outputRequest) []string {
+	if len(requested) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(requested))
+	for id := range requested {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Keep only outputs listed in ids, all outputs are kept if ids is empty.
+// Outputs that are not a map keyed by output id are returned unchanged.
+func selectOutputs(outputs interface{}, ids []string) interface{} {
+	results, ok := outputs.(map[string]interface{})
+	if !ok || len(ids) == 0 {
+		return outputs
+	}
+
+	selected := make(map[string]interface{}, len(ids))
+	for _, id := range ids {
+		if val, ok := results[id]; ok {
+			selected[id] = val
+		}
+	}
+	return selected
+}
+
+// Replace outputs requested by reference with a link to the job results.
+// Outputs stored in the storage bucket are linked to their download route.
+// Outputs that are not a map keyed by output id are returned unchanged.
+func applyTransmissionModes(outputs interface{}, modes map[string]string, jobID string) interface{} {
+	results, ok := outputs.(map[string]interface{})
+	if !ok {
+		return outputs
+	}
+
+	for id, mode := range modes {
+		if val, ok := results[id]; ok && mode == "reference" {
+			if _, stored := outputStorageKey(val); stored {
+				results[id] = link{Href: fmt.Sprintf("/jobs/%s/results/%s", jobID, id), Rel: "enclosure", Title: id}
+				continue
+			}
+			results[id] = link{Href: fmt.Sprintf("/jobs/%s/results", jobID), Rel: "results", Type: "application/json", Title: id}
+		}
+	}
+	return results
+}
+
+// Replace outputs of a sync execution response with references when they exceed the maximum inline size.
+// Outputs that are already references are kept, outputs that are not a map keyed by output id are replaced with a link to the job results.
+func (rh *RESTHandler) limitInlineOutputs(resp *jobResponse) {
+	if rh.Config.MaxSyncResultsSize <= 0 || resp.Outputs == nil {
+		return
+	}
+	b, err := json.Marshal(resp.Outputs)
+	if err != nil || int64(len(b)) <= rh.Config.MaxSyncResultsSize {
+		return
+	}
+
+	if results, ok := resp.Outputs.(map[string]interface{}); ok {
+		modes := make(map[string]string, len(results))
+		for id, val := range results {
+			if _, isLink := val.(link); !isLink {
+				modes[id] = "reference"
+			}
+		}
+		resp.Outputs = applyTransmissionModes(results, modes, resp.JobID)
+	} else {
+		resp.Outputs = link{Href: fmt.Sprintf("/jobs/%s/results", resp.JobID), Rel: "results", Type: "application/json"}
+	}
+	resp.OutputsByReference = true
+	resp.Message = fmt.Sprintf("outputs exceed the maximum inline size of %d bytes and are returned by reference", rh.Config.MaxSyncResultsSize)
 }
 
 // LandingPage godoc
@@ -142,56 +309,62 @@ func (rh *RESTHandler) Conformance(c echo.Context) error {
 	return prepareResponse(c, http.StatusOK, "conformance", output)
 }
 
-// @Summary Execute Process
-// @Description [Execute Process Specification](https://docs.ogc.org/is/18-062r2/18-062r2.html#sc_create_job)
-// @Tags processes
-// @Accept json
-// @Produce json
-// @Param processID path string true "pyecho"
-// @Param inputs body string true "example: {inputs: {text:Hello World!}} (add double quotes for all strings in the payload)"
-// @Success 200 {object} jobResponse
-// @Router /processes/{processID}/execution [post]
-// Does not produce HTML
-func (rh *RESTHandler) Execution(c echo.Context) error {
-	processID := c.Param("processID")
-
-	if processID == "" {
-		return c.JSON(http.StatusBadRequest, errResponse{Message: "'processID' parameter is required"})
+// Signal clients executing a deprecated process with Deprecation and Sunset (RFC 8594) headers
+func setDeprecationHeaders(c echo.Context, p processes.Process) {
+	if !p.Info.Deprecated {
+		return
 	}
-
-	p, _, err := rh.ProcessList.Get(processID)
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, errResponse{Message: "'processID' incorrect"})
+	c.Response().Header().Set("Deprecation", "true")
+	if sunset, ok := p.Info.SunsetTime(); ok {
+		c.Response().Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
 	}
+}
 
-	if rh.Config.AuthLevel > 0 {
-		roles := strings.Split(c.Request().Header.Get("X-ProcessAPI-User-Roles"), ",")
-
-		// admins are allowed to execute all processes, else you need to have a role with same name as processId
-		if !utils.StringInSlice(rh.Config.AdminRoleName, roles) && !utils.StringInSlice(processID, roles) {
-			return c.JSON(http.StatusForbidden, errResponse{Message: "Forbidden"})
-		}
+// Check results of a job against output schemas of its process
+func (rh *RESTHandler) validateJobOutputs(p processes.Process, jobID string) error {
+	var outputs interface{}
+	var err error
+	if p.OutputsSource == "stdout" {
+		outputs, err = jobs.FetchStdoutResults(jobID)
+	} else {
+		outputs, err = jobs.FetchResults(rh.StorageSvc, jobID)
 	}
-
-	var params runRequestBody
-	err = c.Bind(&params)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, errResponse{Message: err.Error()})
-	}
-
-	if params.Inputs == nil {
-		return c.JSON(http.StatusBadRequest, errResponse{Message: "'inputs' is required in the body of the request"})
+		return fmt.Errorf("could not fetch results: %s", err.Error())
 	}
+	return p.ValidateOutputs(outputs)
+}
 
-	err = p.VerifyInputs(params.Inputs)
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, errResponse{Message: err.Error()})
+// ID for a new job of the process, IDs that are not unique by format are regenerated while a job with the ID exists
+func (rh *RESTHandler) newJobID(processID string) (string, error) {
+	for i := 0; i < 5; i++ {
+		jobID := jobs.NewJobID(processID)
+		if !jobs.JobIDsMayCollide() {
+			return jobID, nil
+		}
+		exists, err := rh.DB.CheckJobExist(jobID)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return jobID, nil
+		}
 	}
+	return "", fmt.Errorf("could not generate a unique job ID")
+}
 
-	jsonParams, err := json.Marshal(params.Inputs)
+// Create job of the process host type for the given request.
+// Assumes request has been verified against the process.
+func (rh *RESTHandler) newJob(p processes.Process, params runRequestBody, jobID, submitter, submitterID string) (jobs.Job, error) {
+	// HTML characters are not escaped so that string values reach the process as sent
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(params.Inputs)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, errResponse{Message: err.Error()})
+		return nil, err
 	}
+	jsonParams := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
 
 	// If `"Inputs": {}` in `/execution` payload. Nothing will be appended to process commands.
 	// This allow running processes that do not have any inputs.
@@ -199,16 +372,49 @@ func (rh *RESTHandler) Execution(c echo.Context) error {
 	if p.Command != nil {
 		cmd = append(cmd, p.Command...)
 	}
-	if string(jsonParams) != "{}" {
+	if len(p.CommandTemplate) > 0 {
+		args, err := p.RenderCommandTemplate(jobID, params.Inputs)
+		if err != nil {
+			return nil, err
+		}
+		cmd = append(cmd, args...)
+	} else if string(jsonParams) != "{}" {
 		cmd = append(cmd, string(jsonParams))
 	}
 
-	mode := p.Info.JobControlOptions[0]
-	host := p.Host.Type
+	resources, err := p.ResolveResources(params.Resources)
+	if err != nil {
+		return nil, err
+	}
 
-	// ----------- Process related setup is complete at this point ---------
+	priority, err := p.ResolvePriority(params.Priority)
+	if err != nil {
+		return nil, err
+	}
 
-	jobID := uuid.New().String()
+	outputLocation, err := rh.resolveOutputLocation(jobID, params.OutputPrefix)
+	if err != nil {
+		return nil, err
+	}
+	serverEnv := map[string]string{}
+	if outputLocation != "" {
+		serverEnv[outputLocationEnvVar] = outputLocation
+	}
+	if p.DefaultResultContentType != "" {
+		serverEnv[resultContentTypeEnvVar] = p.DefaultResultContentType
+	}
+	envVars := params.EnvVars
+	if len(serverEnv) > 0 {
+		envVars = make(map[string]string, len(params.EnvVars)+len(serverEnv))
+		for k, v := range params.EnvVars {
+			envVars[k] = v
+		}
+		for k, v := range serverEnv {
+			envVars[k] = v
+		}
+	}
+
+	host := p.Host.Type
 
 	// switch host {
 	// case "docker":
@@ -217,34 +423,78 @@ func (rh *RESTHandler) Execution(c echo.Context) error {
 	// 	params.Inputs["resultsCallbackUri"] = fmt.Sprintf("%s/jobs/%s/results_update", os.Getenv("API_URL_PUBLIC"), jobID)
 	// }
 
-	submitter := c.Request().Header.Get("X-ProcessAPI-User-Email")
+	stopTimeout := 10 * time.Second
+	if p.Config.StopTimeout != nil {
+		stopTimeout = time.Duration(*p.Config.StopTimeout) * time.Second
+	}
+	var inactivityTimeout time.Duration
+	if p.Config.InactivityTimeout != nil {
+		inactivityTimeout = time.Duration(*p.Config.InactivityTimeout) * time.Second
+	}
+	var resultsCheck *jobs.ResultsCheck
+	if p.HasOutputSchemas() {
+		resultsCheck = &jobs.ResultsCheck{
+			Validate: func(jid string) error { return rh.validateJobOutputs(p, jid) },
+			Fail:     p.Config.OutputValidation == "fail",
+		}
+	}
+
+	// staged file inputs are passed to the process as paths under the job inputs directory
+	var inputsDir, inputsHostDir string
+	if rh.Config.InputsDir != "" {
+		inputsDir = filepath.Join(rh.Config.InputsDir, jobID)
+		inputsHostDir = filepath.Join(rh.Config.InputsHostDir, jobID)
+	}
+
 	var j jobs.Job
 	switch host {
 	case "docker":
 		j = &jobs.DockerJob{
-			UUID:           jobID,
-			ProcessName:    processID,
-			ProcessVersion: p.Info.Version,
-			Image:          p.Host.Image,
-			Submitter:      submitter,
-			EnvVars:        p.Config.EnvVars,
-			Resources:      jobs.Resources(p.Config.Resources),
-			Cmd:            cmd,
-			StorageSvc:     rh.StorageSvc,
-			DB:             rh.DB,
-			DoneChan:       rh.MessageQueue.JobDone,
+			UUID:                jobID,
+			ProcessName:         p.Info.ID,
+			ProcessVersion:      p.Info.Version,
+			Image:               p.Host.Image,
+			Submitter:           submitter,
+			SubmitterID:         submitterID,
+			EnvVars:             p.Config.EnvVars,
+			EnvOverrides:        envVars,
+			OutputLocation:      outputLocation,
+			RequestID:           params.RequestID,
+			Metadata:            params.Metadata,
+			ResultsCheck:        resultsCheck,
+			User:                p.Config.User,
+			InactivityTimeout:   inactivityTimeout,
+			KeepFailedContainer: p.Config.KeepFailedContainers,
+			InputsDir:           inputsDir,
+			InputsHostDir:       inputsHostDir,
+			Downloads:           params.Downloads,
+			Priority:            priority,
+			Resources:           jobs.Resources(resources),
+			Cmd:                 cmd,
+			StopTimeout:         stopTimeout,
+			StorageSvc:          rh.StorageSvc,
+			DB:                  rh.DB,
+			DoneChan:            rh.MessageQueue.JobDone,
 		}
 
 	case "aws-batch":
 		j = &jobs.AWSBatchJob{
 			UUID:           jobID,
-			ProcessName:    processID,
+			ProcessName:    p.Info.ID,
 			Image:          p.Host.Image,
 			Submitter:      submitter,
+			SubmitterID:    submitterID,
 			Cmd:            cmd,
 			JobDef:         p.Host.JobDefinition,
 			JobQueue:       p.Host.JobQueue,
-			JobName:        fmt.Sprintf("%s_%s", rh.Name, jobID),
+			JobName:        jobs.BatchJobName(rh.Config.BatchJobNameTemplate, rh.Name, p.Info.ID, jobID),
+			EnvVars:        envVars,
+			OutputLocation: outputLocation,
+			RequestID:      params.RequestID,
+			Metadata:       params.Metadata,
+			ResultsCheck:   resultsCheck,
+			Priority:       priority,
+			Resources:      jobs.Resources(p.ResourceOverrides(params.Resources)),
 			ProcessVersion: p.Info.Version,
 			StorageSvc:     rh.StorageSvc,
 			DB:             rh.DB,
@@ -252,44 +502,354 @@ func (rh *RESTHandler) Execution(c echo.Context) error {
 		}
 
 	case "subprocess":
+		envList := make([]string, 0, len(envVars))
+		for k, v := range envVars {
+			envList = append(envList, k+"="+v)
+		}
 		j = &jobs.SubprocessJob{
 			UUID:           jobID,
-			ProcessName:    processID,
+			ProcessName:    p.Info.ID,
 			Submitter:      submitter,
+			SubmitterID:    submitterID,
 			Cmd:            cmd,
+			EnvVars:        envList,
+			OutputLocation: outputLocation,
+			RequestID:      params.RequestID,
+			Metadata:       params.Metadata,
+			ResultsCheck:   resultsCheck,
+			InputsDir:      inputsDir,
+			Downloads:      params.Downloads,
+			Priority:       priority,
 			ProcessVersion: p.Info.Version,
 			StorageSvc:     rh.StorageSvc,
 			DB:             rh.DB,
 			DoneChan:       rh.MessageQueue.JobDone,
 		}
+
+	default:
+		return nil, fmt.Errorf("unsupported host type: %s", host)
+	}
+
+	return j, nil
+}
+
+// Host of job records of a process
+func recordHost(p processes.Process) string {
+	if p.Host.Type == "aws-batch" {
+		return "aws-batch"
+	}
+	return "local"
+}
+
+// Persist a job that failed to be created so that its status and logs can be queried afterwards
+func (rh *RESTHandler) recordCreateFailure(p processes.Process, j jobs.Job, submitterID string, createErr error) {
+	jr := jobs.JobRecord{JobID: j.JobID(), ProcessID: p.Info.ID, Host: recordHost(p), Submitter: j.SUBMITTER(), SubmitterID: submitterID}
+	err := jobs.RecordCreateFailure(rh.DB, rh.StorageSvc, jr, createErr)
+	if err != nil {
+		log.Errorf("Could not record failed job %s. Error: %s", j.JobID(), err.Error())
+	}
+}
+
+// Fetch results of a successful sync job, from stdout or storage depending on the outputs source of the process
+func (rh *RESTHandler) fetchSyncOutputs(p processes.Process, jobID string) (outputs interface{}, err error) {
+	if p.OutputsSource == "stdout" {
+		return jobs.FetchStdoutResults(jobID)
+	} else if p.Outputs != nil {
+		return jobs.FetchResults(rh.StorageSvc, jobID)
+	}
+	return nil, nil
+}
+
+// Set outputs of a sync job response, keeping only outputIDs if any are requested and applying the result hook,
+// transmission modes and the maximum inline size.
+func (rh *RESTHandler) setSyncOutputs(p processes.Process, outputs interface{}, outputIDs []string, transmissionModes map[string]string, resp *jobResponse) error {
+	outputs, err := p.ApplyResultHook(resp.JobID, selectOutputs(outputs, outputIDs))
+	if err != nil {
+		return err
+	}
+	resp.Outputs = applyTransmissionModes(outputs, transmissionModes, resp.JobID)
+	// stdout results are not available through the results route, so these are always inline
+	if p.OutputsSource != "stdout" {
+		rh.limitInlineOutputs(resp)
+	}
+	return nil
+}
+
+// Respond with the single output of a sync job.
+// Outputs stored in the storage bucket are streamed with their content type as an attachment. Other outputs are
+// returned as JSON, unless the process has a non JSON default result content type and the output is a string.
+// The document rendered by the result hook of the process is returned as is.
+func (rh *RESTHandler) rawResponse(c echo.Context, p processes.Process, outputs interface{}, resp jobResponse) error {
+	if p.ResultHook != nil {
+		outputs, err := p.ApplyResultHook(resp.JobID, outputs)
+		if err != nil {
+			resp.Message = "error fetching results. Error: " + err.Error()
+			return c.JSON(http.StatusInternalServerError, resp)
+		}
+		return c.JSON(http.StatusOK, outputs)
+	}
+
+	results, _ := outputs.(map[string]interface{})
+	val, ok := results[p.Outputs[0].ID]
+	if !ok {
+		resp.Message = fmt.Sprintf("output %s not found in results", p.Outputs[0].ID)
+		return c.JSON(http.StatusInternalServerError, resp)
+	}
+
+	key, ok := outputStorageKey(val)
+	if !ok {
+		if s, isString := val.(string); isString && !isJSONMediaType(p.ResultContentType()) {
+			return c.Blob(http.StatusOK, p.ResultContentType(), []byte(s))
+		}
+		return c.JSON(http.StatusOK, val)
+	}
+	return rh.streamStorageObject(c, key, "", path.Base(key), p.DefaultResultContentType)
+}
+
+// @Summary Execute Default Process
+// @Description Executes the process configured with DEFAULT_PROCESS, see /processes/{processID}/execution
+// @Tags processes
+// @Accept json,mpfd
+// @Produce json
+// @Param inputs body string true "example: {inputs: {text:Hello World!}} (add double quotes for all strings in the payload)"
+// @Success 200 {object} jobResponse
+// @Router /execution [post]
+func (rh *RESTHandler) DefaultExecution(c echo.Context) error {
+	c.SetParamNames("processID")
+	c.SetParamValues(rh.Config.DefaultProcess)
+	return rh.Execution(c)
+}
+
+// @Summary Execute Process
+// @Description [Execute Process Specification](https://docs.ogc.org/is/18-062r2/18-062r2.html#sc_create_job)
+// @Tags processes
+// @Accept json,mpfd
+// @Produce json
+// @Param processID path string true "pyecho"
+// @Param inputs body string true "example: {inputs: {text:Hello World!}} (add double quotes for all strings in the payload)"
+// @Param stream query bool false "stream process logs of sync processes as NDJSON, followed by the results document"
+// @Success 200 {object} jobResponse
+// @Router /processes/{processID}/execution [post]
+// Does not produce HTML
+func (rh *RESTHandler) Execution(c echo.Context) error {
+	processID := c.Param("processID")
+
+	if processID == "" {
+		return c.JSON(http.StatusBadRequest, errResponse{Message: "'processID' parameter is required"})
+	}
+
+	p, _, err := rh.ProcessList.Get(processID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionNoSuchProcess, Message: "'processID' incorrect"})
+	}
+	setDeprecationHeaders(c, p)
+
+	if rh.Config.AuthLevel > 0 {
+		roles := strings.Split(c.Request().Header.Get("X-ProcessAPI-User-Roles"), ",")
+
+		// admins are allowed to execute all processes, else you need to have a role with same name as processId
+		if !utils.StringInSlice(rh.Config.AdminRoleName, roles) && !utils.StringInSlice(p.Info.ID, roles) {
+			return c.JSON(http.StatusForbidden, errResponse{Message: "Forbidden"})
+		}
+	}
+
+	jobID, err := rh.newJobID(p.Info.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, errResponse{Message: err.Error()})
+	}
+
+	// staged inputs are removed by the job once it is created, until then by the handler
+	jobCreated := false
+	defer func() {
+		if !jobCreated && rh.Config.InputsDir != "" {
+			os.RemoveAll(filepath.Join(rh.Config.InputsDir, jobID))
+		}
+	}()
+
+	var params runRequestBody
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		params, err = rh.bindMultipartRequest(c, p, jobID)
+	} else {
+		err = bindJSONRequest(c, &params)
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Message: err.Error()})
+	}
+
+	if params.Inputs == nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Message: "'inputs' is required in the body of the request"})
+	}
+	err = p.ApplyServerInputs(params.Inputs)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: err.Error()})
+	}
+	p.DecodeInputNumbers(params.Inputs)
+
+	err = rh.verifyInputLimits(params.Inputs)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: err.Error()})
+	}
+
+	err = p.NormalizeQualifiedInputs(params.Inputs)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: err.Error()})
+	}
+
+	// bbox coordinates must be normalized before verifying, otherwise they are counted as multiple occurrences
+	err = p.NormalizeBBoxInputs(params.Inputs)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: err.Error()})
+	}
+
+	err = p.VerifyInputs(params.Inputs)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: err.Error()})
+	}
+
+	err = p.VerifySafeInputs(params.Inputs)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: err.Error()})
+	}
+
+	err = p.VerifyEnvOverrides(params.EnvVars)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: err.Error()})
+	}
+
+	_, err = p.ResolveResources(params.Resources)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: err.Error()})
+	}
+
+	_, err = p.ResolvePriority(params.Priority)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: err.Error()})
+	}
+
+	err = jobs.ValidateCustomMetadata(params.Metadata, rh.Config.MaxJobMetadataSize)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: err.Error()})
+	}
+
+	outputLocation, err := rh.resolveOutputLocation(jobID, params.OutputPrefix)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: err.Error()})
+	}
+
+	switch params.Response {
+	case "", "document":
+	case "raw":
+		if len(p.Outputs) != 1 {
+			return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: "'raw' response is only supported for processes with a single output"})
+		}
+	default:
+		return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: "Invalid option for 'response'. Valid options are 'document' or 'raw'."})
+	}
+
+	// logs are streamed for sync processes only, async jobs respond immediately
+	stream := c.QueryParam("stream") == "true"
+	if stream && params.Response == "raw" {
+		return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: "'raw' response can not be streamed"})
+	}
+
+	requestedModes := make(map[string]string, len(params.Outputs))
+	for id, o := range params.Outputs {
+		requestedModes[id] = o.TransmissionMode
+	}
+	transmissionModes, err := p.ResolveOutputTransmission(requestedModes)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: err.Error()})
+	}
+	// unknown output IDs are rejected above, so only outputs of the process are selected
+	outputIDs := requestedOutputIDs(params.Outputs)
+
+	if err = p.CallValidationHook(params.Inputs); err != nil {
+		return validationHookError(c, err)
+	}
+
+	if ok, err := rh.checkJobQuota(c, 1); !ok {
+		return err
+	}
+
+	submitterID := c.Request().Header.Get("X-ProcessAPI-User-ID")
+
+	// Results are not reused when they are requested at an output location, these must be written by the job
+	var cacheKey string
+	if p.Config.CacheResults && params.OutputPrefix == "" {
+		submitter := submitterID
+		if submitter == "" {
+			submitter = c.Request().Header.Get("X-ProcessAPI-User-Email")
+		}
+		cacheKey, err = p.CacheKey(params.Inputs, params.EnvVars, submitter)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errResponse{Message: err.Error()})
+		}
+		if srcJobID, outputs, ok := rh.cachedResults(cacheKey); ok {
+			return rh.cacheHit(c, p, params, jobID, submitterID, srcJobID, outputs, outputIDs, transmissionModes)
+		}
+	}
+
+	params.Downloads, err = rh.hrefDownloads(p, params.Inputs, jobID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: err.Error()})
+	}
+
+	mode := p.Info.JobControlOptions[0]
+
+	// sync jobs would hold the request open until the scheduler is resumed, only async jobs are queued while paused
+	if mode == "sync-execute" && jobs.GetSchedulerState().Paused {
+		c.Response().Header().Set("Retry-After", strconv.Itoa(pausedRetryAfter))
+		return c.JSON(http.StatusServiceUnavailable, errResponse{Message: "job scheduler is paused, sync executions are not accepted until it is resumed"})
+	}
+
+	// ----------- Process related setup is complete at this point ---------
+
+	params.RequestID = c.Response().Header().Get(echo.HeaderXRequestID)
+	j, err := rh.newJob(p, params, jobID, c.Request().Header.Get("X-ProcessAPI-User-Email"), submitterID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, errResponse{Message: err.Error()})
 	}
 
 	// Create job
 	err = j.Create()
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, errResponse{Message: fmt.Sprintf("submission error %s", err.Error())})
+		rh.recordCreateFailure(p, j, submitterID, err)
+		return c.JSON(http.StatusInternalServerError, jobResponse{ProcessID: p.Info.ID, Type: "process", JobID: jobID, Status: jobs.FAILED, Message: fmt.Sprintf("submission error %s", err.Error())})
 	}
 
+	jobCreated = true
+	rh.recordSubmission(jobID, p, outputIDs)
+
 	// Add to active jobs
 	rh.ActiveJobs.Add(&j)
 
-	resp := jobResponse{ProcessID: j.ProcessID(), Type: "process", JobID: jobID, Status: j.CurrentStatus()}
+	if cacheKey != "" {
+		rh.cacheResults(cacheKey, jobID)
+	}
+
+	resp := jobResponse{ProcessID: j.ProcessID(), Type: "process", JobID: jobID, Status: j.CurrentStatus(), OutputLocation: outputLocation}
 	switch mode {
 	case "sync-execute":
+		if stream {
+			return rh.streamSyncExecution(c, p, j, outputIDs, transmissionModes, resp)
+		}
 		j.WaitForRunCompletion()
 		resp.Status = j.CurrentStatus()
 
 		if resp.Status == "successful" {
-			var outputs interface{}
-
-			if p.Outputs != nil {
-				outputs, err = jobs.FetchResults(rh.StorageSvc, j.JobID())
-				if err != nil {
-					resp.Message = "error fetching results. Error: " + err.Error()
-					return c.JSON(http.StatusInternalServerError, resp)
-				}
+			outputs, err := rh.fetchSyncOutputs(p, jobID)
+			if err != nil {
+				resp.Message = "error fetching results. Error: " + err.Error()
+				return c.JSON(http.StatusInternalServerError, resp)
+			}
+			if params.Response == "raw" {
+				return rh.rawResponse(c, p, outputs, resp)
+			}
+			err = rh.setSyncOutputs(p, outputs, outputIDs, transmissionModes, &resp)
+			if err != nil {
+				resp.Message = "error fetching results. Error: " + err.Error()
+				return c.JSON(http.StatusInternalServerError, resp)
 			}
-			resp.Outputs = outputs
 			return c.JSON(http.StatusOK, resp)
 		} else {
 			resp.Message = "job unsuccessful. Call logs route for details"
@@ -317,12 +877,9 @@ func (rh *RESTHandler) JobDismissHandler(c echo.Context) error {
 	jobID := c.Param("jobID")
 	if j, ok := rh.ActiveJobs.Jobs[jobID]; ok {
 
-		if rh.Config.AuthLevel > 0 {
-			roles := strings.Split(c.Request().Header.Get("X-ProcessAPI-User-Roles"), ",")
-
-			if (*j).SUBMITTER() != c.Request().Header.Get("X-ProcessAPI-User-Email") && !utils.StringInSlice(rh.Config.AdminRoleName, roles) {
-				return c.JSON(http.StatusForbidden, errResponse{Message: "Forbidden"})
-			}
+		// not found instead of forbidden, so that existence of other users' jobs is not leaked
+		if !rh.jobReadable(c, (*j).SUBMITTER(), (*j).SUBMITTERID()) {
+			return c.JSON(http.StatusNotFound, errResponse{Type: exceptionNoSuchJob, Message: fmt.Sprintf("job %s not in the active jobs list", jobID)})
 		}
 
 		err := (*j).Kill()
@@ -331,7 +888,7 @@ func (rh *RESTHandler) JobDismissHandler(c echo.Context) error {
 		}
 		return c.JSON(http.StatusOK, jobResponse{ProcessID: (*j).ProcessID(), Type: "process", JobID: jobID, Status: (*j).CurrentStatus(), Message: fmt.Sprintf("job %s dismissed", jobID)})
 	}
-	return c.JSON(http.StatusNotFound, errResponse{Message: fmt.Sprintf("job %s not in the active jobs list", jobID)})
+	return c.JSON(http.StatusNotFound, errResponse{Type: exceptionNoSuchJob, Message: fmt.Sprintf("job %s not in the active jobs list", jobID)})
 }
 
 // @Summary Job Status
@@ -340,49 +897,201 @@ func (rh *RESTHandler) JobDismissHandler(c echo.Context) error {
 // @Info [Format YAML](http://schemas.opengis.net/ogcapi/processes/part1/1.0/openapi/schemas/statusInfo.yaml)
 // @Accept */*
 // @Param jobID path string true "example: 44d9ca0e-2ca7-4013-907f-a8ccc60da3b4"
-// @Produce json
+// @Param f query string false "html, json or yaml"
+// @Produce json,application/yaml
 // @Success 200 {object} jobResponse
 // @Router /jobs/{jobID} [get]
 func (rh *RESTHandler) JobStatusHandler(c echo.Context) (err error) {
-	err = validateFormat(c)
+	// statusInfo is also available as YAML
+	if c.QueryParam("f") != "yaml" {
+		err = validateFormat(c)
+		if err != nil {
+			return err
+		}
+	}
+
+	jobID := c.Param("jobID")
+	resp, ok, err := rh.jobStatus(c, jobID)
+	if ok {
+		return prepareResponse(c, http.StatusOK, "jobStatus", resp)
+	}
+
 	if err != nil {
-		return err
+		output := errResponse{HTTPStatus: http.StatusInternalServerError, Message: err.Error()}
+		return prepareResponse(c, http.StatusInternalServerError, "error", output)
 	}
+	output := errResponse{HTTPStatus: http.StatusNotFound, Type: exceptionNoSuchJob, Message: fmt.Sprintf("%s job id not found", jobID)}
+	return prepareResponse(c, http.StatusNotFound, "error", output)
+}
 
+// Status of a job readable by the requester, from active jobs or the database.
+// ok is false if the job is not found or not readable.
+func (rh *RESTHandler) jobStatus(c echo.Context, jobID string) (resp jobResponse, ok bool, err error) {
 	var jRcrd jobs.JobRecord
-	jobID := c.Param("jobID")
-	if job, ok := rh.ActiveJobs.Jobs[jobID]; ok {
-		resp := jobResponse{
+	if job, found := rh.ActiveJobs.Jobs[jobID]; found && rh.jobReadable(c, (*job).SUBMITTER(), (*job).SUBMITTERID()) {
+		resp = jobResponse{
+			Type:       "process",
 			ProcessID:  (*job).ProcessID(),
 			JobID:      (*job).JobID(),
 			LastUpdate: (*job).LastUpdate(),
 			Status:     (*job).CurrentStatus(),
+			Links:      rh.jobLinks((*job).JobID()),
 		}
-		return prepareResponse(c, http.StatusOK, "jobStatus", resp)
-	} else if jRcrd, ok, err = rh.DB.GetJob(jobID); ok {
-		resp := jobResponse{
+		switch jj := (*job).(type) {
+		case *jobs.DockerJob:
+			resp.Priority = jj.Priority
+		case *jobs.SubprocessJob:
+			resp.Priority = jj.Priority
+		case *jobs.AWSBatchJob:
+			resp.Priority = jj.Priority
+		}
+		if rh.showJobCommand(c) {
+			resp.Image, resp.CommandOverride = (*job).IMAGE(), processes.RedactCommand((*job).CMD())
+		}
+		return resp, true, nil
+	} else if jRcrd, ok, err = rh.DB.GetJob(jobID); ok && rh.jobReadable(c, jRcrd.Submitter, jRcrd.SubmitterID) {
+		resp = jobResponse{
+			Type:       "process",
 			ProcessID:  jRcrd.ProcessID,
 			JobID:      jRcrd.JobID,
 			LastUpdate: jRcrd.LastUpdate,
 			Status:     jRcrd.Status,
+			Links:      rh.jobLinks(jRcrd.JobID),
+			ExpiresAt:  jRcrd.ExpiresAt,
 		}
-		return prepareResponse(c, http.StatusOK, "jobStatus", resp)
+		if rh.showJobCommand(c) {
+			rh.setCommandFromMetadata(&resp)
+		}
+		return resp, true, nil
+	}
+	return jobResponse{}, false, err
+}
+
+// Check if image and command of jobs are included in status documents for the requester.
+// These are only shown when enabled, and only to admins if authorization is enabled.
+func (rh *RESTHandler) showJobCommand(c echo.Context) bool {
+	if !rh.Config.JobStatusCommand {
+		return false
+	}
+	if rh.Config.AuthLevel > 0 {
+		roles := strings.Split(c.Request().Header.Get("X-ProcessAPI-User-Roles"), ",")
+		return utils.StringInSlice(rh.Config.AdminRoleName, roles)
 	}
+	return true
+}
 
+// Set image and command of a job that is no longer active from its metadata, nothing is set if metadata is not available
+func (rh *RESTHandler) setCommandFromMetadata(resp *jobResponse) {
+	md, err := jobs.FetchMeta(rh.StorageSvc, resp.JobID)
+	if err != nil {
+		return
+	}
+	m, _ := md.(map[string]interface{})
+	if img, ok := m["image"].(map[string]interface{}); ok {
+		resp.Image, _ = img["imageURI"].(string)
+	}
+	if cmds, ok := m["commands"].([]interface{}); ok {
+		args := make([]string, 0, len(cmds))
+		for _, cmd := range cmds {
+			if arg, ok := cmd.(string); ok {
+				args = append(args, arg)
+			}
+		}
+		resp.CommandOverride = processes.RedactCommand(args)
+	}
+}
+
+// @Summary Job Status Check
+// @Description Status of a job in the X-Job-Status header without a body, 404 if the job is not found
+// @Tags jobs
+// @Param jobID path string true "example: 44d9ca0e-2ca7-4013-907f-a8ccc60da3b4"
+// @Success 200
+// @Router /jobs/{jobID} [head]
+func (rh *RESTHandler) JobStatusHeadHandler(c echo.Context) error {
+	resp, ok, err := rh.jobStatus(c, c.Param("jobID"))
+	if err != nil {
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	if !ok {
+		return c.NoContent(http.StatusNotFound)
+	}
+	setJobStatusHeaders(c, resp)
+	return c.NoContent(http.StatusOK)
+}
+
+// @Summary Job Results Check
+// @Description Responds 200 without a body if results of a job are available, 404 otherwise. Status of the job is in the X-Job-Status header
+// @Tags jobs
+// @Param jobID path string true "example: 44d9ca0e-2ca7-4013-907f-a8ccc60da3b4"
+// @Success 200
+// @Router /jobs/{jobID}/results [head]
+func (rh *RESTHandler) JobResultsHeadHandler(c echo.Context) error {
+	resp, ok, err := rh.jobStatus(c, c.Param("jobID"))
+	if err != nil {
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	if !ok {
+		return c.NoContent(http.StatusNotFound)
+	}
+	setJobStatusHeaders(c, resp)
+	// same as GET, results are only served for successful jobs that are no longer active
+	if _, active := rh.ActiveJobs.Jobs[resp.JobID]; active || resp.Status != jobs.SUCCESSFUL {
+		return c.NoContent(http.StatusNotFound)
+	}
+	if resp.ExpiresAt != nil && time.Now().After(*resp.ExpiresAt) {
+		return c.NoContent(http.StatusGone)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func setJobStatusHeaders(c echo.Context, resp jobResponse) {
+	c.Response().Header().Set("X-Job-Status", resp.Status)
+	if !resp.LastUpdate.IsZero() {
+		c.Response().Header().Set(echo.HeaderLastModified, resp.LastUpdate.UTC().Format(http.TimeFormat))
+	}
+}
+
+// Links of a job status document to the job and its sub-resources
+func (rh *RESTHandler) jobLinks(jobID string) []link {
+	return []link{
+		{Href: rh.externalURL("/jobs/" + jobID), Rel: "self", Type: "application/json", Title: "status"},
+		{Href: rh.externalURL("/jobs/" + jobID + "/results"), Rel: "http://www.opengis.net/def/rel/ogc/1.0/results", Type: "application/json", Title: "results"},
+		{Href: rh.externalURL("/jobs/" + jobID + "/logs"), Rel: "related", Type: "application/json", Title: "logs"},
+		{Href: rh.externalURL("/jobs/" + jobID + "/metadata"), Rel: "related", Type: "application/json", Title: "metadata"},
+	}
+}
+
+// Respond with results written so far by a running job
+func (rh *RESTHandler) partialResults(c echo.Context, j jobs.Job) error {
+	err := j.UpdateProcessLogs()
 	if err != nil {
 		output := errResponse{HTTPStatus: http.StatusInternalServerError, Message: err.Error()}
 		return prepareResponse(c, http.StatusInternalServerError, "error", output)
 	}
-	output := errResponse{HTTPStatus: http.StatusNotFound, Message: fmt.Sprintf("%s job id not found", jobID)}
-	return prepareResponse(c, http.StatusNotFound, "error", output)
+
+	outputs, err := jobs.FetchPartialResults(rh.StorageSvc, j.JobID())
+	if err != nil {
+		if err.Error() == "not found" {
+			output := errResponse{HTTPStatus: http.StatusNotFound, Type: exceptionResultNotReady, Message: "no partial results available yet, job running"}
+			return prepareResponse(c, http.StatusNotFound, "error", output)
+		}
+		output := errResponse{HTTPStatus: http.StatusInternalServerError, Message: err.Error()}
+		return prepareResponse(c, http.StatusInternalServerError, "error", output)
+	}
+
+	output := jobResponse{JobID: j.JobID(), Status: j.CurrentStatus(), Outputs: outputs, Partial: true, Message: "partial results of a running job, these are not final"}
+	return prepareResponse(c, http.StatusOK, "jobResults", output)
 }
 
 // @Summary Job Results
 // @Description [Job Results Specification](https://docs.ogc.org/is/18-062r2/18-062r2.html#sc_retrieve_job_results)
+// @Description With Accept: application/x-ndjson records of results are streamed one per line, e.g. features of a feature collection
+// @Description With Accept: application/zip output files stored in storage are streamed as a ZIP archive, with a directory per output
 // @Tags jobs
 // @Accept */*
-// @Produce json
+// @Produce json,application/x-ndjson,application/zip
 // @Param jobID path string true "ex: 44d9ca0e-2ca7-4013-907f-a8ccc60da3b4"
+// @Param partial query bool false "return results written so far by a running job"
 // @Success 200 {object} map[string]interface{}
 // @Router /jobs/{jobID}/results [get]
 // Does not produce HTML
@@ -394,14 +1103,21 @@ func (rh *RESTHandler) JobResultsHandler(c echo.Context) (err error) {
 
 	var jRcrd jobs.JobRecord
 	jobID := c.Param("jobID")
-	if job, ok := rh.ActiveJobs.Jobs[jobID]; ok { // ActiveJobs hit
-		output := errResponse{HTTPStatus: http.StatusNotFound, Message: fmt.Sprintf("results not ready, job %s", (*job).CurrentStatus())}
+	if job, ok := rh.ActiveJobs.Jobs[jobID]; ok && rh.jobReadable(c, (*job).SUBMITTER(), (*job).SUBMITTERID()) { // ActiveJobs hit
+		if c.QueryParam("partial") == "true" && (*job).CurrentStatus() == jobs.RUNNING {
+			return rh.partialResults(c, *job)
+		}
+		output := errResponse{HTTPStatus: http.StatusNotFound, Type: exceptionResultNotReady, Message: fmt.Sprintf("results not ready, job %s", (*job).CurrentStatus())}
 		return prepareResponse(c, http.StatusNotFound, "error", output)
 
-	} else if jRcrd, ok, err = rh.DB.GetJob(jobID); ok { // db hit
+	} else if jRcrd, ok, err = rh.DB.GetJob(jobID); ok && rh.jobReadable(c, jRcrd.Submitter, jRcrd.SubmitterID) { // db hit
 
 		switch jRcrd.Status {
 		case jobs.SUCCESSFUL:
+			if jRcrd.ResultsExpired() {
+				output := errResponse{HTTPStatus: http.StatusGone, Message: fmt.Sprintf("results expired at %s", jRcrd.ExpiresAt.UTC().Format(time.RFC3339))}
+				return prepareResponse(c, http.StatusGone, "error", output)
+			}
 			outputs, err := jobs.FetchResults(rh.StorageSvc, jRcrd.JobID)
 			if err != nil {
 				if err.Error() == "not found" {
@@ -411,7 +1127,32 @@ func (rh *RESTHandler) JobResultsHandler(c echo.Context) (err error) {
 				output := errResponse{HTTPStatus: http.StatusInternalServerError, Message: err.Error()}
 				return prepareResponse(c, http.StatusInternalServerError, "error", output)
 			}
-			output := jobResponse{JobID: jobID, Outputs: outputs}
+			// digests are of results written by the process, before the result hook
+			integrity, err := jobs.NewResultsIntegrity(outputs)
+			if err != nil {
+				output := errResponse{HTTPStatus: http.StatusInternalServerError, Message: err.Error()}
+				return prepareResponse(c, http.StatusInternalServerError, "error", output)
+			}
+			outputs = selectOutputs(outputs, jRcrd.RequestedOutputs)
+			// files are archived as stored, before the result hook changes how outputs are presented
+			if wantsZip(c) {
+				return rh.writeResultsZip(c, jobID, outputs)
+			}
+			// result hook of the process at submission, so results are served as submitted after the process changes
+			if p, ok := rh.submittedProcess(jRcrd); ok && p.ResultHook != nil {
+				outputs, err = p.ApplyResultHook(jobID, outputs)
+				if err != nil {
+					output := errResponse{HTTPStatus: http.StatusInternalServerError, Message: err.Error()}
+					return prepareResponse(c, http.StatusInternalServerError, "error", output)
+				}
+			} else if wantsNDJSON(c) {
+				// outputs are served as stored, so stored files can be streamed without loading them in memory
+				return rh.streamResultsNDJSON(c, jobID, outputs)
+			}
+			if wantsNDJSON(c) {
+				return writeNDJSON(c, outputs)
+			}
+			output := jobResponse{JobID: jobID, Outputs: outputs, OutputLocation: rh.outputLocation(jobID), Integrity: integrity, ExpiresAt: jRcrd.ExpiresAt}
 			return prepareResponse(c, http.StatusOK, "jobResults", output)
 
 		case jobs.FAILED, jobs.DISMISSED:
@@ -431,7 +1172,7 @@ func (rh *RESTHandler) JobResultsHandler(c echo.Context) (err error) {
 	}
 
 	// miss
-	output := errResponse{HTTPStatus: http.StatusNotFound, Message: fmt.Sprintf("%s job id not found", jobID)}
+	output := errResponse{HTTPStatus: http.StatusNotFound, Type: exceptionNoSuchJob, Message: fmt.Sprintf("%s job id not found", jobID)}
 	return prepareResponse(c, http.StatusNotFound, "error", output)
 }
 
@@ -453,11 +1194,11 @@ func (rh *RESTHandler) JobMetaDataHandler(c echo.Context) (err error) {
 	var jRcrd jobs.JobRecord
 
 	jobID := c.Param("jobID")
-	if job, ok := rh.ActiveJobs.Jobs[jobID]; ok { // ActiveJobs hit
+	if job, ok := rh.ActiveJobs.Jobs[jobID]; ok && rh.jobReadable(c, (*job).SUBMITTER(), (*job).SUBMITTERID()) { // ActiveJobs hit
 		output := errResponse{HTTPStatus: http.StatusNotFound, Message: fmt.Sprintf("metadata not ready, job %s", (*job).CurrentStatus())}
 		return prepareResponse(c, http.StatusNotFound, "error", output)
 
-	} else if jRcrd, ok, err = rh.DB.GetJob(jobID); ok { // db hit
+	} else if jRcrd, ok, err = rh.DB.GetJob(jobID); ok && rh.jobReadable(c, jRcrd.Submitter, jRcrd.SubmitterID) { // db hit
 		switch jRcrd.Status {
 		case jobs.SUCCESSFUL:
 			md, err := jobs.FetchMeta(rh.StorageSvc, jobID)
@@ -488,16 +1229,67 @@ func (rh *RESTHandler) JobMetaDataHandler(c echo.Context) (err error) {
 	}
 
 	// miss
-	output := errResponse{HTTPStatus: http.StatusNotFound, Message: fmt.Sprintf("%s job id not found", jobID)}
+	output := errResponse{HTTPStatus: http.StatusNotFound, Type: exceptionNoSuchJob, Message: fmt.Sprintf("%s job id not found", jobID)}
 	return prepareResponse(c, http.StatusNotFound, "error", output)
 }
 
+// Filter log entries to the ones after since and truncate them to limit.
+// since can either be a line offset or an RFC3339 timestamp, empty since returns all entries.
+// limit less than 1 means no limit.
+func filterLogEntries(logs []jobs.LogEntry, since string, limit int) ([]jobs.LogEntry, error) {
+	if since != "" {
+		if offset, err := strconv.Atoi(since); err == nil {
+			if offset < 0 {
+				return nil, fmt.Errorf("'since' line offset can not be negative")
+			}
+			if offset > len(logs) {
+				offset = len(logs)
+			}
+			logs = logs[offset:]
+		} else if sinceTime, err := time.Parse(time.RFC3339, since); err == nil {
+			filtered := make([]jobs.LogEntry, 0)
+			for _, l := range logs {
+				if l.Time.After(sinceTime) {
+					filtered = append(filtered, l)
+				}
+			}
+			logs = filtered
+		} else {
+			return nil, fmt.Errorf("'since' must be a line offset or an RFC3339 timestamp")
+		}
+	}
+
+	if limit > 0 && limit < len(logs) {
+		logs = logs[:limit]
+	}
+	return logs, nil
+}
+
+// Fetch logs of a job, recently fetched logs are reused
+func (rh *RESTHandler) fetchJobLogs(jobID string) (jobs.JobLogs, error) {
+	return rh.logsCache.get(jobID, func() (jobs.JobLogs, error) {
+		return rh.loadJobLogs(jobID)
+	})
+}
+
+// Fetch current logs of a job without the logs cache, process logs of active jobs are updated first
+func (rh *RESTHandler) loadJobLogs(jobID string) (jobs.JobLogs, error) {
+	if job, ok := rh.ActiveJobs.Jobs[jobID]; ok {
+		_ = (*job).UpdateProcessLogs()
+	}
+	return jobs.FetchLogs(rh.StorageSvc, jobID, false)
+}
+
 // @Summary Job Logs
-// @Description
+// @Description Logs can be fetched incrementally using stream, since and limit query parameters.
+// @Description Process logs are streamed as server-sent events when Accept header is text/event-stream.
 // @Tags jobs
 // @Accept */*
 // @Produce json
 // @Param jobID path string true "example: 44d9ca0e-2ca7-4013-907f-a8ccc60da3b4"
+// @Param stream query string false "container, api, stdout or stderr, default is all"
+// @Param since query string false "line offset or RFC3339 timestamp"
+// @Param limit query int false "maximum number of log entries per stream"
 // @Success 200 {object} jobs.JobLogs
 // @Router /jobs/{jobID}/logs [get]
 func (rh *RESTHandler) JobLogsHandler(c echo.Context) (err error) {
@@ -508,19 +1300,32 @@ func (rh *RESTHandler) JobLogsHandler(c echo.Context) (err error) {
 		return err
 	}
 
+	stream := c.QueryParam("stream")
+	if !utils.StringInSlice(stream, []string{"", "container", "api", "stdout", "stderr"}) {
+		output := errResponse{HTTPStatus: http.StatusBadRequest, Message: "Invalid option for query parameter 'stream'. Valid options are 'container', 'api', 'stdout' or 'stderr'."}
+		return prepareResponse(c, http.StatusBadRequest, "error", output)
+	}
+
+	var limit int
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			output := errResponse{HTTPStatus: http.StatusBadRequest, Message: "'limit' must be a positive integer"}
+			return prepareResponse(c, http.StatusBadRequest, "error", output)
+		}
+	}
+
 	var pid, status string
 	var jRcrd jobs.JobRecord
 
-	if job, ok := rh.ActiveJobs.Jobs[jobID]; ok { // ActiveJobs hit
+	if job, ok := rh.ActiveJobs.Jobs[jobID]; ok && rh.jobReadable(c, (*job).SUBMITTER(), (*job).SUBMITTERID()) { // ActiveJobs hit
 		pid = (*job).ProcessID()
 		status = (*job).CurrentStatus()
 		if status == jobs.ACCEPTED { // this prevents AWS Cloudwatch errors where logs are not available till some time after job is started
 			output := errResponse{HTTPStatus: http.StatusBadRequest, Message: "Logs will be available after the job has reached running state."}
 			return prepareResponse(c, http.StatusBadRequest, "error", output)
 		}
-
-		_ = (*job).UpdateProcessLogs()
-	} else if jRcrd, ok, err = rh.DB.GetJob(jobID); ok { // db hit
+	} else if jRcrd, ok, err = rh.DB.GetJob(jobID); ok && rh.jobReadable(c, jRcrd.Submitter, jRcrd.SubmitterID) { // db hit
 		pid = jRcrd.ProcessID
 		status = jRcrd.Status
 
@@ -529,16 +1334,54 @@ func (rh *RESTHandler) JobLogsHandler(c echo.Context) (err error) {
 			return prepareResponse(c, http.StatusInternalServerError, "error", output)
 		}
 	} else { // miss
-		output := errResponse{HTTPStatus: http.StatusNotFound, Message: "jobID not found"}
+		output := errResponse{HTTPStatus: http.StatusNotFound, Type: exceptionNoSuchJob, Message: "jobID not found"}
 		return prepareResponse(c, http.StatusNotFound, "error", output)
 	}
 
-	logs, err := jobs.FetchLogs(rh.StorageSvc, jobID, false)
+	if strings.Contains(c.Request().Header.Get(echo.HeaderAccept), "text/event-stream") {
+		var offset int
+		if since := c.QueryParam("since"); since != "" {
+			offset, err = strconv.Atoi(since)
+			if err != nil || offset < 0 {
+				output := errResponse{HTTPStatus: http.StatusBadRequest, Message: "'since' must be a line offset when streaming logs"}
+				return prepareResponse(c, http.StatusBadRequest, "error", output)
+			}
+		}
+		return rh.streamLogs(c, jobID, offset)
+	}
+
+	logs, err := rh.fetchJobLogs(jobID)
 	if err != nil {
 		output := errResponse{HTTPStatus: http.StatusInternalServerError, Message: "error while fetching logs: " + err.Error()}
 		return prepareResponse(c, http.StatusInternalServerError, "error", output)
 	}
 
+	switch stream {
+	case "container":
+		logs.ServerLogs = []jobs.LogEntry{}
+	case "api":
+		logs.ProcessLogs = []jobs.LogEntry{}
+		logs.Stdout, logs.Stderr = nil, nil
+	case "stdout":
+		logs.ProcessLogs, logs.ServerLogs = []jobs.LogEntry{}, []jobs.LogEntry{}
+		logs.Stderr = nil
+	case "stderr":
+		logs.ProcessLogs, logs.ServerLogs = []jobs.LogEntry{}, []jobs.LogEntry{}
+		logs.Stdout = nil
+	}
+
+	since := c.QueryParam("since")
+	for _, l := range []*[]jobs.LogEntry{&logs.ProcessLogs, &logs.ServerLogs, &logs.Stdout, &logs.Stderr} {
+		*l, err = filterLogEntries(*l, since, limit)
+		if err != nil {
+			break
+		}
+	}
+	if err != nil {
+		output := errResponse{HTTPStatus: http.StatusBadRequest, Message: err.Error()}
+		return prepareResponse(c, http.StatusBadRequest, "error", output)
+	}
+
 	logs.ProcessID = pid
 	logs.Status = status
 	return prepareResponse(c, http.StatusOK, "jobLogs", logs)
@@ -550,6 +1393,7 @@ func (rh *RESTHandler) JobLogsHandler(c echo.Context) (err error) {
 // @Tags jobs
 // @Accept */*
 // @Produce json
+// @Param active query bool false "only accepted and running jobs, served from memory instead of the database"
 // @Success 200 {object} []jobs.JobRecord
 // @Router /jobs [get]
 func (rh *RESTHandler) ListJobsHandler(c echo.Context) error {
@@ -564,6 +1408,16 @@ func (rh *RESTHandler) ListJobsHandler(c echo.Context) error {
 	statuses := c.QueryParam("status")
 	submitters := c.QueryParam("submitter")
 
+	// active jobs are served from memory, which is much faster than querying the database for all jobs
+	var active bool
+	if a := c.QueryParam("active"); a != "" {
+		active, err = strconv.ParseBool(a)
+		if err != nil {
+			output := errResponse{HTTPStatus: http.StatusBadRequest, Type: exceptionInvalidParameterValue, Message: "active must be true or false"}
+			return prepareResponse(c, http.StatusBadRequest, "error", output)
+		}
+	}
+
 	var processIDList []string
 	if processIDs != "" {
 		processIDList = strings.Split(processIDs, ",")
@@ -578,18 +1432,13 @@ func (rh *RESTHandler) ListJobsHandler(c echo.Context) error {
 		case jobs.ACCEPTED, jobs.RUNNING, jobs.DISMISSED, jobs.FAILED, jobs.SUCCESSFUL:
 			// valid status
 		default:
-			output := errResponse{HTTPStatus: http.StatusBadRequest, Message: "One or more status values not valid"}
+			output := errResponse{HTTPStatus: http.StatusBadRequest, Type: exceptionInvalidParameterValue, Message: "One or more status values not valid"}
 			return prepareResponse(c, http.StatusBadRequest, "error", output)
 		}
 	}
 
-	if rh.Config.AuthLevel > 1 { // changed for hotfix, should be > 0 when clients are updated
-		roles := strings.Split(c.Request().Header.Get("X-ProcessAPI-User-Roles"), ",")
-
-		if !utils.StringInSlice(rh.Config.AdminRoleName, roles) {
-			submitters = c.Request().Header.Get("X-ProcessAPI-User-Email")
-		}
-	}
+	// non-admins only list their own jobs, see jobReadable
+	owner := rh.jobOwner(c)
 
 	var submittersList []string
 	if submitters != "" {
@@ -606,23 +1455,41 @@ func (rh *RESTHandler) ListJobsHandler(c echo.Context) error {
 		offset = 0
 	}
 
-	result, err := rh.DB.GetJobs(limit, offset, processIDList, statusList, submittersList)
-	if err != nil {
-		output := errResponse{HTTPStatus: http.StatusInternalServerError, Message: err.Error()}
-		return prepareResponse(c, http.StatusNotFound, "error", output)
+	var result []jobs.JobRecord
+	if active {
+		result = rh.ActiveJobs.Records(processIDList, statusList, submittersList, owner)
+		if offset < len(result) {
+			result = result[offset:]
+		} else {
+			result = result[:0]
+		}
+		if len(result) > limit {
+			result = result[:limit]
+		}
+	} else {
+		result, err = rh.DB.GetJobs(limit, offset, processIDList, statusList, submittersList, owner)
+		if err != nil {
+			output := errResponse{HTTPStatus: http.StatusInternalServerError, Message: err.Error()}
+			return prepareResponse(c, http.StatusNotFound, "error", output)
+		}
+	}
+
+	var activeParam string
+	if active {
+		activeParam = "&active=true"
 	}
 
 	links := make([]link, 0)
 	if offset != 0 {
 		lnk := link{
-			Href:  fmt.Sprintf("/jobs?offset=%v&limit=%v&processID=%v&status=%v&submitter=%v", offset-limit, limit, processIDs, statuses, submitters),
+			Href:  fmt.Sprintf("/jobs?offset=%v&limit=%v&processID=%v&status=%v&submitter=%v%s", offset-limit, limit, processIDs, statuses, submitters, activeParam),
 			Title: "prev",
 		}
 		links = append(links, lnk)
 	}
 	if limit == len(result) {
 		lnk := link{
-			Href:  fmt.Sprintf("/jobs?offset=%v&limit=%v&processID=%v&status=%v&submitter=%v", offset+limit, limit, processIDs, statuses, submitters),
+			Href:  fmt.Sprintf("/jobs?offset=%v&limit=%v&processID=%v&status=%v&submitter=%v%s", offset+limit, limit, processIDs, statuses, submitters, activeParam),
 			Title: "next",
 		}
 		links = append(links, lnk)
@@ -661,10 +1528,10 @@ func (rh *RESTHandler) JobStatusUpdateHandler(c echo.Context) error {
 		defer c.Request().Body.Close()
 		dataBytes, err := io.ReadAll(c.Request().Body)
 		if err != nil {
-			return c.JSON(http.StatusBadRequest, errResponse{http.StatusBadRequest, "could not read message body"})
+			return c.JSON(http.StatusBadRequest, errResponse{HTTPStatus: http.StatusBadRequest, Message: "could not read message body"})
 		}
 		if err = json.Unmarshal(dataBytes, &sm); err != nil {
-			return c.JSON(http.StatusBadRequest, errResponse{http.StatusBadRequest, "incorrect message body"})
+			return c.JSON(http.StatusBadRequest, errResponse{HTTPStatus: http.StatusBadRequest, Message: "incorrect message body"})
 		}
 		// check status valid
 		switch sm.Status {
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"app/jobs"
+	"app/processes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Bind a JSON request body, numbers are decoded as json.Number so that they can be passed to processes as written.
+// An empty body leaves params unchanged.
+func bindJSONRequest(c echo.Context, params interface{}) error {
+	dec := json.NewDecoder(c.Request().Body)
+	dec.UseNumber()
+	err := dec.Decode(params)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("could not parse request body: %s", err.Error())
+	}
+	return nil
+}
+
+// Bind a multipart/form-data execution request.
+// The optional `json` part supplies the execute request body (scalar inputs, env, etc.).
+// Every file part is staged to the job inputs directory and its path is injected as the input with the same name as the part.
+// Multiple files for the same input are injected as an array of paths.
+// File parts must be named after inputs of the process, they are checked before anything is written.
+// Staged files are removed if an error is encountered.
+func (rh *RESTHandler) bindMultipartRequest(c echo.Context, p processes.Process, jobID string) (params runRequestBody, err error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return params, fmt.Errorf("could not parse multipart form: %s", err.Error())
+	}
+
+	if jsonPart, ok := form.Value["json"]; ok && len(jsonPart) > 0 {
+		dec := json.NewDecoder(strings.NewReader(jsonPart[0]))
+		dec.UseNumber()
+		if err := dec.Decode(&params); err != nil {
+			return params, fmt.Errorf("incorrect json part: %s", err.Error())
+		}
+	}
+	if params.Inputs == nil {
+		params.Inputs = make(map[string]interface{})
+	}
+
+	if len(form.File) == 0 {
+		return params, nil
+	}
+
+	if rh.Config.InputsDir == "" {
+		return params, fmt.Errorf("file inputs are not supported, env variable TMP_JOB_INPUTS_DIR not set")
+	}
+	// staged files are only reachable by jobs running on this server
+	if p.Host.Type == "aws-batch" {
+		return params, fmt.Errorf("file inputs are not supported for aws-batch processes")
+	}
+
+	for inputID, files := range form.File {
+		if !hasInput(p, inputID) {
+			return params, fmt.Errorf("file part %s is not an input of process %s", inputID, p.Info.ID)
+		}
+		for _, fh := range files {
+			switch filepath.Base(fh.Filename) {
+			case ".", "..", string(filepath.Separator):
+				return params, fmt.Errorf("invalid file name '%s' for input %s", fh.Filename, inputID)
+			}
+		}
+	}
+
+	jobInputsDir := filepath.Join(rh.Config.InputsDir, jobID)
+	defer func() {
+		if err != nil {
+			os.RemoveAll(jobInputsDir)
+		}
+	}()
+
+	for inputID, files := range form.File {
+		if _, ok := params.Inputs[inputID]; ok {
+			return params, fmt.Errorf("input %s provided both as a file and in the json part", inputID)
+		}
+
+		paths := make([]interface{}, 0, len(files))
+		for _, fh := range files {
+			if fh.Size > rh.Config.MaxInputPartSize {
+				return params, fmt.Errorf("file %s for input %s exceeds the maximum allowed size of %d bytes", fh.Filename, inputID, rh.Config.MaxInputPartSize)
+			}
+
+			dst := filepath.Join(jobInputsDir, inputID, filepath.Base(fh.Filename))
+			if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+				return params, err
+			}
+
+			src, err := fh.Open()
+			if err != nil {
+				return params, err
+			}
+			err = stageFile(src, dst)
+			src.Close()
+			if err != nil {
+				return params, fmt.Errorf("could not stage file %s for input %s: %s", fh.Filename, inputID, err.Error())
+			}
+			paths = append(paths, dst)
+		}
+
+		if len(paths) == 1 {
+			params.Inputs[inputID] = paths[0]
+		} else {
+			params.Inputs[inputID] = paths
+		}
+	}
+
+	return params, nil
+}
+
+func hasInput(p processes.Process, inputID string) bool {
+	for _, i := range p.Inputs {
+		if i.ID == inputID {
+			return true
+		}
+	}
+	return false
+}
+
+// Reject inputs exceeding the configured number of distinct inputs or nesting depth
+// so that pathological payloads are not marshaled into commands.
+func (rh *RESTHandler) verifyInputLimits(inputs map[string]interface{}) error {
+	if len(inputs) > rh.Config.MaxInputs {
+		return fmt.Errorf("number of inputs %d exceeds the maximum allowed %d", len(inputs), rh.Config.MaxInputs)
+	}
+
+	for id, val := range inputs {
+		if inputDepth(val, rh.Config.MaxInputDepth+1) > rh.Config.MaxInputDepth {
+			return fmt.Errorf("input %s exceeds the maximum allowed nesting depth of %d", id, rh.Config.MaxInputDepth)
+		}
+	}
+	return nil
+}
+
+// Respond to an error of a validation hook, rejected inputs are a bad request, a hook that can not be called is a bad gateway
+func validationHookError(c echo.Context, err error) error {
+	var rejected *processes.InputsRejectedError
+	if errors.As(err, &rejected) {
+		return c.JSON(http.StatusBadRequest, errResponse{Type: exceptionInvalidParameterValue, Message: err.Error()})
+	}
+	return c.JSON(http.StatusBadGateway, errResponse{Message: err.Error()})
+}
+
+// Nesting depth of objects and arrays in a decoded JSON value, scalars have a depth of 0.
+// Stops descending once limit is reached.
+func inputDepth(val interface{}, limit int) int {
+	if limit <= 0 {
+		return 0
+	}
+
+	depth := 0
+	switch v := val.(type) {
+	case map[string]interface{}:
+		for _, child := range v {
+			if d := inputDepth(child, limit-1); d > depth {
+				depth = d
+			}
+		}
+	case []interface{}:
+		for _, child := range v {
+			if d := inputDepth(child, limit-1); d > depth {
+				depth = d
+			}
+		}
+	default:
+		return 0
+	}
+	return depth + 1
+}
+
+// Copy src to a new file at dst
+func stageFile(src io.Reader, dst string) error {
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, src)
+	return err
+}
+
+// Plan downloads of href inputs that are marked for staging in the process definition and replace them with
+// the path the file is downloaded to under the job inputs directory. Files are downloaded by the job when it starts.
+// Href inputs must be objects such as {"href": "https://...", "checksum": "sha256:<hex>"}, checksum is optional.
+func (rh *RESTHandler) hrefDownloads(p processes.Process, inputs map[string]interface{}, jobID string) ([]jobs.InputDownload, error) {
+	var downloads []jobs.InputDownload
+	for _, def := range p.Inputs {
+		if !def.Stage {
+			continue
+		}
+		val, ok := inputs[def.ID]
+		if !ok {
+			continue
+		}
+
+		if rh.Config.InputsDir == "" {
+			return nil, fmt.Errorf("input %s can not be staged, env variable TMP_JOB_INPUTS_DIR not set", def.ID)
+		}
+
+		dstDir := filepath.Join(rh.Config.InputsDir, jobID, def.ID)
+		switch v := val.(type) {
+		case []interface{}:
+			for i, item := range v {
+				d, err := hrefDownload(item, def, filepath.Join(dstDir, fmt.Sprint(i)))
+				if err != nil {
+					return nil, err
+				}
+				v[i] = d.Path
+				downloads = append(downloads, d)
+			}
+		default:
+			d, err := hrefDownload(v, def, dstDir)
+			if err != nil {
+				return nil, err
+			}
+			inputs[def.ID] = d.Path
+			downloads = append(downloads, d)
+		}
+	}
+	return downloads, nil
+}
+
+// Download of a single href input to dstDir
+func hrefDownload(val interface{}, def processes.Inputs, dstDir string) (jobs.InputDownload, error) {
+	obj, ok := val.(map[string]interface{})
+	if !ok {
+		return jobs.InputDownload{}, fmt.Errorf("input %s must be an object with href", def.ID)
+	}
+	href, ok := obj["href"].(string)
+	if !ok || href == "" {
+		return jobs.InputDownload{}, fmt.Errorf("input %s must be an object with href", def.ID)
+	}
+	checksum, _ := obj["checksum"].(string)
+
+	d := jobs.InputDownload{InputID: def.ID, Href: href, MediaType: def.MediaType, Checksum: checksum}
+	if err := d.Validate(); err != nil {
+		return d, err
+	}
+
+	u, _ := url.Parse(href)
+	name := path.Base(u.Path)
+	if name == "/" || name == "." || name == ".." {
+		name = def.ID
+	}
+	d.Path = filepath.Join(dstDir, name)
+	return d, nil
+}
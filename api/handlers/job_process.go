@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"app/jobs"
+	"app/processes"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Record metadata of the process and outputs requested for a submitted job, so that the job is handled as submitted
+// after the process is updated or removed. Errors are logged, the job itself is not affected.
+func (rh *RESTHandler) recordSubmission(jobID string, p processes.Process, outputIDs []string) {
+	if err := rh.DB.SetProcessSnapshot(jobID, processSnapshot(p)); err != nil {
+		log.Errorf("Could not record process metadata of job %s. Error: %s", jobID, err.Error())
+	}
+	if outputIDs != nil {
+		if err := rh.DB.SetRequestedOutputs(jobID, outputIDs); err != nil {
+			log.Errorf("Could not record requested outputs of job %s. Error: %s", jobID, err.Error())
+		}
+	}
+}
+
+func processSnapshot(p processes.Process) jobs.ProcessSnapshot {
+	s := jobs.ProcessSnapshot{
+		Version: p.Info.Version, MaxRunningTime: p.Config.MaxRunningTime, StuckAction: p.Config.StuckAction,
+		DefaultResultContentType: p.DefaultResultContentType,
+	}
+	if p.ResultHook != nil {
+		s.ResultHook = p.ResultHook.Template
+	}
+	return s
+}
+
+// Process with the fields of a snapshot set, enough to handle jobs of a process that no longer exists
+func snapshotProcess(processID string, s jobs.ProcessSnapshot) processes.Process {
+	p := processes.Process{Info: processes.Info{ID: processID, Version: s.Version}, DefaultResultContentType: s.DefaultResultContentType}
+	p.Config.MaxRunningTime = s.MaxRunningTime
+	p.Config.StuckAction = s.StuckAction
+	if s.ResultHook != "" {
+		p.ResultHook = &processes.ResultHook{Template: s.ResultHook}
+	}
+	return p
+}
+
+// Process of a job as it was at submission. Jobs recorded without a snapshot use the current process,
+// ok is false if they have no snapshot and the process no longer exists.
+func (rh *RESTHandler) submittedProcess(jr jobs.JobRecord) (p processes.Process, ok bool) {
+	if jr.Process != nil {
+		return snapshotProcess(jr.ProcessID, *jr.Process), true
+	}
+	p, _, err := rh.ProcessList.Get(jr.ProcessID)
+	return p, err == nil
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"app/jobs"
+	"app/processes"
+	"testing"
+)
+
+func testProcess() processes.Process {
+	maxRunningTime := 60
+	p := processes.Process{
+		Info:                     processes.Info{ID: "grid", Version: "1.0.0"},
+		DefaultResultContentType: "application/geo+json",
+		ResultHook:               &processes.ResultHook{Template: `{"path": "{{.results.path}}", "job": "{{.jobID}}"}`},
+	}
+	p.Config.MaxRunningTime = &maxRunningTime
+	p.Config.StuckAction = "fail"
+	return p
+}
+
+// Jobs of a process removed after submission are still handled as submitted
+func TestSubmittedProcessRemovedMidJob(t *testing.T) {
+	rh := &RESTHandler{ProcessList: &processes.ProcessList{}}
+	p := testProcess()
+	if err := rh.ProcessList.Add(p); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := processSnapshot(p)
+	jr := jobs.JobRecord{JobID: "job", ProcessID: p.Info.ID, Process: &snapshot}
+
+	if err := rh.ProcessList.Remove(p.Info.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := rh.submittedProcess(jr)
+	if !ok {
+		t.Fatal("submittedProcess() of a removed process with a snapshot is not ok")
+	}
+	if got.Info.ID != p.Info.ID || got.Info.Version != p.Info.Version {
+		t.Errorf("process = %s@%s, want %s@%s", got.Info.ID, got.Info.Version, p.Info.ID, p.Info.Version)
+	}
+	if got.DefaultResultContentType != p.DefaultResultContentType {
+		t.Errorf("defaultResultContentType = %s, want %s", got.DefaultResultContentType, p.DefaultResultContentType)
+	}
+	if got.Config.MaxRunningTime == nil || *got.Config.MaxRunningTime != 60 || got.Config.StuckAction != "fail" {
+		t.Errorf("maxRunningTime, stuckAction = %v, %s, want 60, fail", got.Config.MaxRunningTime, got.Config.StuckAction)
+	}
+
+	results, err := got.ApplyResultHook("job", map[string]interface{}{"path": "s3://bucket/out.tif"})
+	if err != nil {
+		t.Fatalf("ApplyResultHook() error = %v", err)
+	}
+	hooked, _ := results.(map[string]interface{})
+	if hooked["path"] != "s3://bucket/out.tif" || hooked["job"] != "job" {
+		t.Errorf("hooked results = %v", results)
+	}
+}
+
+// Snapshots keep the process as submitted when it is updated afterwards
+func TestSubmittedProcessUpdatedMidJob(t *testing.T) {
+	rh := &RESTHandler{ProcessList: &processes.ProcessList{}}
+	p := testProcess()
+	if err := rh.ProcessList.Add(p); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := processSnapshot(p)
+	jr := jobs.JobRecord{JobID: "job", ProcessID: p.Info.ID, Process: &snapshot}
+
+	updated := p
+	updated.Info.Version = "2.0.0"
+	updated.ResultHook = nil
+	if err := rh.ProcessList.Replace(updated); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := rh.submittedProcess(jr)
+	if !ok || got.Info.Version != "1.0.0" || got.ResultHook == nil {
+		t.Errorf("submittedProcess() = %s, hook %v, %v, want version 1.0.0 with hook", got.Info.Version, got.ResultHook, ok)
+	}
+}
+
+// Jobs recorded before snapshots existed fall back to the current process
+func TestSubmittedProcessWithoutSnapshot(t *testing.T) {
+	rh := &RESTHandler{ProcessList: &processes.ProcessList{}}
+	p := testProcess()
+	jr := jobs.JobRecord{JobID: "job", ProcessID: p.Info.ID}
+
+	if _, ok := rh.submittedProcess(jr); ok {
+		t.Error("submittedProcess() of a missing process without snapshot is ok")
+	}
+
+	if err := rh.ProcessList.Add(p); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := rh.submittedProcess(jr)
+	if !ok || got.Info.Version != p.Info.Version {
+		t.Errorf("submittedProcess() = %s, %v, want current process", got.Info.Version, ok)
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"app/jobs"
+	"sync"
+	"time"
+)
+
+type logsCacheEntry struct {
+	logs    jobs.JobLogs
+	fetched time.Time
+}
+
+// logsCache keeps recently fetched job logs for a short time so that repeated requests for the same job
+// do not trigger new fetches (e.g. CloudWatch calls for aws-batch jobs), and bounds the number of concurrent fetches.
+// Cached logs are shared between requests and must not be modified in place.
+type logsCache struct {
+	ttl     time.Duration
+	sem     chan struct{}
+	mu      sync.Mutex
+	entries map[string]logsCacheEntry
+}
+
+// A ttl of 0 disables caching, maxConcurrent must be at least 1
+func newLogsCache(ttl time.Duration, maxConcurrent int) *logsCache {
+	return &logsCache{
+		ttl:     ttl,
+		sem:     make(chan struct{}, maxConcurrent),
+		entries: make(map[string]logsCacheEntry),
+	}
+}
+
+// Return cached logs of a job if they were fetched within ttl, otherwise fetch them.
+// Fetches wait for a free slot if the maximum number of concurrent fetches is reached.
+func (lc *logsCache) get(jobID string, fetch func() (jobs.JobLogs, error)) (jobs.JobLogs, error) {
+	if logs, ok := lc.cached(jobID); ok {
+		return logs, nil
+	}
+
+	lc.sem <- struct{}{}
+	defer func() { <-lc.sem }()
+
+	// another request might have fetched the logs while waiting
+	if logs, ok := lc.cached(jobID); ok {
+		return logs, nil
+	}
+
+	logs, err := fetch()
+	if err != nil {
+		return logs, err
+	}
+
+	if lc.ttl > 0 {
+		lc.mu.Lock()
+		defer lc.mu.Unlock()
+		now := time.Now()
+		for jid, e := range lc.entries {
+			if now.Sub(e.fetched) > lc.ttl {
+				delete(lc.entries, jid)
+			}
+		}
+		lc.entries[jobID] = logsCacheEntry{logs: logs, fetched: now}
+	}
+	return logs, nil
+}
+
+func (lc *logsCache) cached(jobID string) (jobs.JobLogs, bool) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	e, ok := lc.entries[jobID]
+	if !ok || time.Since(e.fetched) > lc.ttl {
+		return jobs.JobLogs{}, false
+	}
+	return e.logs, true
+}
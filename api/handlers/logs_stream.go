@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"app/jobs"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Interval between checks for new log lines of a streamed job
+const logStreamInterval = 2 * time.Second
+
+// Write a server-sent event with data marshalled to JSON
+func writeEvent(c echo.Context, event string, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.Response(), "event: %s\ndata: %s\n\n", event, b)
+	c.Response().Flush()
+	return err
+}
+
+// Stream process logs of a job as server-sent events, each log line is sent as a `log` event.
+// An `end` event is sent once the job is finished and all lines are sent. Streams are closed with a `close` event
+// when the maximum lines, size or duration of a stream is reached, clients can reconnect using `since` to continue.
+func (rh *RESTHandler) streamLogs(c echo.Context, jobID string, since int) error {
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("Connection", "keep-alive")
+	res.WriteHeader(http.StatusOK)
+
+	var deadline time.Time
+	if rh.Config.LogStreamMaxDuration > 0 {
+		deadline = time.Now().Add(rh.Config.LogStreamMaxDuration)
+	}
+
+	ticker := time.NewTicker(logStreamInterval)
+	defer ticker.Stop()
+
+	offset := since
+	var lines int
+	var size int64
+	for {
+		// jobs leave active jobs once closed, after their final logs are written,
+		// so terminated jobs that are still closing are polled again
+		status := ""
+		active := false
+		if job, ok := rh.ActiveJobs.Jobs[jobID]; ok {
+			status = (*job).CurrentStatus()
+			active = true
+		} else if jRcrd, ok, err := rh.DB.GetJob(jobID); err == nil && ok {
+			status = jRcrd.Status
+		}
+		finished := !active && (status == jobs.SUCCESSFUL || status == jobs.FAILED || status == jobs.DISMISSED)
+
+		// the logs cache is bypassed, cached logs would repeat between polls and could miss the last lines of a finished job
+		logs, err := rh.loadJobLogs(jobID)
+		if err != nil {
+			return writeEvent(c, "error", errResponse{Message: "error while fetching logs: " + err.Error()})
+		}
+
+		for ; offset < len(logs.ProcessLogs); offset++ {
+			b, err := json.Marshal(logs.ProcessLogs[offset])
+			if err != nil {
+				return err
+			}
+
+			if (rh.Config.LogStreamMaxLines > 0 && lines >= rh.Config.LogStreamMaxLines) ||
+				(rh.Config.LogStreamMaxSize > 0 && size+int64(len(b)) > rh.Config.LogStreamMaxSize) {
+				return writeEvent(c, "close", map[string]interface{}{"message": "maximum lines or size of a stream reached", "since": offset})
+			}
+
+			if _, err = fmt.Fprintf(res, "event: log\ndata: %s\n\n", b); err != nil {
+				return nil
+			}
+			lines++
+			size += int64(len(b))
+		}
+		res.Flush()
+
+		if finished {
+			return writeEvent(c, "end", map[string]interface{}{"status": status, "since": offset})
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return writeEvent(c, "close", map[string]interface{}{"message": "maximum duration of a stream reached", "since": offset})
+		}
+
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"app/jobs"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Metrics
+// @Description Metrics of active jobs and storage write retries in Prometheus text format
+// @Tags info
+// @Produce plain
+// @Success 200 {string} string
+// @Router /metrics [get]
+// Does not produce HTML
+func (rh *RESTHandler) MetricsHandler(c echo.Context) error {
+	entries, active, evicted := rh.ActiveJobs.Stats()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP jobs_cache_entries Number of jobs held in active jobs, including terminated jobs not purged yet.\n")
+	fmt.Fprintf(&b, "# TYPE jobs_cache_entries gauge\n")
+	fmt.Fprintf(&b, "jobs_cache_entries %d\n", entries)
+	fmt.Fprintf(&b, "# HELP jobs_active Number of jobs in accepted or running status.\n")
+	fmt.Fprintf(&b, "# TYPE jobs_active gauge\n")
+	fmt.Fprintf(&b, "jobs_active %d\n", active)
+	fmt.Fprintf(&b, "# HELP jobs_evicted_total Number of terminated jobs purged from active jobs since startup.\n")
+	fmt.Fprintf(&b, "# TYPE jobs_evicted_total counter\n")
+	fmt.Fprintf(&b, "jobs_evicted_total %d\n", evicted)
+
+	pending, failed := jobs.StorageRetryStats()
+	fmt.Fprintf(&b, "# HELP storage_writes_pending Number of failed storage writes of job metadata and logs waiting to be retried.\n")
+	fmt.Fprintf(&b, "# TYPE storage_writes_pending gauge\n")
+	fmt.Fprintf(&b, "storage_writes_pending %d\n", pending)
+	fmt.Fprintf(&b, "# HELP storage_writes_failed Number of storage writes that failed after all retries and need operator attention.\n")
+	fmt.Fprintf(&b, "# TYPE storage_writes_failed gauge\n")
+	fmt.Fprintf(&b, "storage_writes_failed %d\n", failed)
+
+	return c.Blob(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
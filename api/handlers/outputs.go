@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"app/jobs"
+	"fmt"
+	"mime"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Env variable through which the resolved output location is passed to the process
+const outputLocationEnvVar = "OUTPUT_LOCATION"
+
+// Env variable through which the default result content type of the process is passed to it,
+// processes should write stored outputs with this content type
+const resultContentTypeEnvVar = "RESULT_CONTENT_TYPE"
+
+var validOutputPrefix = regexp.MustCompile(`^[a-zA-Z0-9._/-]+$`)
+
+// Resolve the storage location a job writes its results to from the output prefix requested by the client.
+// Prefixes under one of the allowlisted prefixes are used as is, all other prefixes are namespaced under
+// the job ID in the results prefix so that jobs cannot overwrite each others results.
+// Returns empty string if no prefix is requested.
+func (rh *RESTHandler) resolveOutputLocation(jobID, prefix string) (string, error) {
+	if prefix == "" {
+		return "", nil
+	}
+
+	if !validOutputPrefix.MatchString(prefix) {
+		return "", fmt.Errorf("outputPrefix can only have letters, numbers, '.', '_', '-' and '/'")
+	}
+	cleaned := path.Clean(prefix)
+	if strings.HasPrefix(prefix, "/") || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("outputPrefix must be a relative path within storage")
+	}
+
+	var key string
+	for _, allowed := range rh.Config.OutputPrefixAllowlist {
+		allowed = strings.Trim(allowed, "/")
+		if cleaned == allowed || strings.HasPrefix(cleaned, allowed+"/") {
+			key = cleaned
+			break
+		}
+	}
+	if key == "" {
+		key = path.Join(os.Getenv("STORAGE_RESULTS_PREFIX"), jobID, cleaned)
+	}
+
+	return fmt.Sprintf("s3://%s/%s", os.Getenv("STORAGE_BUCKET"), key), nil
+}
+
+// Output location of a finished job from its metadata.
+// Returns empty string if the job did not request an output location or metadata is not available.
+func (rh *RESTHandler) outputLocation(jobID string) string {
+	md, err := jobs.FetchMeta(rh.StorageSvc, jobID)
+	if err != nil {
+		return ""
+	}
+	if m, ok := md.(map[string]interface{}); ok {
+		if loc, ok := m["outputLocation"].(string); ok {
+			return loc
+		}
+	}
+	return ""
+}
+
+// Check if a media type is JSON or a JSON based type such as application/geo+json
+func isJSONMediaType(mediaType string) bool {
+	mt, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return false
+	}
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"app/controllers"
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Maximum time to wait for a single image to be pulled
+const prepullTimeout = 30 * time.Minute
+
+// PrepullImages pulls images of all docker processes that are not present on the host yet, so that first jobs of
+// these processes do not wait for the pull. Images are pulled one after another, failures are logged and skipped.
+// Images already present are not pulled again, same as when a job starts.
+func (rh *RESTHandler) PrepullImages() {
+	images := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, p := range rh.ProcessList.Processes() {
+		if p.Host.Type != "docker" || p.Host.Image == "" || seen[p.Host.Image] {
+			continue
+		}
+		seen[p.Host.Image] = true
+		images = append(images, p.Host.Image)
+	}
+	if len(images) == 0 {
+		return
+	}
+
+	c, err := controllers.NewDockerController()
+	if err != nil {
+		log.Errorf("Could not pre-pull images, docker is not available: %s", err.Error())
+		return
+	}
+
+	log.Infof("Pre-pulling %d images.", len(images))
+	failed := 0
+	for i, image := range images {
+		start := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), prepullTimeout)
+		err := c.EnsureImage(ctx, image, false)
+		cancel()
+		if err != nil {
+			failed++
+			log.Errorf("Could not pre-pull image %s (%d/%d): %s", image, i+1, len(images), err.Error())
+			continue
+		}
+		log.Infof("Pre-pulled image %s (%d/%d) in %s", image, i+1, len(images), time.Since(start).Round(time.Second))
+	}
+	log.Infof("Finished pre-pulling images, %d of %d failed.", failed, len(images))
+}
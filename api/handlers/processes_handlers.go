@@ -5,6 +5,7 @@ import (
 	"app/utils"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -19,6 +20,7 @@ import (
 // @Tags processes
 // @Accept */*
 // @Produce json
+// @Param keyword query string false "comma separated keywords, processes having all of them are listed"
 // @Success 200 {object} map[string]interface{}
 // @Router /processes [get]
 func (rh *RESTHandler) ProcessListHandler(c echo.Context) error {
@@ -41,15 +43,40 @@ func (rh *RESTHandler) ProcessListHandler(c echo.Context) error {
 		offset = 0
 	}
 
-	// instantiate result variable without importing processes pkg
-	result := rh.ProcessList.InfoList[0:0]
+	// keywords can be repeated or comma separated, processes must have all of them
+	var keywords []string
+	for _, kwParam := range c.QueryParams()["keyword"] {
+		for _, kw := range strings.Split(kwParam, ",") {
+			if kw = strings.TrimSpace(kw); kw != "" {
+				keywords = append(keywords, kw)
+			}
+		}
+	}
+
+	infoList := rh.ProcessList.Infos()
+	if len(keywords) > 0 {
+		allInfos := infoList
+		infoList = make([]processes.Info, 0)
+		for _, info := range allInfos {
+			if info.HasKeywords(keywords) {
+				infoList = append(infoList, info)
+			}
+		}
+	}
 
-	if offset < len(rh.ProcessList.InfoList) {
+	result := infoList[0:0]
+
+	if offset < len(infoList) {
 		upperBound := offset + limit
-		if upperBound > len(rh.ProcessList.InfoList) {
-			upperBound = len(rh.ProcessList.InfoList)
+		if upperBound > len(infoList) {
+			upperBound = len(infoList)
 		}
-		result = rh.ProcessList.InfoList[offset:upperBound]
+		result = infoList[offset:upperBound]
+	}
+
+	keywordQuery := ""
+	if len(keywords) > 0 {
+		keywordQuery = "&keyword=" + url.QueryEscape(strings.Join(keywords, ","))
 	}
 
 	// required by /req/core/process-list-success
@@ -58,7 +85,7 @@ func (rh *RESTHandler) ProcessListHandler(c echo.Context) error {
 	// if offset is not 0
 	if offset != 0 {
 		lnk := link{
-			Href:  fmt.Sprintf("/processes?offset=%v&limit=%v", offset-limit, limit),
+			Href:  fmt.Sprintf("/processes?offset=%v&limit=%v%s", offset-limit, limit, keywordQuery),
 			Title: "prev",
 		}
 		links = append(links, lnk)
@@ -67,7 +94,7 @@ func (rh *RESTHandler) ProcessListHandler(c echo.Context) error {
 	// if limit is not exhausted
 	if limit == len(result) {
 		lnk := link{
-			Href:  fmt.Sprintf("/processes?offset=%v&limit=%v", offset+limit, limit),
+			Href:  fmt.Sprintf("/processes?offset=%v&limit=%v%s", offset+limit, limit, keywordQuery),
 			Title: "next",
 		}
 		links = append(links, lnk)
@@ -85,6 +112,7 @@ func (rh *RESTHandler) ProcessListHandler(c echo.Context) error {
 // @Description [Process Description Specification](https://docs.ogc.org/is/18-062r2/18-062r2.html#sc_process_description)
 // @Tags processes
 // @Param processID path string true "example: pyecho"
+// @Param profile query string false "conformance profile the description is tailored to, 'ogc-process-description' (default) or 'core'"
 // @Accept */*
 // @Produce json
 // @Success 200 {object} processes.processDescription
@@ -97,18 +125,95 @@ func (rh *RESTHandler) ProcessDescribeHandler(c echo.Context) error {
 		return err
 	}
 
+	profile := c.QueryParam("profile")
+	if profile != "" && !utils.StringInSlice(profile, processes.DescriptionProfiles) {
+		msg := fmt.Sprintf("Invalid option for query parameter 'profile'. Valid options are %s.", strings.Join(processes.DescriptionProfiles, ", "))
+		return prepareResponse(c, http.StatusBadRequest, "error", errResponse{Type: exceptionInvalidParameterValue, Message: msg, HTTPStatus: http.StatusBadRequest})
+	}
+
 	p, _, err := rh.ProcessList.Get(processID)
 	if err != nil {
-		return prepareResponse(c, http.StatusBadRequest, "error", errResponse{Message: err.Error(), HTTPStatus: http.StatusBadRequest})
+		return prepareResponse(c, http.StatusBadRequest, "error", errResponse{Type: exceptionNoSuchProcess, Message: err.Error(), HTTPStatus: http.StatusBadRequest})
+	}
+
+	// processID is an alias of a renamed process
+	if p.Info.ID != processID {
+		location := "/processes/" + p.Info.ID
+		if c.QueryString() != "" {
+			location += "?" + c.QueryString()
+		}
+		return c.Redirect(http.StatusMovedPermanently, location)
 	}
 
-	description, err := p.Describe()
+	description, err := p.Describe(profile)
 	if err != nil {
 		return prepareResponse(c, http.StatusInternalServerError, "error", errResponse{Message: err.Error(), HTTPStatus: http.StatusInternalServerError})
 	}
 	return prepareResponse(c, http.StatusOK, "process", description)
 }
 
+// @Summary Process Inputs Schema
+// @Description JSON Schema of the inputs of a process, can be used to generate forms
+// @Tags processes
+// @Accept */*
+// @Produce json
+// @Param processID path string true "pyecho"
+// @Success 200 {object} map[string]interface{}
+// @Router /processes/{processID}/inputs/schema [get]
+// Does not produce HTML
+func (rh *RESTHandler) ProcessInputsSchemaHandler(c echo.Context) error {
+	processID := c.Param("processID")
+
+	p, _, err := rh.ProcessList.Get(processID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, errResponse{Type: exceptionNoSuchProcess, Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, p.InputsSchema())
+}
+
+// @Summary Process Definition
+// @Description Definition of a process as loaded, including host and config that are not part of the description. Admins only.
+// @Description Values of secret-like keys, secret references and passwords of URLs are redacted.
+// @Tags processes
+// @Accept */*
+// @Produce json,application/yaml
+// @Param processID path string true "pyecho"
+// @Param f query string false "json (default) or yaml"
+// @Success 200 {object} map[string]interface{}
+// @Router /processes/{processID}/definition [get]
+// Does not produce HTML
+func (rh *RESTHandler) ProcessDefinitionHandler(c echo.Context) error {
+
+	if rh.Config.AuthLevel > 0 {
+		roles := strings.Split(c.Request().Header.Get("X-ProcessAPI-User-Roles"), ",")
+
+		// non-admins are not allowed
+		if !utils.StringInSlice(rh.Config.AdminRoleName, roles) {
+			return c.JSON(http.StatusForbidden, errResponse{Message: "Forbidden"})
+		}
+	}
+
+	f := c.QueryParam("f")
+	if f != "" && f != "json" && f != "yaml" {
+		return c.JSON(http.StatusBadRequest, errResponse{Message: "Invalid option for query parameter 'f'. Valid options are 'json' or 'yaml'."})
+	}
+
+	p, _, err := rh.ProcessList.Get(c.Param("processID"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, errResponse{Type: exceptionNoSuchProcess, Message: err.Error()})
+	}
+
+	def, err := p.Definition()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, errResponse{Message: err.Error()})
+	}
+	if f == "yaml" {
+		return yamlResponse(c, http.StatusOK, def)
+	}
+	return c.JSON(http.StatusOK, def)
+}
+
 // AddProcessHandler adds a new process configuration
 func (rh *RESTHandler) AddProcessHandler(c echo.Context) error {
 
@@ -121,6 +226,10 @@ func (rh *RESTHandler) AddProcessHandler(c echo.Context) error {
 		}
 	}
 
+	if rh.Config.ProcessRegistryURL != "" {
+		return c.JSON(http.StatusConflict, errResponse{Message: "Processes are managed by the process registry"})
+	}
+
 	processID := c.Param("processID")
 	_, _, err := rh.ProcessList.Get(processID)
 	if err == nil {
@@ -142,6 +251,12 @@ func (rh *RESTHandler) AddProcessHandler(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, errResponse{Message: err.Error()})
 	}
+	if rh.StorageSvc == nil && newProcess.RequiresStorage() {
+		return c.JSON(http.StatusBadRequest, errResponse{Message: "process requires a storage service, " + utils.ErrStorageUnconfigured.Error()})
+	}
+	if err = rh.ProcessList.VerifyNames(newProcess); err != nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Message: err.Error()})
+	}
 
 	pluginsDir := os.Getenv("PLUGINS_DIR") // We already know this env variable exist because it is being checked in plguinsInit function
 	filename := fmt.Sprintf("%s/%s/%s.yml", pluginsDir, processID, processID)
@@ -164,8 +279,9 @@ func (rh *RESTHandler) AddProcessHandler(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, errResponse{Message: "Failed to write process file"})
 	}
 
-	rh.ProcessList.List = append(rh.ProcessList.List, newProcess)
-	rh.ProcessList.InfoList = append(rh.ProcessList.InfoList, newProcess.Info)
+	if err := rh.ProcessList.Add(newProcess); err != nil {
+		return prepareResponse(c, http.StatusBadRequest, "error", errResponse{Message: "Process already exist. Use PUT method to update", HTTPStatus: http.StatusBadRequest})
+	}
 
 	return c.JSON(http.StatusOK, map[string]string{"message": "Process added successfully"})
 }
@@ -183,11 +299,18 @@ func (rh *RESTHandler) UpdateProcessHandler(c echo.Context) error {
 		}
 	}
 
+	if rh.Config.ProcessRegistryURL != "" {
+		return c.JSON(http.StatusConflict, errResponse{Message: "Processes are managed by the process registry"})
+	}
+
 	processID := c.Param("processID")
 
-	oldProcess, i, err := rh.ProcessList.Get(processID)
+	oldProcess, _, err := rh.ProcessList.Get(processID)
 	if err != nil {
-		return prepareResponse(c, http.StatusBadRequest, "error", errResponse{Message: "Process does not exist", HTTPStatus: http.StatusBadRequest})
+		return prepareResponse(c, http.StatusBadRequest, "error", errResponse{Type: exceptionNoSuchProcess, Message: "Process does not exist", HTTPStatus: http.StatusBadRequest})
+	}
+	if oldProcess.Info.ID != processID {
+		return prepareResponse(c, http.StatusBadRequest, "error", errResponse{Message: fmt.Sprintf("%s is an alias, use process ID %s", processID, oldProcess.Info.ID), HTTPStatus: http.StatusBadRequest})
 	}
 
 	var updatedProcess processes.Process
@@ -204,6 +327,12 @@ func (rh *RESTHandler) UpdateProcessHandler(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, errResponse{Message: err.Error()})
 	}
+	if rh.StorageSvc == nil && updatedProcess.RequiresStorage() {
+		return c.JSON(http.StatusBadRequest, errResponse{Message: "process requires a storage service, " + utils.ErrStorageUnconfigured.Error()})
+	}
+	if err = rh.ProcessList.VerifyNames(updatedProcess); err != nil {
+		return c.JSON(http.StatusBadRequest, errResponse{Message: err.Error()})
+	}
 
 	pluginsDir := os.Getenv("PLUGINS_DIR") // We already know this env variable exist because it is being checked in plguinsInit function
 	filename := fmt.Sprintf("%s/%s/%s.yml", pluginsDir, processID, processID)
@@ -236,8 +365,9 @@ func (rh *RESTHandler) UpdateProcessHandler(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, errResponse{Message: "Failed to write process file"})
 	}
 
-	rh.ProcessList.List[i] = updatedProcess
-	rh.ProcessList.InfoList[i] = updatedProcess.Info
+	if err := rh.ProcessList.Replace(updatedProcess); err != nil {
+		return c.JSON(http.StatusInternalServerError, errResponse{Message: "Process was removed while updating"})
+	}
 
 	return c.JSON(http.StatusOK, map[string]string{"message": "Process updated successfully"})
 }
@@ -254,11 +384,18 @@ func (rh *RESTHandler) DeleteProcessHandler(c echo.Context) error {
 		}
 	}
 
+	if rh.Config.ProcessRegistryURL != "" {
+		return c.JSON(http.StatusConflict, errResponse{Message: "Processes are managed by the process registry"})
+	}
+
 	processID := c.Param("processID")
 
-	oldProcess, i, err := rh.ProcessList.Get(processID)
+	oldProcess, _, err := rh.ProcessList.Get(processID)
 	if err != nil {
-		return prepareResponse(c, http.StatusBadRequest, "error", errResponse{Message: "Process does not exist", HTTPStatus: http.StatusBadRequest})
+		return prepareResponse(c, http.StatusBadRequest, "error", errResponse{Type: exceptionNoSuchProcess, Message: "Process does not exist", HTTPStatus: http.StatusBadRequest})
+	}
+	if oldProcess.Info.ID != processID {
+		return prepareResponse(c, http.StatusBadRequest, "error", errResponse{Message: fmt.Sprintf("%s is an alias, use process ID %s", processID, oldProcess.Info.ID), HTTPStatus: http.StatusBadRequest})
 	}
 
 	pluginsDir := os.Getenv("PLUGINS_DIR") // We already know this env variable exist because it is being checked in plguinsInit function
@@ -282,8 +419,9 @@ func (rh *RESTHandler) DeleteProcessHandler(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, errResponse{Message: "Failed to deprecate old process"})
 	}
 
-	rh.ProcessList.List = append(rh.ProcessList.List[:i], rh.ProcessList.List[i+1:]...)
-	rh.ProcessList.InfoList = append(rh.ProcessList.InfoList[:i], rh.ProcessList.InfoList[i+1:]...)
+	if err := rh.ProcessList.Remove(processID); err != nil {
+		return c.JSON(http.StatusInternalServerError, errResponse{Message: "Process was removed while deleting"})
+	}
 
 	return c.JSON(http.StatusOK, map[string]string{"message": "Process deleted successfully"})
 }
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"app/jobs"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Check the job quota of the requester before n jobs are submitted, and set quota headers of the response.
+// Jobs are counted per submitter over a rolling window from job records, so counts survive restarts.
+// Submitters are matched by submitter ID, falling back to email for jobs recorded without one.
+// Without authorization all requests share the quota of the empty submitter.
+// Returns false if the submission exceeds the quota, the response has been written in that case.
+func (rh *RESTHandler) checkJobQuota(c echo.Context, n int) (bool, error) {
+	quota, window := rh.Config.JobQuota, rh.Config.JobQuotaWindow
+	if quota <= 0 {
+		return true, nil
+	}
+
+	now := time.Now()
+	owner := jobs.Owner{
+		SubmitterID: c.Request().Header.Get("X-ProcessAPI-User-ID"),
+		Submitter:   c.Request().Header.Get("X-ProcessAPI-User-Email"),
+	}
+	count, oldest, err := rh.DB.CountSubmissions(owner, now.Add(-window))
+	if err != nil {
+		return false, c.JSON(http.StatusInternalServerError, errResponse{Message: "could not check job quota: " + err.Error()})
+	}
+
+	// the quota frees up as jobs leave the window, starting with the oldest
+	reset := now.Add(window)
+	if count > 0 {
+		reset = oldest.Add(window)
+	}
+
+	header := c.Response().Header()
+	header.Set("X-Quota-Limit", strconv.Itoa(quota))
+	header.Set("X-Quota-Reset", reset.UTC().Format(time.RFC3339))
+
+	if count+n > quota {
+		remaining := quota - count
+		if remaining < 0 {
+			remaining = 0
+		}
+		header.Set("X-Quota-Remaining", strconv.Itoa(remaining))
+		header.Set("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(reset).Seconds()))))
+		msg := fmt.Sprintf("job quota of %d jobs per %s exceeded, %d remaining, quota resets at %s", quota, window, remaining, reset.UTC().Format(time.RFC3339))
+		return false, c.JSON(http.StatusTooManyRequests, errResponse{Message: msg})
+	}
+
+	header.Set("X-Quota-Remaining", strconv.Itoa(quota-count-n))
+	return true, nil
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"app/jobs"
+	"app/utils"
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Job ID of a storage object from its key, keys are of the form {prefix}/{jobID}.{ext} or {prefix}/{jobID}/...
+// Returns false if the key does not belong to a job of the configured job ID format.
+func storageKeyJobID(prefix, key string) (string, bool) {
+	name := strings.TrimPrefix(key, prefix+"/")
+	if i := strings.IndexAny(name, "./"); i != -1 {
+		name = name[:i]
+	}
+	return name, jobs.IsJobID(name)
+}
+
+// ReconcileStorage scans metadata, results and logs in storage for objects of jobs that do not have a database record,
+// and terminated jobs in the database whose logs or metadata are missing from storage. Archived jobs have records too.
+// Discrepancies are logged, orphaned objects are deleted if cleanup is true. Records missing objects are only reported.
+func (rh *RESTHandler) ReconcileStorage(cleanup bool) {
+	log.Info("Starting storage reconciliation.")
+	defer log.Info("Finished storage reconciliation.")
+
+	records := make(map[string]jobs.JobRecord)
+	const pageSize = 1000
+	for offset := 0; ; offset += pageSize {
+		page, err := rh.DB.GetAllJobs(pageSize, offset)
+		if err != nil {
+			log.Errorf("Storage reconciliation: could not list job records: %s", err.Error())
+			return
+		}
+		for _, jr := range page {
+			records[jr.JobID] = jr
+		}
+		if len(page) < pageSize {
+			break
+		}
+	}
+
+	stored := make(map[string]bool)
+	// keys of objects without a job record and their job ID
+	orphans := make(map[string]string)
+	for _, prefix := range []string{os.Getenv("STORAGE_METADATA_PREFIX"), os.Getenv("STORAGE_RESULTS_PREFIX"), os.Getenv("STORAGE_LOGS_PREFIX")} {
+		if prefix == "" {
+			continue
+		}
+		keys, err := utils.ListS3Keys(prefix+"/", rh.StorageSvc)
+		if err != nil {
+			log.Errorf("Storage reconciliation: could not list objects under %s: %s", prefix, err.Error())
+			return
+		}
+		for _, key := range keys {
+			jid, ok := storageKeyJobID(prefix, key)
+			if !ok {
+				continue
+			}
+			stored[key] = true
+			if _, ok := records[jid]; !ok {
+				log.Warnf("Storage reconciliation: object %s has no job record", key)
+				orphans[key] = jid
+			}
+		}
+	}
+
+	var missing int
+	for jid, jr := range records {
+		var expected []string
+		switch jr.Status {
+		case jobs.SUCCESSFUL:
+			expected = append(expected, fmt.Sprintf("%s/%s.json", os.Getenv("STORAGE_METADATA_PREFIX"), jid))
+			fallthrough
+		case jobs.FAILED, jobs.DISMISSED:
+			expected = append(expected, fmt.Sprintf("%s/%s.server.jsonl", os.Getenv("STORAGE_LOGS_PREFIX"), jid))
+		}
+		for _, key := range expected {
+			if !stored[key] {
+				log.Warnf("Storage reconciliation: job %s is %s but object %s is missing", jid, jr.Status, key)
+				missing++
+			}
+		}
+	}
+
+	log.Infof("Storage reconciliation: %d orphaned objects, %d missing objects", len(orphans), missing)
+
+	if cleanup && len(orphans) > 0 {
+		// jobs submitted while scanning would otherwise lose their objects
+		deletable := make([]string, 0, len(orphans))
+		for key, jid := range orphans {
+			if exist, err := rh.DB.CheckJobExist(jid); err == nil && !exist {
+				deletable = append(deletable, key)
+			}
+		}
+
+		if err := utils.DeleteS3Keys(deletable, rh.StorageSvc); err != nil {
+			log.Errorf("Storage reconciliation: could not delete orphaned objects: %s", err.Error())
+			return
+		}
+		log.Infof("Storage reconciliation: deleted %d orphaned objects", len(deletable))
+	}
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// fieldSchema is a minimal JSON schema of a request body property.
+// Items is the schema of the values of an object or the items of an array, nil means any value.
+type fieldSchema struct {
+	Type     string
+	Required bool
+	Items    *fieldSchema
+}
+
+// Schemas of the execution request bodies, these must be kept in sync with runRequestBody and batchRunRequestBody
+var executeRequestSchema = map[string]fieldSchema{
+	"inputs":       {Type: "object", Required: true},
+	"outputs":      {Type: "object", Items: &fieldSchema{Type: "object"}},
+	"env":          {Type: "object", Items: &fieldSchema{Type: "string"}},
+	"resources":    {Type: "object"},
+	"outputPrefix": {Type: "string"},
+	"response":     {Type: "string"},
+	"priority":     {Type: "integer"},
+	"metadata":     {Type: "object"},
+}
+
+var batchExecuteRequestSchema = map[string]fieldSchema{
+	"inputs":    {Type: "array", Required: true, Items: &fieldSchema{Type: "object"}},
+	"env":       {Type: "object", Items: &fieldSchema{Type: "string"}},
+	"resources": {Type: "object"},
+	"priority":  {Type: "integer"},
+	"metadata":  {Type: "object"},
+}
+
+// fieldError describes a schema violation of a request body property
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// validationErrResponse is returned when a request body does not match its schema
+type validationErrResponse struct {
+	Message string       `json:"message"`
+	Errors  []fieldError `json:"errors"`
+}
+
+// ValidateExecuteRequest validates JSON execution request bodies against the execute request schema
+// before the handler runs. Multipart requests are passed through, their inputs can be supplied as file parts.
+func ValidateExecuteRequest(next echo.HandlerFunc) echo.HandlerFunc {
+	return validateRequestBody(executeRequestSchema, next)
+}
+
+// ValidateBatchExecuteRequest validates batch execution request bodies against the batch execute request schema
+// before the handler runs.
+func ValidateBatchExecuteRequest(next echo.HandlerFunc) echo.HandlerFunc {
+	return validateRequestBody(batchExecuteRequestSchema, next)
+}
+
+func validateRequestBody(schema map[string]fieldSchema, next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+			return next(c)
+		}
+
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, errResponse{Message: err.Error()})
+		}
+		// restore body for the handler
+		c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+		var doc interface{}
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return c.JSON(http.StatusBadRequest, validationErrResponse{
+				Message: "request body does not match schema",
+				Errors:  []fieldError{{Field: "", Message: fmt.Sprintf("invalid JSON: %s", err.Error())}},
+			})
+		}
+
+		errs := validateObject(doc, schema)
+		if len(errs) > 0 {
+			return c.JSON(http.StatusBadRequest, validationErrResponse{Message: "request body does not match schema", Errors: errs})
+		}
+		return next(c)
+	}
+}
+
+// Validate properties of a decoded JSON document against schema. Unknown properties are rejected.
+func validateObject(doc interface{}, schema map[string]fieldSchema) []fieldError {
+	obj, ok := doc.(map[string]interface{})
+	if !ok {
+		return []fieldError{{Field: "", Message: "must be an object"}}
+	}
+
+	errs := make([]fieldError, 0)
+	for field, fs := range schema {
+		val, ok := obj[field]
+		if !ok || val == nil {
+			if fs.Required {
+				errs = append(errs, fieldError{Field: field, Message: "is required"})
+			}
+			continue
+		}
+		errs = append(errs, validateField(field, val, fs)...)
+	}
+
+	for field := range obj {
+		if _, ok := schema[field]; !ok {
+			errs = append(errs, fieldError{Field: field, Message: "unknown property"})
+		}
+	}
+
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs
+}
+
+func validateField(path string, val interface{}, fs fieldSchema) []fieldError {
+	errs := make([]fieldError, 0)
+	switch v := val.(type) {
+	case map[string]interface{}:
+		if fs.Type != "object" {
+			return append(errs, fieldError{Field: path, Message: fmt.Sprintf("must be of type %s", fs.Type)})
+		}
+		if fs.Items != nil {
+			for k, item := range v {
+				errs = append(errs, validateField(path+"."+k, item, *fs.Items)...)
+			}
+		}
+	case []interface{}:
+		if fs.Type != "array" {
+			return append(errs, fieldError{Field: path, Message: fmt.Sprintf("must be of type %s", fs.Type)})
+		}
+		if fs.Items != nil {
+			for i, item := range v {
+				errs = append(errs, validateField(fmt.Sprintf("%s[%d]", path, i), item, *fs.Items)...)
+			}
+		}
+	case string:
+		if fs.Type != "string" {
+			return append(errs, fieldError{Field: path, Message: fmt.Sprintf("must be of type %s", fs.Type)})
+		}
+	case float64:
+		if fs.Type != "number" && (fs.Type != "integer" || v != math.Trunc(v)) {
+			return append(errs, fieldError{Field: path, Message: fmt.Sprintf("must be of type %s", fs.Type)})
+		}
+	default:
+		return append(errs, fieldError{Field: path, Message: fmt.Sprintf("must be of type %s", fs.Type)})
+	}
+	return errs
+}
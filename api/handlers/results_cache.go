@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"app/jobs"
+	"app/processes"
+	"app/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+// Storage key of the cache entry of a cache key, entries hold the ID of the job whose results are reused
+func resultsCacheStorageKey(cacheKey string) string {
+	return fmt.Sprintf("%s/cache/%s.json", os.Getenv("STORAGE_RESULTS_PREFIX"), cacheKey)
+}
+
+// Job ID and results of the job of a cache entry.
+// Returns false if there is no entry, or the job of the entry is not successful or its results are not available.
+func (rh *RESTHandler) cachedResults(cacheKey string) (string, interface{}, bool) {
+	key := resultsCacheStorageKey(cacheKey)
+	exist, err := utils.KeyExists(key, rh.StorageSvc)
+	if err != nil {
+		log.Errorf("Could not check results cache entry %s: %s", key, err.Error())
+		return "", nil, false
+	}
+	if !exist {
+		return "", nil, false
+	}
+
+	data, err := utils.GetS3JsonData(key, rh.StorageSvc)
+	if err != nil {
+		log.Errorf("Could not read results cache entry %s: %s", key, err.Error())
+		return "", nil, false
+	}
+	entry, _ := data.(map[string]interface{})
+	jobID, _ := entry["jobID"].(string)
+	if jobID == "" {
+		return "", nil, false
+	}
+
+	jRcrd, ok, err := rh.DB.GetJob(jobID)
+	if err != nil || !ok || jRcrd.Status != jobs.SUCCESSFUL || jRcrd.ResultsExpired() {
+		return "", nil, false
+	}
+
+	outputs, err := jobs.FetchResults(rh.StorageSvc, jobID)
+	if err != nil {
+		return "", nil, false
+	}
+	return jobID, outputs, true
+}
+
+// Point the cache entry of a cache key to a job, the entry is only used once the job is successful
+func (rh *RESTHandler) cacheResults(cacheKey, jobID string) {
+	b, err := json.Marshal(map[string]string{"jobID": jobID})
+	if err != nil {
+		return
+	}
+	key := resultsCacheStorageKey(cacheKey)
+	if err = utils.WriteToS3(rh.StorageSvc, b, key, "application/json", 0); err != nil {
+		log.Errorf("Could not write results cache entry %s: %s", key, err.Error())
+	}
+}
+
+// Respond to an execution request with results of the successful job srcJobID.
+// A new job is recorded as successful without executing the process, its results are the results of srcJobID.
+func (rh *RESTHandler) cacheHit(c echo.Context, p processes.Process, params runRequestBody, jobID, submitterID, srcJobID string, outputs interface{}, outputIDs []string, transmissionModes map[string]string) error {
+	jr := jobs.JobRecord{JobID: jobID, ProcessID: p.Info.ID, Mode: p.Info.JobControlOptions[0], Host: recordHost(p), Submitter: c.Request().Header.Get("X-ProcessAPI-User-Email"), SubmitterID: submitterID}
+	err := jobs.RecordCacheHit(rh.DB, rh.StorageSvc, jr, srcJobID, outputs)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, errResponse{Message: err.Error()})
+	}
+	rh.recordSubmission(jobID, p, outputIDs)
+
+	resp := jobResponse{ProcessID: p.Info.ID, Type: "process", JobID: jobID, Status: jobs.SUCCESSFUL, Message: fmt.Sprintf("results reused from job %s", srcJobID), CacheHit: true}
+	if p.Info.JobControlOptions[0] != "sync-execute" {
+		return c.JSON(http.StatusCreated, resp)
+	}
+
+	if params.Response == "raw" {
+		return rh.rawResponse(c, p, outputs, resp)
+	}
+	outputs, err = p.ApplyResultHook(jobID, selectOutputs(outputs, outputIDs))
+	if err != nil {
+		resp.Message = "error fetching results. Error: " + err.Error()
+		return c.JSON(http.StatusInternalServerError, resp)
+	}
+	resp.Outputs = applyTransmissionModes(outputs, transmissionModes, jobID)
+	rh.limitInlineOutputs(&resp)
+	return c.JSON(http.StatusOK, resp)
+}
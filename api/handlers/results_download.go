@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"app/jobs"
+	"app/utils"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/labstack/echo/v4"
+)
+
+// Storage key of an output value that references an object in the storage bucket.
+// Output values can be an s3 uri string or an object with an s3 uri href.
+// Returns false if the value does not reference an object in the storage bucket.
+func outputStorageKey(v interface{}) (string, bool) {
+	var href string
+	switch val := v.(type) {
+	case string:
+		href = val
+	case map[string]interface{}:
+		href, _ = val["href"].(string)
+	}
+
+	prefix := fmt.Sprintf("s3://%s/", os.Getenv("STORAGE_BUCKET"))
+	if !strings.HasPrefix(href, prefix) || len(href) == len(prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(href, prefix), true
+}
+
+// @Summary Job Result Download
+// @Description Streams an output of a successful job stored in the storage bucket. Supports the Range header, partial content is returned with 206.
+// @Tags jobs
+// @Accept */*
+// @Produce octet-stream
+// @Param jobID path string true "example: 44d9ca0e-2ca7-4013-907f-a8ccc60da3b4"
+// @Param outputID path string true "id of the output"
+// @Param Range header string false "ex: bytes=0-1023"
+// @Success 200 {file} file
+// @Success 206 {file} file
+// @Router /jobs/{jobID}/results/{outputID} [get]
+// Does not produce HTML
+func (rh *RESTHandler) JobResultDownloadHandler(c echo.Context) error {
+	jobID := c.Param("jobID")
+	outputID := c.Param("outputID")
+
+	// content type of stored outputs written without one
+	var defaultContentType string
+	if job, ok := rh.ActiveJobs.Jobs[jobID]; ok && rh.jobReadable(c, (*job).SUBMITTER(), (*job).SUBMITTERID()) { // ActiveJobs hit
+		if (*job).CurrentStatus() != jobs.SUCCESSFUL {
+			return c.JSON(http.StatusNotFound, errResponse{Type: exceptionResultNotReady, Message: fmt.Sprintf("results not ready, job %s", (*job).CurrentStatus())})
+		}
+		if p, _, err := rh.ProcessList.Get((*job).ProcessID()); err == nil {
+			defaultContentType = p.DefaultResultContentType
+		}
+	} else if jRcrd, ok, err := rh.DB.GetJob(jobID); err != nil {
+		return c.JSON(http.StatusInternalServerError, errResponse{Message: err.Error()})
+	} else if !ok || !rh.jobReadable(c, jRcrd.Submitter, jRcrd.SubmitterID) { // miss
+		return c.JSON(http.StatusNotFound, errResponse{Type: exceptionNoSuchJob, Message: fmt.Sprintf("%s job id not found", jobID)})
+	} else if jRcrd.Status != jobs.SUCCESSFUL {
+		return c.JSON(http.StatusNotFound, errResponse{Message: "job Failed or Dismissed. Call logs route for details"})
+	} else if jRcrd.ResultsExpired() {
+		return c.JSON(http.StatusGone, errResponse{Message: fmt.Sprintf("results expired at %s", jRcrd.ExpiresAt.UTC().Format(time.RFC3339))})
+	} else if p, ok := rh.submittedProcess(jRcrd); ok {
+		defaultContentType = p.DefaultResultContentType
+	}
+
+	outputs, err := jobs.FetchResults(rh.StorageSvc, jobID)
+	if err != nil {
+		if err.Error() == "not found" {
+			return c.JSON(http.StatusNotFound, errResponse{Message: "results not available"})
+		}
+		return c.JSON(http.StatusInternalServerError, errResponse{Message: err.Error()})
+	}
+
+	results, _ := outputs.(map[string]interface{})
+	val, ok := results[outputID]
+	if !ok {
+		return c.JSON(http.StatusNotFound, errResponse{Message: fmt.Sprintf("output %s not found", outputID)})
+	}
+	key, ok := outputStorageKey(val)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, errResponse{Message: fmt.Sprintf("output %s is not stored in storage and can not be downloaded, use the results route", outputID)})
+	}
+
+	// only single byte ranges are supported
+	byteRange := c.Request().Header.Get("Range")
+	if byteRange != "" && (!strings.HasPrefix(byteRange, "bytes=") || strings.Contains(byteRange, ",")) {
+		return c.JSON(http.StatusRequestedRangeNotSatisfiable, errResponse{Message: "Range must be a single byte range, ex: bytes=0-1023"})
+	}
+
+	return rh.streamStorageObject(c, key, byteRange, "", defaultContentType)
+}
+
+// Content types storage reports for objects written without one
+var unsetContentTypes = []string{"", "binary/octet-stream", echo.MIMEOctetStream}
+
+// Stream an object from storage, errors are returned as JSON before any bytes of the object are written.
+// If filename is not empty the object is sent as an attachment with this filename.
+// Objects stored without a content type are sent with defaultContentType, or as octet-stream if it is empty.
+func (rh *RESTHandler) streamStorageObject(c echo.Context, key, byteRange, filename, defaultContentType string) error {
+	obj, err := utils.GetS3Object(key, byteRange, rh.StorageSvc)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case "NoSuchKey", "NotFound":
+				return c.JSON(http.StatusNotFound, errResponse{Message: fmt.Sprintf("%s not found in storage", key)})
+			case "InvalidRange":
+				return c.JSON(http.StatusRequestedRangeNotSatisfiable, errResponse{Message: "requested range not satisfiable"})
+			}
+		}
+		return c.JSON(http.StatusInternalServerError, errResponse{Message: err.Error()})
+	}
+	defer obj.Body.Close()
+
+	header := c.Response().Header()
+	header.Set("Accept-Ranges", "bytes")
+	if obj.ContentLength != nil {
+		header.Set(echo.HeaderContentLength, strconv.FormatInt(*obj.ContentLength, 10))
+	}
+	if obj.ETag != nil {
+		header.Set("ETag", *obj.ETag)
+	}
+	if filename != "" {
+		header.Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+
+	contentType := aws.StringValue(obj.ContentType)
+	if utils.StringInSlice(contentType, unsetContentTypes) {
+		contentType = defaultContentType
+	}
+	if contentType == "" {
+		contentType = echo.MIMEOctetStream
+	}
+
+	status := http.StatusOK
+	if obj.ContentRange != nil {
+		header.Set("Content-Range", *obj.ContentRange)
+		status = http.StatusPartialContent
+	}
+	return c.Stream(status, contentType, obj.Body)
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"app/utils"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+const mimeNDJSON = "application/x-ndjson"
+
+// NDJSON is returned when requested through Accept header, the f query parameter takes precedence
+func wantsNDJSON(c echo.Context) bool {
+	return c.QueryParam("f") == "" && strings.Contains(c.Request().Header.Get(echo.HeaderAccept), mimeNDJSON)
+}
+
+// Records of results written as NDJSON lines. Arrays yield their elements and feature collections their features,
+// results with a single output yield records of that output, anything else is a single record.
+func ndjsonRecords(v interface{}) []interface{} {
+	switch val := v.(type) {
+	case []interface{}:
+		return val
+	case map[string]interface{}:
+		if features, ok := val["features"].([]interface{}); ok && val["type"] == "FeatureCollection" {
+			return features
+		}
+		if len(val) == 1 {
+			for _, output := range val {
+				return ndjsonRecords(output)
+			}
+		}
+	}
+	return []interface{}{v}
+}
+
+// Stream results as newline delimited JSON, flushing every record so that clients can process them incrementally
+func writeNDJSON(c echo.Context, results interface{}) error {
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, mimeNDJSON)
+	res.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(res)
+	for _, r := range ndjsonRecords(results) {
+		// encoder terminates every record with a newline
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+		res.Flush()
+	}
+	return nil
+}
+
+// Storage key of the output of results with a single output stored in the storage bucket
+func singleStoredOutput(results interface{}) (string, bool) {
+	outputs, ok := results.(map[string]interface{})
+	if !ok || len(outputs) != 1 {
+		return "", false
+	}
+	for _, v := range outputs {
+		return outputStorageKey(v)
+	}
+	return "", false
+}
+
+// Stream results as NDJSON. A single output stored in the storage bucket is streamed from storage record by record,
+// so that large outputs are not loaded in memory. Other results are written from memory.
+func (rh *RESTHandler) streamResultsNDJSON(c echo.Context, jobID string, results interface{}) error {
+	key, ok := singleStoredOutput(results)
+	if !ok {
+		return writeNDJSON(c, results)
+	}
+
+	obj, err := utils.GetS3Object(key, "", rh.StorageSvc)
+	if err != nil {
+		output := errResponse{HTTPStatus: http.StatusInternalServerError, Message: fmt.Sprintf("could not read %s from storage: %s", key, err.Error())}
+		return prepareResponse(c, http.StatusInternalServerError, "error", output)
+	}
+	defer obj.Body.Close()
+
+	var body io.Reader = obj.Body
+	if aws.StringValue(obj.ContentEncoding) == "gzip" {
+		gz, err := gzip.NewReader(obj.Body)
+		if err != nil {
+			output := errResponse{HTTPStatus: http.StatusInternalServerError, Message: fmt.Sprintf("could not decompress %s: %s", key, err.Error())}
+			return prepareResponse(c, http.StatusInternalServerError, "error", output)
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, mimeNDJSON)
+	res.WriteHeader(http.StatusOK)
+
+	// errors after the status is written abort the response, leaving the stream truncated
+	if mt, _, _ := mime.ParseMediaType(aws.StringValue(obj.ContentType)); mt == mimeNDJSON {
+		_, err = io.Copy(res, body)
+	} else {
+		err = streamJSONRecords(json.NewDecoder(body), json.NewEncoder(res), res.Flush)
+	}
+	if err != nil {
+		log.Errorf("Could not stream %s as NDJSON results of job %s. Error: %s", key, jobID, err.Error())
+	}
+	return err
+}
+
+// Decode a JSON document token by token, writing records the same way as ndjsonRecords: elements of an array,
+// features of an object with a features array or the document itself. Only one record is held in memory at a time,
+// except for members of an object other than features.
+func streamJSONRecords(dec *json.Decoder, enc *json.Encoder, flush func()) error {
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == json.Delim('[') {
+		return streamJSONArray(dec, enc, flush)
+	}
+	if tok != json.Delim('{') {
+		return writeRecord(enc, flush, tok)
+	}
+
+	members := make(map[string]interface{})
+	streamed := false
+	for dec.More() {
+		keyTok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := keyTok.(string)
+		valTok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		// features are written as they are decoded, the object is not a record anymore
+		if key == "features" && valTok == json.Delim('[') && !streamed {
+			if err := streamJSONArray(dec, enc, flush); err != nil {
+				return err
+			}
+			streamed = true
+			continue
+		}
+		if members[key], err = decodeFrom(dec, valTok); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	if streamed {
+		return nil
+	}
+	return writeRecord(enc, flush, members)
+}
+
+// Write elements of an array whose opening bracket has been read, consuming the closing bracket
+func streamJSONArray(dec *json.Decoder, enc *json.Encoder, flush func()) error {
+	for dec.More() {
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		if err := writeRecord(enc, flush, v); err != nil {
+			return err
+		}
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// Value starting with a token already read from the decoder
+func decodeFrom(dec *json.Decoder, tok json.Token) (interface{}, error) {
+	switch tok {
+	case json.Delim('['):
+		values := make([]interface{}, 0)
+		for dec.More() {
+			var v interface{}
+			if err := dec.Decode(&v); err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		_, err := dec.Token()
+		return values, err
+	case json.Delim('{'):
+		members := make(map[string]interface{})
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := keyTok.(string)
+			var v interface{}
+			if err := dec.Decode(&v); err != nil {
+				return nil, err
+			}
+			members[key] = v
+		}
+		_, err := dec.Token()
+		return members, err
+	default:
+		return tok, nil
+	}
+}
+
+// encoder terminates every record with a newline
+func writeRecord(enc *json.Encoder, flush func(), v interface{}) error {
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	flush()
+	return nil
+}
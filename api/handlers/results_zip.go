@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"app/utils"
+	"archive/zip"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+const mimeZip = "application/zip"
+
+// ZIP archive of output files is returned when requested through Accept header, the f query parameter takes precedence
+func wantsZip(c echo.Context) bool {
+	return c.QueryParam("f") == "" && strings.Contains(c.Request().Header.Get(echo.HeaderAccept), mimeZip)
+}
+
+// Storage keys of output files of results, keyed by their name in the archive.
+// Files are placed in a directory per output, outputs that are not stored in storage are skipped.
+func zipEntries(results interface{}) map[string]string {
+	entries := make(map[string]string)
+	outputs, ok := results.(map[string]interface{})
+	if !ok {
+		return entries
+	}
+
+	for id, val := range outputs {
+		values, ok := val.([]interface{})
+		if !ok {
+			values = []interface{}{val}
+		}
+		for i, v := range values {
+			key, ok := outputStorageKey(v)
+			if !ok {
+				continue
+			}
+			name := path.Join(id, path.Base(key))
+			// same file name in an output array, index keeps entries unique
+			if _, exists := entries[name]; exists {
+				name = path.Join(id, fmt.Sprintf("%d_%s", i, path.Base(key)))
+			}
+			entries[name] = key
+		}
+	}
+	return entries
+}
+
+// Stream output files of a job as a ZIP archive. Files are copied from storage into the archive one at a time,
+// the archive is not buffered. Errors after the first byte is written abort the response, leaving the archive truncated.
+func (rh *RESTHandler) writeResultsZip(c echo.Context, jobID string, results interface{}) error {
+	entries := zipEntries(results)
+	if len(entries) == 0 {
+		output := errResponse{HTTPStatus: http.StatusNotFound, Message: "job has no output files to archive"}
+		return prepareResponse(c, http.StatusNotFound, "error", output)
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, mimeZip)
+	res.Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": jobID + ".zip"}))
+	res.WriteHeader(http.StatusOK)
+
+	zw := zip.NewWriter(res)
+	for _, name := range names {
+		if err := rh.copyToZip(zw, name, entries[name]); err != nil {
+			log.Errorf("Could not write %s to results archive of job %s. Error: %s", name, jobID, err.Error())
+			return err
+		}
+		res.Flush()
+	}
+	return zw.Close()
+}
+
+func (rh *RESTHandler) copyToZip(zw *zip.Writer, name, key string) error {
+	obj, err := utils.GetS3Object(key, "", rh.StorageSvc)
+	if err != nil {
+		return err
+	}
+	defer obj.Body.Close()
+
+	modified := time.Now()
+	if obj.LastModified != nil {
+		modified = *obj.LastModified
+	}
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, obj.Body)
+	return err
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"app/jobs"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Window of job statistics when not provided by the client
+const defaultStatsWindow = 24 * time.Hour
+
+// @Summary Job Statistics
+// @Description Counts by status, failure rates and durations per process of jobs updated within a time window
+// @Tags info
+// @Produce json
+// @Param window query string false "duration of the window, e.g. 1h, 24h (default 24h)"
+// @Success 200 {object} jobs.JobStats
+// @Failure 400 {object} errResponse
+// @Failure 500 {object} errResponse
+// @Router /stats [get]
+// Does not produce HTML
+func (rh *RESTHandler) StatsHandler(c echo.Context) error {
+	window := defaultStatsWindow
+	if w := c.QueryParam("window"); w != "" {
+		var err error
+		window, err = time.ParseDuration(w)
+		if err != nil || window <= 0 {
+			return c.JSON(http.StatusBadRequest, errResponse{Message: "window must be a positive duration, e.g. 1h or 24h"})
+		}
+	}
+
+	stats, err := jobs.ComputeJobStats(rh.DB, time.Now().Add(-window))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, errResponse{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, stats)
+}
@@ -1,13 +1,19 @@
 package jobs
 
 import (
+	"sort"
 	"sync"
+	"time"
+
+	"app/utils"
 )
 
 // It is the resoponsibility of originator to add and remove job from ActiveJobs
 type ActiveJobs struct {
 	Jobs map[string]*Job `json:"jobs"`
 	mu   sync.Mutex
+	// Number of terminated jobs purged since startup
+	evicted uint64
 }
 
 func (ac *ActiveJobs) Add(j *Job) {
@@ -37,3 +43,112 @@ func (ac *ActiveJobs) KillAll() {
 		}
 	}
 }
+
+// Remove jobs that have been in a terminated status for longer than retention.
+// Returns the number of jobs removed. Jobs in accepted or running status are never removed.
+func (ac *ActiveJobs) PurgeTerminated(retention time.Duration) int {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	var n int
+	for jid, j := range ac.Jobs {
+		switch (*j).CurrentStatus() {
+		case SUCCESSFUL, DISMISSED, FAILED:
+			if time.Since((*j).LastUpdate()) > retention {
+				delete(ac.Jobs, jid)
+				n++
+			}
+		}
+	}
+	ac.evicted += uint64(n)
+	return n
+}
+
+// Returns number of jobs held, number of jobs in accepted or running status,
+// and number of jobs purged since startup.
+func (ac *ActiveJobs) Stats() (entries int, active int, evicted uint64) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	for _, j := range ac.Jobs {
+		if (*j).CurrentStatus() == ACCEPTED || (*j).CurrentStatus() == RUNNING {
+			active++
+		}
+	}
+	return len(ac.Jobs), active, ac.evicted
+}
+
+// Returns jobs that are currently in accepted or running status.
+func (ac *ActiveJobs) Unfinished() []Job {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	unfinished := make([]Job, 0)
+	for _, j := range ac.Jobs {
+		if (*j).CurrentStatus() == ACCEPTED || (*j).CurrentStatus() == RUNNING {
+			unfinished = append(unfinished, *j)
+		}
+	}
+	return unfinished
+}
+
+// Returns jobs that are currently in running status.
+func (ac *ActiveJobs) Running() []Job {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	running := make([]Job, 0)
+	for _, j := range ac.Jobs {
+		if (*j).CurrentStatus() == RUNNING {
+			running = append(running, *j)
+		}
+	}
+	return running
+}
+
+// Returns records of jobs in accepted or running status, most recently updated first.
+// Jobs are only included if they match every non empty filter.
+func (ac *ActiveJobs) Records(processIDs, statuses, submitters []string, owner *Owner) []JobRecord {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	records := make([]JobRecord, 0)
+	for _, j := range ac.Jobs {
+		status := (*j).CurrentStatus()
+		if status != ACCEPTED && status != RUNNING {
+			continue
+		}
+		if (len(processIDs) > 0 && !utils.StringInSlice((*j).ProcessID(), processIDs)) ||
+			(len(statuses) > 0 && !utils.StringInSlice(status, statuses)) ||
+			(len(submitters) > 0 && !utils.StringInSlice((*j).SUBMITTER(), submitters)) ||
+			(owner != nil && !owner.Owns((*j).SUBMITTER(), (*j).SUBMITTERID())) {
+			continue
+		}
+		records = append(records, jobRecord(*j))
+	}
+
+	sort.Slice(records, func(i, k int) bool {
+		return records[i].LastUpdate.After(records[k].LastUpdate)
+	})
+	return records
+}
+
+// Record of a job as stored in the database
+func jobRecord(j Job) JobRecord {
+	jr := JobRecord{
+		JobID:       j.JobID(),
+		LastUpdate:  j.LastUpdate(),
+		Status:      j.CurrentStatus(),
+		ProcessID:   j.ProcessID(),
+		Type:        "process",
+		Submitter:   j.SUBMITTER(),
+		SubmitterID: j.SUBMITTERID(),
+	}
+	switch j.(type) {
+	case *DockerJob, *SubprocessJob:
+		jr.Host = "local"
+	case *AWSBatchJob:
+		jr.Host = "aws-batch"
+	}
+	return jr
+}
@@ -2,12 +2,12 @@ package jobs
 
 import (
 	"app/controllers"
-	"app/utils"
 	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -28,15 +28,17 @@ type AWSBatchJob struct {
 	// Used for monitoring running complete for sync jobs
 	wgRun sync.WaitGroup
 
-	UUID           string `json:"jobID"`
-	AWSBatchID     string
+	UUID       string `json:"jobID"`
+	AWSBatchID string
+	// Guards AWSBatchID of jobs held by the paused scheduler, which are submitted to AWS Batch once it is resumed
+	submitMu       sync.Mutex
 	Image          string `json:"image"`
 	ProcessName    string `json:"processID"`
 	ProcessVersion string
 	Submitter      string
+	SubmitterID    string
 	Cmd            []string `json:"commandOverride"`
-	UpdateTime     time.Time
-	Status         string `json:"status"`
+	jobStatus
 	// results       interface{}
 
 	logger  *log.Logger
@@ -53,11 +55,50 @@ type AWSBatchJob struct {
 	cloudWatchForwardToken string
 	// MetaData
 
+	// Storage location results are written to, passed to the process as OUTPUT_LOCATION
+	OutputLocation string
+	// X-Request-ID of the execution request that created the job
+	RequestID string
+	// Custom fields of the execution request, written to the metadata document
+	Metadata map[string]interface{}
+	// Results are checked against output schemas of the process before the job is marked successful, nil skips the check
+	ResultsCheck *ResultsCheck
+	// Scheduling priority, nil means default priority of the job queue.
+	// Also the order in which jobs held by the paused scheduler are submitted.
+	Priority *int
+
+	// Overrides of the job definition resources, zero fields are not overridden
+	Resources
 	DB         Database
 	StorageSvc *s3.S3
 	DoneChan   chan Job
 }
 
+var (
+	invalidBatchJobNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+	// Matches IDs of the job ID format, see SetJobIDFormat
+	jobIDPattern = regexp.MustCompile(uuidPattern)
+)
+
+// BatchJobName renders an AWS Batch job name from a template with {apiName}, {processID} and {jobID} placeholders.
+// Batch job names can only have letters, numbers, hyphens and underscores, must start with a letter or number
+// and be at most 128 characters. Invalid characters are replaced with underscores and long names are
+// truncated from the start so that the job ID at the end of the name is preserved.
+func BatchJobName(tmpl, apiName, processID, jobID string) string {
+	name := strings.NewReplacer("{apiName}", apiName, "{processID}", processID, "{jobID}", jobID).Replace(tmpl)
+	name = invalidBatchJobNameChars.ReplaceAllString(name, "_")
+	if len(name) > 128 {
+		name = name[len(name)-128:]
+	}
+	return strings.TrimLeft(name, "_-")
+}
+
+// JobIDFromBatchJobName extracts job ID from an AWS Batch job name created using BatchJobName.
+// Returns empty string if the name does not contain a job ID.
+func JobIDFromBatchJobName(name string) string {
+	return jobIDPattern.FindString(name)
+}
+
 func (j *AWSBatchJob) WaitForRunCompletion() {
 	j.wgRun.Wait()
 }
@@ -74,6 +115,10 @@ func (j *AWSBatchJob) SUBMITTER() string {
 	return j.Submitter
 }
 
+func (j *AWSBatchJob) SUBMITTERID() string {
+	return j.SubmitterID
+}
+
 func (j *AWSBatchJob) ProcessVersionID() string {
 	return j.ProcessVersion
 }
@@ -146,30 +191,8 @@ func (j *AWSBatchJob) LogMessage(m string, level log.Level) {
 	}
 }
 
-func (j *AWSBatchJob) LastUpdate() time.Time {
-	return j.UpdateTime
-}
-
-func (j *AWSBatchJob) NewStatusUpdate(status string, updateTime time.Time) {
-
-	// If old status is one of the terminated status, it should not update status.
-	switch j.Status {
-	case SUCCESSFUL, DISMISSED, FAILED:
-		return
-	}
-
-	j.Status = status
-	if updateTime.IsZero() {
-		j.UpdateTime = time.Now()
-	} else {
-		j.UpdateTime = updateTime
-	}
-	j.DB.updateJobRecord(j.UUID, status, j.UpdateTime)
-	j.logger.Infof("Status changed to %s.", status)
-}
-
-func (j *AWSBatchJob) CurrentStatus() string {
-	return j.Status
+func (j *AWSBatchJob) NewStatusUpdate(status string, updateTime time.Time) bool {
+	return j.jobStatus.update(j, j.DB, j.ResultsCheck, j.logger, status, updateTime)
 }
 
 func (j *AWSBatchJob) ProviderID() string {
@@ -219,31 +242,36 @@ func (j *AWSBatchJob) Create() error {
 	if err != nil {
 		return err
 	}
+	if j.RequestID != "" {
+		j.logger.Infof("Request ID: %s", j.RequestID)
+	}
 	j.logger.Info("Container Commands: ", j.CMD())
 
 	ctx, cancelFunc := context.WithCancel(context.TODO())
 	j.ctx = ctx
 	j.ctxCancel = cancelFunc
 
-	batchContext, err := controllers.NewAWSBatchController(os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_REGION"))
+	batchContext, err := controllers.NewAWSBatchController(os.Getenv("AWS_REGION"))
 	if err != nil {
 		j.ctxCancel()
 		return err
 	}
 
-	aWSBatchID, err := batchContext.JobCreate(j.ctx, j.JobDef, j.JobName, j.JobQueue, j.Cmd, j.EnvVars)
-	if err != nil {
-		j.ctxCancel()
-		return err
+	j.batchContext = batchContext
+
+	// submission errors are returned to the client unless the job is held by the paused scheduler
+	held := GetSchedulerState().Paused
+	if !held {
+		if err = j.submit(); err != nil {
+			j.ctxCancel()
+			return err
+		}
 	}
 
 	j.wgRun.Add(1) // When status is one of the final status this should be decremented, this is the responsibility of who ever is updating status
 
-	j.AWSBatchID = aWSBatchID
-	j.batchContext = batchContext
-
 	// At this point job is ready to be added to database
-	err = j.DB.addJob(j.UUID, "accepted", "", "aws-batch", j.ProcessName, j.Submitter, time.Now())
+	err = j.DB.addJob(j.UUID, "accepted", "", "aws-batch", j.ProcessName, j.Submitter, j.SubmitterID, time.Now())
 	if err != nil {
 		j.ctxCancel()
 		return err
@@ -251,11 +279,56 @@ func (j *AWSBatchJob) Create() error {
 
 	j.NewStatusUpdate(ACCEPTED, time.Time{})
 
+	if held {
+		go j.submitWhenResumed()
+	}
+
 	// to do defer get log stream name
 
 	return nil
 }
 
+// Submit the job to AWS Batch
+func (j *AWSBatchJob) submit() error {
+	aWSBatchID, err := j.batchContext.JobCreate(j.ctx, j.JobDef, j.JobName, j.JobQueue, j.Cmd, j.EnvVars, j.Resources.CPUs, j.Resources.Memory, j.Priority)
+	if err != nil {
+		return err
+	}
+	j.AWSBatchID = aWSBatchID
+	return nil
+}
+
+// Submit the job to AWS Batch once the scheduler is resumed, the job fails if it can not be submitted
+func (j *AWSBatchJob) submitWhenResumed() {
+	if !waitForScheduler(j.ctx, j.logger, j.Priority) {
+		return
+	}
+
+	j.submitMu.Lock()
+	defer j.submitMu.Unlock()
+
+	// dismissed while held
+	if j.CurrentStatus() != ACCEPTED {
+		return
+	}
+	if err := j.submit(); err != nil {
+		j.logger.Errorf("Could not submit job to AWS Batch. Error: %s", err.Error())
+		if j.NewStatusUpdate(FAILED, time.Time{}) {
+			go func() {
+				j.Close()
+				j.RunFinished()
+			}()
+		}
+	}
+}
+
+// Returns false if the job has not been submitted to AWS Batch yet
+func (j *AWSBatchJob) submitted() bool {
+	j.submitMu.Lock()
+	defer j.submitMu.Unlock()
+	return j.AWSBatchID != ""
+}
+
 func (j *AWSBatchJob) Kill() error {
 	j.logger.Info("Received dismiss signal.")
 
@@ -265,7 +338,20 @@ func (j *AWSBatchJob) Kill() error {
 		return fmt.Errorf("can't call delete on an already completed, failed, or dismissed job")
 	}
 
-	c, err := controllers.NewAWSBatchController(os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_REGION"))
+	j.submitMu.Lock()
+	if j.AWSBatchID == "" {
+		// held by the paused scheduler, there is nothing to cancel on AWS Batch
+		dismissed := j.NewStatusUpdate(DISMISSED, time.Time{})
+		j.submitMu.Unlock()
+		if !dismissed {
+			return fmt.Errorf("can't call delete on an already completed, failed, or dismissed job")
+		}
+		go j.Close()
+		return nil
+	}
+	j.submitMu.Unlock()
+
+	c, err := controllers.NewAWSBatchController(os.Getenv("AWS_REGION"))
 	if err != nil {
 		j.logger.Errorf("Could not send kill signal to AWS Batch API. Error: %s", err.Error())
 		return err
@@ -277,7 +363,10 @@ func (j *AWSBatchJob) Kill() error {
 		return err
 	}
 
-	j.NewStatusUpdate(DISMISSED, time.Time{})
+	// the job may have terminated on its own while it was being killed
+	if !j.NewStatusUpdate(DISMISSED, time.Time{}) {
+		return fmt.Errorf("can't call delete on an already completed, failed, or dismissed job")
+	}
 	// If a dismiss status is updated the job is considered dismissed at this point
 	// Close being graceful or not does not matter.
 
@@ -289,7 +378,7 @@ func (j *AWSBatchJob) Kill() error {
 
 // Get log stream name for this job
 func (j *AWSBatchJob) getLogStreamName() (err error) {
-	c, err := controllers.NewAWSBatchController(os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_DEFAULT_REGION"))
+	c, err := controllers.NewAWSBatchController(os.Getenv("AWS_DEFAULT_REGION"))
 	if err != nil {
 		return
 	}
@@ -326,6 +415,12 @@ func (j *AWSBatchJob) fetchCloudWatchLogs() ([]string, error) {
 
 	svc := cloudwatchlogs.New(sess)
 
+	// the first fetch reads the end of the stream, so that memory is bounded however much the job has logged
+	if j.cloudWatchForwardToken == "" {
+		return j.fetchCloudWatchLogsTail(svc)
+	}
+
+	var budget logsBudget
 	logs := make([]string, 0)
 	for {
 		// Define the parameters for the log stream
@@ -347,14 +442,13 @@ func (j *AWSBatchJob) fetchCloudWatchLogs() ([]string, error) {
 				j.logger.Error(err)
 				// reset everything
 				j.cloudWatchForwardToken = ""
-				logs = make([]string, 0)
 				// overwrite file
 				file, err := os.Create(fmt.Sprintf("%s/%s.process.jsonl", os.Getenv("TMP_JOB_LOGS_DIR"), j.UUID))
 				if err != nil {
 					return nil, fmt.Errorf("failed to open log file: %s", err.Error())
 				}
 				file.Close()
-				continue
+				return j.fetchCloudWatchLogsTail(svc)
 			} else if err.Error() == "ResourceNotFoundException: The specified log stream does not exist." {
 				return []string{}, nil
 			} else {
@@ -365,6 +459,11 @@ func (j *AWSBatchJob) fetchCloudWatchLogs() ([]string, error) {
 
 		// Get the log events
 		for _, event := range resp.Events {
+			if !budget.take(*event.Message) {
+				// new events do not fit within the limits, the most recent ones are read from the end of the stream
+				// instead, events before them are skipped
+				return j.fetchCloudWatchLogsTail(svc)
+			}
 			logs = append(logs, *event.Message)
 		}
 
@@ -383,8 +482,10 @@ func (j *AWSBatchJob) WriteMetaData() {
 	j.wg.Add(1)
 	defer j.wg.Done()
 	defer j.logger.Info("Finished metadata writing routine.")
+	release := acquireStorageWorker()
+	defer release()
 
-	c, err := controllers.NewAWSBatchController(os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_REGION"))
+	c, err := controllers.NewAWSBatchController(os.Getenv("AWS_REGION"))
 	if err != nil {
 		j.logger.Errorf("Error writing metadata: %s", err.Error())
 		return
@@ -428,9 +529,13 @@ func (j *AWSBatchJob) WriteMetaData() {
 		Process:         p,
 		Image:           i,
 		Commands:        j.Cmd,
+		OutputLocation:  j.OutputLocation,
+		RequestID:       j.RequestID,
+		Custom:          j.Metadata,
 		GeneratedAtTime: g,
 		StartedAtTime:   s,
 		EndedAtTime:     e,
+		Results:         metaDataResultsIntegrity(j, j.StorageSvc),
 	}
 
 	jsonBytes, err := json.Marshal(md)
@@ -442,7 +547,10 @@ func (j *AWSBatchJob) WriteMetaData() {
 	metadataDir := os.Getenv("STORAGE_METADATA_PREFIX")
 	mdLocation := fmt.Sprintf("%s/%s.json", metadataDir, j.UUID)
 	// TODO: Determine if batch metadata should be put on aws...currently this is the case
-	utils.WriteToS3(j.StorageSvc, jsonBytes, mdLocation, "application/json", 0)
+	err = writeToStorage(j.StorageSvc, j.UUID, jsonBytes, mdLocation, "application/json")
+	if err != nil {
+		j.logger.Errorf("Error writing metadata, write will be retried: %s", err.Error())
+	}
 }
 
 // func (j *AWSBatchJob) WriteResults(data []byte) (err error) {
@@ -458,6 +566,19 @@ func (j *AWSBatchJob) WriteMetaData() {
 // 	return
 // }
 
+// Cancel or terminate the job on AWS Batch without changing its status
+func (j *AWSBatchJob) terminate() error {
+	if !j.submitted() {
+		return nil
+	}
+	c, err := controllers.NewAWSBatchController(os.Getenv("AWS_REGION"))
+	if err != nil {
+		return err
+	}
+	_, err = c.JobKill(j.AWSBatchID)
+	return err
+}
+
 func (j *AWSBatchJob) RunFinished() {
 	j.wgRun.Done()
 }
@@ -469,7 +590,8 @@ func (j *AWSBatchJob) Close() {
 
 	const maxAttempts = 5
 
-	for i := 1; i <= maxAttempts; i++ {
+	// jobs dismissed while held by the paused scheduler never ran and have no logs
+	for i := 1; i <= maxAttempts && j.submitted(); i++ {
 		// It can take a few moments for logs to be delivered to CloudWatch
 		// Programs like docker (which might be running this app) don't give much time after sending interrupt signal
 		// Hence this duration can't be too high
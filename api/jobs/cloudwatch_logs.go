@@ -0,0 +1,98 @@
+package jobs
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+// Limits of log events retained by a single fetch of CloudWatch logs of AWS Batch jobs, 0 means no limit
+var cloudWatchLogsLimits = struct {
+	maxEvents int
+	maxBytes  int
+}{maxEvents: 100000, maxBytes: 100 * 1024 * 1024}
+
+// SetCloudWatchLogsLimits sets how many log events and bytes of messages a single fetch of CloudWatch logs retains,
+// 0 means no limit. Jobs logging more keep only their most recent events, so that results at the end of logs are kept.
+// Must be called at startup before any job is created.
+func SetCloudWatchLogsLimits(maxEvents, maxBytes int) {
+	cloudWatchLogsLimits.maxEvents = maxEvents
+	cloudWatchLogsLimits.maxBytes = maxBytes
+}
+
+// Counts log events retained by a fetch against cloudWatchLogsLimits
+type logsBudget struct {
+	events int
+	bytes  int
+}
+
+// Take budget for msg, returns false if it does not fit within the limits
+func (b *logsBudget) take(msg string) bool {
+	l := cloudWatchLogsLimits
+	if (l.maxEvents > 0 && b.events+1 > l.maxEvents) || (l.maxBytes > 0 && b.bytes+len(msg) > l.maxBytes) {
+		return false
+	}
+	b.events++
+	b.bytes += len(msg)
+	return true
+}
+
+// Fetch the most recent log events of the log stream within cloudWatchLogsLimits, paging backward from the end of the
+// stream and stopping once the limits are hit. A truncation marker is the first line if older events were left out.
+// Following fetches continue forward from the end of the stream.
+func (j *AWSBatchJob) fetchCloudWatchLogsTail(svc *cloudwatchlogs.CloudWatchLogs) ([]string, error) {
+	var budget logsBudget
+	// messages from the newest to the oldest
+	var reversed []string
+	var forwardToken string
+	var backwardToken *string
+	truncated := false
+
+	for !truncated {
+		resp, err := svc.GetLogEvents(&cloudwatchlogs.GetLogEventsInput{
+			LogGroupName:  aws.String(os.Getenv("BATCH_LOG_STREAM_GROUP")),
+			LogStreamName: aws.String(j.logStreamName),
+			StartFromHead: aws.Bool(false),
+			NextToken:     backwardToken,
+		})
+		if err != nil {
+			if err.Error() == "ResourceNotFoundException: The specified log stream does not exist." {
+				return []string{}, nil
+			}
+			j.logger.Error(err)
+			return nil, err
+		}
+
+		// first page is the end of the stream, new events are fetched forward from it
+		if backwardToken == nil {
+			forwardToken = aws.StringValue(resp.NextForwardToken)
+		}
+		if len(resp.Events) == 0 {
+			break
+		}
+
+		// events of a page are in chronological order
+		for i := len(resp.Events) - 1; i >= 0; i-- {
+			msg := aws.StringValue(resp.Events[i].Message)
+			if !budget.take(msg) {
+				truncated = true
+				break
+			}
+			reversed = append(reversed, msg)
+		}
+		backwardToken = resp.NextBackwardToken
+	}
+
+	logs := make([]string, 0, len(reversed)+1)
+	if truncated {
+		j.logger.Warnf("CloudWatch logs exceed the retained limits, only the most recent %d events are kept.", len(reversed))
+		logs = append(logs, fmt.Sprintf("... earlier log events truncated, only the most recent %d events are kept", len(reversed)))
+	}
+	for i := len(reversed) - 1; i >= 0; i-- {
+		logs = append(logs, reversed[i])
+	}
+	j.cloudWatchForwardToken = forwardToken
+	return logs, nil
+}
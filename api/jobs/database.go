@@ -1,6 +1,7 @@
 package jobs
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"time"
@@ -8,11 +9,20 @@ import (
 
 // Database interface abstracts database operations
 type Database interface {
-	addJob(jid, status, mode, host, processID, submitter string, updated time.Time) error
+	addJob(jid, status, mode, host, processID, submitter, submitterID string, updated time.Time) error
 	updateJobRecord(jid, status string, now time.Time) error
 	GetJob(jid string) (JobRecord, bool, error)
 	CheckJobExist(jid string) (bool, error)
-	GetJobs(limit, offset int, processIDs, statuses, submitters []string) ([]JobRecord, error)
+	// owner restricts jobs to jobs of a caller, nil lists jobs of all submitters
+	GetJobs(limit, offset int, processIDs, statuses, submitters []string, owner *Owner) ([]JobRecord, error)
+	GetAllJobs(limit, offset int) ([]JobRecord, error)
+	AddBatch(batchID string, jobIDs []string) error
+	GetBatchJobs(batchID string) ([]JobRecord, error)
+	getJobTimings(since time.Time) ([]jobTiming, error)
+	ArchiveJobs(before time.Time, keep int) (int, error)
+	SetRequestedOutputs(jid string, outputIDs []string) error
+	SetProcessSnapshot(jid string, snapshot ProcessSnapshot) error
+	CountSubmissions(owner Owner, since time.Time) (count int, oldest time.Time, err error)
 	Close() error
 }
 
@@ -31,6 +41,8 @@ func NewDatabase(dbType string) (db Database, err error) {
 			return nil, fmt.Errorf("env variable POSTGRES_CONN_STRING not set")
 		}
 		db, err = NewPostgresDB(connString)
+	case "memory":
+		db = NewMemoryDB()
 	default:
 		return nil, fmt.Errorf("unsupported database type: %s", dbType)
 	}
@@ -41,3 +53,58 @@ func NewDatabase(dbType string) (db Database, err error) {
 
 	return db, nil
 }
+
+// Requested outputs are stored as a JSON array, empty string means all outputs
+func encodeRequestedOutputs(outputIDs []string) (string, error) {
+	if len(outputIDs) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(outputIDs)
+	return string(b), err
+}
+
+func decodeRequestedOutputs(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var outputIDs []string
+	err := json.Unmarshal([]byte(s), &outputIDs)
+	return outputIDs, err
+}
+
+// Process snapshots are stored as a JSON object, empty string means no snapshot was taken
+func encodeProcessSnapshot(snapshot ProcessSnapshot) (string, error) {
+	b, err := json.Marshal(snapshot)
+	return string(b), err
+}
+
+func decodeProcessSnapshot(s string) (*ProcessSnapshot, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var snapshot ProcessSnapshot
+	if err := json.Unmarshal([]byte(s), &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// Mark job record as dismissed if job exists and is not already in a terminated status.
+// Returns true if the record was updated.
+func DismissJobRecord(db Database, jid string) (bool, error) {
+	jr, ok, err := db.GetJob(jid)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	switch jr.Status {
+	case SUCCESSFUL, DISMISSED, FAILED:
+		return false, nil
+	}
+
+	err = db.updateJobRecord(jid, DISMISSED, time.Now())
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
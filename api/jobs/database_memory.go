@@ -0,0 +1,281 @@
+package jobs
+
+import (
+	"app/utils"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryDB keeps job records in memory only, records are lost on restart.
+// Intended for testing and demos where no external database is available.
+type MemoryDB struct {
+	mu      sync.RWMutex
+	jobs    map[string]JobRecord
+	batches map[string][]string
+	created map[string]time.Time
+	archive map[string]JobRecord
+}
+
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{
+		jobs:    make(map[string]JobRecord),
+		batches: make(map[string][]string),
+		created: make(map[string]time.Time),
+		archive: make(map[string]JobRecord),
+	}
+}
+
+// Add job to the database. Will return error if job exist.
+func (memDB *MemoryDB) addJob(jid, status, mode, host, processID, submitter, submitterID string, updated time.Time) error {
+	memDB.mu.Lock()
+	defer memDB.mu.Unlock()
+
+	if _, ok := memDB.jobs[jid]; ok {
+		return fmt.Errorf("job %s already exists", jid)
+	}
+	if _, ok := memDB.archive[jid]; ok {
+		return fmt.Errorf("job %s already exists", jid)
+	}
+	memDB.jobs[jid] = JobRecord{
+		JobID:       jid,
+		Status:      status,
+		LastUpdate:  updated,
+		Mode:        mode,
+		Host:        host,
+		ProcessID:   processID,
+		Submitter:   submitter,
+		SubmitterID: submitterID,
+	}
+	memDB.created[jid] = updated
+	return nil
+}
+
+// Update status and time of a job.
+func (memDB *MemoryDB) updateJobRecord(jid, status string, now time.Time) error {
+	memDB.mu.Lock()
+	defer memDB.mu.Unlock()
+
+	if jr, ok := memDB.jobs[jid]; ok {
+		jr.Status = status
+		jr.LastUpdate = now
+		jr.ExpiresAt = resultsExpiry(status, now)
+		memDB.jobs[jid] = jr
+	}
+	return nil
+}
+
+// Get Job Record from database given a job id, archived jobs included.
+func (memDB *MemoryDB) GetJob(jid string) (JobRecord, bool, error) {
+	memDB.mu.RLock()
+	defer memDB.mu.RUnlock()
+
+	jr, ok := memDB.jobs[jid]
+	if !ok {
+		jr, ok = memDB.archive[jid]
+	}
+	return jr, ok, nil
+}
+
+// Check if a job exists in database, archived jobs included.
+func (memDB *MemoryDB) CheckJobExist(jid string) (bool, error) {
+	memDB.mu.RLock()
+	defer memDB.mu.RUnlock()
+
+	_, ok := memDB.jobs[jid]
+	if !ok {
+		_, ok = memDB.archive[jid]
+	}
+	return ok, nil
+}
+
+// Assumes query parameters are valid
+func (memDB *MemoryDB) GetJobs(limit, offset int, processIDs, statuses, submitters []string, owner *Owner) ([]JobRecord, error) {
+	memDB.mu.RLock()
+	defer memDB.mu.RUnlock()
+
+	res := []JobRecord{}
+	for _, jr := range memDB.jobs {
+		if len(processIDs) > 0 && !utils.StringInSlice(jr.ProcessID, processIDs) {
+			continue
+		}
+		if len(statuses) > 0 && !utils.StringInSlice(jr.Status, statuses) {
+			continue
+		}
+		if len(submitters) > 0 && !utils.StringInSlice(jr.Submitter, submitters) {
+			continue
+		}
+		if owner != nil && !owner.Owns(jr.Submitter, jr.SubmitterID) {
+			continue
+		}
+		res = append(res, jr)
+	}
+	sortByUpdatedDesc(res)
+
+	if offset >= len(res) {
+		return []JobRecord{}, nil
+	}
+	res = res[offset:]
+	if limit < len(res) {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+// Get records of all jobs ordered by ID, archived jobs included.
+func (memDB *MemoryDB) GetAllJobs(limit, offset int) ([]JobRecord, error) {
+	memDB.mu.RLock()
+	defer memDB.mu.RUnlock()
+
+	res := make([]JobRecord, 0, len(memDB.jobs)+len(memDB.archive))
+	for _, jr := range memDB.jobs {
+		res = append(res, jr)
+	}
+	for _, jr := range memDB.archive {
+		res = append(res, jr)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].JobID < res[j].JobID })
+
+	if offset >= len(res) {
+		return []JobRecord{}, nil
+	}
+	res = res[offset:]
+	if limit < len(res) {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+// Get timings of jobs updated after since.
+func (memDB *MemoryDB) getJobTimings(since time.Time) ([]jobTiming, error) {
+	memDB.mu.RLock()
+	defer memDB.mu.RUnlock()
+
+	res := []jobTiming{}
+	for jid, jr := range memDB.jobs {
+		if jr.LastUpdate.After(since) {
+			res = append(res, jobTiming{ProcessID: jr.ProcessID, Status: jr.Status, Created: memDB.created[jid], Updated: jr.LastUpdate})
+		}
+	}
+	return res, nil
+}
+
+// Set outputs requested for a job, results of the job only include these.
+func (memDB *MemoryDB) SetRequestedOutputs(jid string, outputIDs []string) error {
+	memDB.mu.Lock()
+	defer memDB.mu.Unlock()
+
+	if jr, ok := memDB.jobs[jid]; ok {
+		jr.RequestedOutputs = outputIDs
+		memDB.jobs[jid] = jr
+	}
+	return nil
+}
+
+// Set metadata of the process a job was submitted to.
+func (memDB *MemoryDB) SetProcessSnapshot(jid string, snapshot ProcessSnapshot) error {
+	memDB.mu.Lock()
+	defer memDB.mu.Unlock()
+
+	if jr, ok := memDB.jobs[jid]; ok {
+		jr.Process = &snapshot
+		memDB.jobs[jid] = jr
+	}
+	return nil
+}
+
+// Count jobs of owner created after since, archived jobs included, and creation time of the oldest of these.
+func (memDB *MemoryDB) CountSubmissions(owner Owner, since time.Time) (count int, oldest time.Time, err error) {
+	memDB.mu.RLock()
+	defer memDB.mu.RUnlock()
+
+	for jid, created := range memDB.created {
+		if !created.After(since) {
+			continue
+		}
+		jr, ok := memDB.jobs[jid]
+		if !ok {
+			jr = memDB.archive[jid]
+		}
+		if !owner.Owns(jr.Submitter, jr.SubmitterID) {
+			continue
+		}
+		count++
+		if oldest.IsZero() || created.Before(oldest) {
+			oldest = created
+		}
+	}
+	return count, oldest, nil
+}
+
+// Move terminated jobs updated before before, or not among the keep most recently updated jobs, to the archive.
+// A zero before or keep disables that condition. Returns number of archived jobs.
+func (memDB *MemoryDB) ArchiveJobs(before time.Time, keep int) (int, error) {
+	memDB.mu.Lock()
+	defer memDB.mu.Unlock()
+
+	if before.IsZero() && keep <= 0 {
+		return 0, nil
+	}
+
+	records := make([]JobRecord, 0, len(memDB.jobs))
+	for _, jr := range memDB.jobs {
+		records = append(records, jr)
+	}
+	sortByUpdatedDesc(records)
+
+	n := 0
+	for i, jr := range records {
+		switch jr.Status {
+		case SUCCESSFUL, FAILED, DISMISSED:
+		default:
+			continue
+		}
+		if (before.IsZero() || !jr.LastUpdate.Before(before)) && (keep <= 0 || i < keep) {
+			continue
+		}
+		jr.Archived = true
+		memDB.archive[jr.JobID] = jr
+		// creation time is kept for submission quotas
+		delete(memDB.jobs, jr.JobID)
+		n++
+	}
+	return n, nil
+}
+
+// Add jobs to a batch.
+func (memDB *MemoryDB) AddBatch(batchID string, jobIDs []string) error {
+	memDB.mu.Lock()
+	defer memDB.mu.Unlock()
+
+	memDB.batches[batchID] = append(memDB.batches[batchID], jobIDs...)
+	return nil
+}
+
+// Get records of all jobs in a batch. Returns empty slice if batch does not exist.
+func (memDB *MemoryDB) GetBatchJobs(batchID string) ([]JobRecord, error) {
+	memDB.mu.RLock()
+	defer memDB.mu.RUnlock()
+
+	res := []JobRecord{}
+	for _, jid := range memDB.batches[batchID] {
+		if jr, ok := memDB.jobs[jid]; ok {
+			res = append(res, jr)
+		} else if jr, ok := memDB.archive[jid]; ok {
+			res = append(res, jr)
+		}
+	}
+	sortByUpdatedDesc(res)
+	return res, nil
+}
+
+func (memDB *MemoryDB) Close() error {
+	return nil
+}
+
+func sortByUpdatedDesc(records []JobRecord) {
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].LastUpdate.After(records[j].LastUpdate)
+	})
+}
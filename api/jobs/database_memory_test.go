@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryDBCountSubmissions(t *testing.T) {
+	db := NewMemoryDB()
+	since := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	submissions := []struct {
+		jid, submitter, submitterID string
+		created                     time.Time
+	}{
+		{"before", "a@example.com", "a", since.Add(-time.Second)},
+		// the window excludes its start
+		{"at", "a@example.com", "a", since},
+		{"oldest", "a@example.com", "a", since.Add(time.Nanosecond)},
+		{"later", "a@example.com", "a", since.Add(time.Hour)},
+		// recorded before submitter IDs, matched by email
+		{"legacy", "a@example.com", "", since.Add(2 * time.Hour)},
+		// same email with another ID belongs to another user
+		{"other", "a@example.com", "b", since.Add(time.Hour)},
+		{"anonymous", "", "", since.Add(time.Hour)},
+	}
+	for _, s := range submissions {
+		if err := db.addJob(s.jid, ACCEPTED, "async-execute", "docker", "p", s.submitter, s.submitterID, s.created); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		owner      Owner
+		wantCount  int
+		wantOldest time.Time
+	}{
+		{"submitter ID", Owner{SubmitterID: "a", Submitter: "a@example.com"}, 3, since.Add(time.Nanosecond)},
+		{"email fallback", Owner{Submitter: "a@example.com"}, 1, since.Add(2 * time.Hour)},
+		{"other ID", Owner{SubmitterID: "b", Submitter: "a@example.com"}, 2, since.Add(time.Hour)},
+		{"no submissions", Owner{SubmitterID: "c", Submitter: "c@example.com"}, 0, time.Time{}},
+		{"without auth", Owner{}, 1, since.Add(time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, oldest, err := db.CountSubmissions(tt.owner, since)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != tt.wantCount || !oldest.Equal(tt.wantOldest) {
+				t.Errorf("CountSubmissions() = %d, %s, want %d, %s", count, oldest, tt.wantCount, tt.wantOldest)
+			}
+		})
+	}
+}
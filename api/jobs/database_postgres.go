@@ -44,9 +44,39 @@ func (postgresDB *PostgresDB) createTables() error {
         mode TEXT NOT NULL,
         host TEXT NOT NULL,
         process_id TEXT NOT NULL,
-        submitter TEXT NOT NULL DEFAULT ''
+        submitter TEXT NOT NULL DEFAULT '',
+        submitter_id TEXT NOT NULL DEFAULT ''
     );
 
+    ALTER TABLE jobs ADD COLUMN IF NOT EXISTS submitter_id TEXT NOT NULL DEFAULT '';
+    ALTER TABLE jobs ADD COLUMN IF NOT EXISTS created TIMESTAMP WITHOUT TIME ZONE;
+    ALTER TABLE jobs ADD COLUMN IF NOT EXISTS expires TIMESTAMP WITHOUT TIME ZONE;
+    ALTER TABLE jobs ADD COLUMN IF NOT EXISTS requested_outputs TEXT NOT NULL DEFAULT '';
+    ALTER TABLE jobs ADD COLUMN IF NOT EXISTS process_snapshot TEXT NOT NULL DEFAULT '';
+
+    CREATE TABLE IF NOT EXISTS batch_jobs (
+        batch_id TEXT NOT NULL,
+        job_id TEXT NOT NULL,
+        PRIMARY KEY (batch_id, job_id)
+    );
+
+    -- terminated jobs moved out of jobs to keep it small, only looked up by id so no other indices
+    CREATE TABLE IF NOT EXISTS jobs_archive (
+        id TEXT PRIMARY KEY,
+        status TEXT NOT NULL,
+        updated TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+        mode TEXT NOT NULL,
+        host TEXT NOT NULL,
+        process_id TEXT NOT NULL,
+        submitter TEXT NOT NULL DEFAULT '',
+        submitter_id TEXT NOT NULL DEFAULT '',
+        created TIMESTAMP WITHOUT TIME ZONE,
+        expires TIMESTAMP WITHOUT TIME ZONE
+    );
+
+    ALTER TABLE jobs_archive ADD COLUMN IF NOT EXISTS requested_outputs TEXT NOT NULL DEFAULT '';
+    ALTER TABLE jobs_archive ADD COLUMN IF NOT EXISTS process_snapshot TEXT NOT NULL DEFAULT '';
+
     CREATE INDEX IF NOT EXISTS idx_jobs_updated ON jobs(updated);
     CREATE INDEX IF NOT EXISTS idx_jobs_process_id ON jobs(process_id);
     CREATE INDEX IF NOT EXISTS idx_jobs_submitter ON jobs(submitter);
@@ -60,36 +90,72 @@ func (postgresDB *PostgresDB) createTables() error {
 }
 
 // AddJob adds a new job to the database
-func (db *PostgresDB) addJob(jid, status, mode, host, processID, submitter string, updated time.Time) error {
-	query := `INSERT INTO jobs (id, status, updated, mode, host, process_id, submitter) VALUES ($1, $2, $3, $4, $5, $6, $7)`
-	_, err := db.Handle.Exec(query, jid, status, updated, mode, host, processID, submitter)
+func (db *PostgresDB) addJob(jid, status, mode, host, processID, submitter, submitterID string, updated time.Time) error {
+	query := `INSERT INTO jobs (id, status, updated, mode, host, process_id, submitter, submitter_id, created) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $3)`
+	_, err := db.Handle.Exec(query, jid, status, updated, mode, host, processID, submitter, submitterID)
 	return err
 }
 
 // UpdateJobRecord updates a job record
 func (db *PostgresDB) updateJobRecord(jid, status string, now time.Time) error {
-	query := `UPDATE jobs SET status = $2, updated = $3 WHERE id = $1`
-	_, err := db.Handle.Exec(query, jid, status, now)
+	query := `UPDATE jobs SET status = $2, updated = $3, expires = $4 WHERE id = $1`
+	_, err := db.Handle.Exec(query, jid, status, now, resultsExpiry(status, now))
 	return err
 }
 
-// GetJob retrieves a job record by id
+// GetJob retrieves a job record by id, archived jobs included
 func (db *PostgresDB) GetJob(jid string) (JobRecord, bool, error) {
-	query := `SELECT * FROM jobs WHERE id = $1`
+	query := `SELECT id, status, updated, mode, host, process_id, submitter, submitter_id, expires, requested_outputs, process_snapshot, false FROM jobs WHERE id = $1
+    UNION ALL SELECT id, status, updated, mode, host, process_id, submitter, submitter_id, expires, requested_outputs, process_snapshot, true FROM jobs_archive WHERE id = $1`
 	var jr JobRecord
-	err := db.Handle.QueryRow(query, jid).Scan(&jr.JobID, &jr.Status, &jr.LastUpdate, &jr.Mode, &jr.Host, &jr.ProcessID, &jr.Submitter)
+	var expires sql.NullTime
+	var requestedOutputs, processSnapshot string
+	err := db.Handle.QueryRow(query, jid).Scan(&jr.JobID, &jr.Status, &jr.LastUpdate, &jr.Mode, &jr.Host, &jr.ProcessID, &jr.Submitter, &jr.SubmitterID, &expires, &requestedOutputs, &processSnapshot, &jr.Archived)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return JobRecord{}, false, nil
 		}
 		return JobRecord{}, false, err
 	}
+	if expires.Valid {
+		jr.ExpiresAt = &expires.Time
+	}
+	if jr.RequestedOutputs, err = decodeRequestedOutputs(requestedOutputs); err != nil {
+		return JobRecord{}, false, err
+	}
+	if jr.Process, err = decodeProcessSnapshot(processSnapshot); err != nil {
+		return JobRecord{}, false, err
+	}
 	return jr, true, nil
 }
 
-// CheckJobExist checks if a job exists in the database
+// getJobTimings retrieves timings of jobs updated after since
+func (db *PostgresDB) getJobTimings(since time.Time) ([]jobTiming, error) {
+	query := `SELECT process_id, status, created, updated FROM jobs WHERE updated > $1`
+	rows, err := db.Handle.Query(query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []jobTiming{}
+	for rows.Next() {
+		var t jobTiming
+		var created sql.NullTime
+		if err := rows.Scan(&t.ProcessID, &t.Status, &created, &t.Updated); err != nil {
+			return nil, err
+		}
+		if created.Valid {
+			t.Created = created.Time
+		}
+		res = append(res, t)
+	}
+	return res, rows.Err()
+}
+
+// CheckJobExist checks if a job exists in the database, archived jobs included
 func (db *PostgresDB) CheckJobExist(jid string) (bool, error) {
-	query := `SELECT 1 FROM jobs WHERE id = $1`
+	query := `SELECT 1 FROM jobs WHERE id = $1 UNION ALL SELECT 1 FROM jobs_archive WHERE id = $1`
 	var exists int
 	err := db.Handle.QueryRow(query, jid).Scan(&exists)
 	if err != nil {
@@ -102,8 +168,8 @@ func (db *PostgresDB) CheckJobExist(jid string) (bool, error) {
 }
 
 // Assumes query parameters are valid
-func (pgDB *PostgresDB) GetJobs(limit, offset int, processIDs, statuses, submitters []string) ([]JobRecord, error) {
-	baseQuery := `SELECT id, status, updated, process_id, submitter FROM jobs`
+func (pgDB *PostgresDB) GetJobs(limit, offset int, processIDs, statuses, submitters []string, owner *Owner) ([]JobRecord, error) {
+	baseQuery := `SELECT id, status, updated, process_id, submitter, submitter_id FROM jobs`
 	whereClauses := []string{}
 	args := []interface{}{}
 
@@ -145,6 +211,13 @@ func (pgDB *PostgresDB) GetJobs(limit, offset int, processIDs, statuses, submitt
 		}
 	}
 
+	// same rule as Owner.Owns
+	if owner != nil {
+		whereClauses = append(whereClauses, fmt.Sprintf("((submitter_id <> '' AND submitter_id = $%d) OR (submitter_id = '' AND submitter = $%d))", argIndex, argIndex+1))
+		argIndex += 2
+		args = append(args, owner.SubmitterID, owner.Submitter)
+	}
+
 	if len(whereClauses) > 0 {
 		baseQuery += " WHERE " + strings.Join(whereClauses, " AND ")
 	}
@@ -163,7 +236,150 @@ func (pgDB *PostgresDB) GetJobs(limit, offset int, processIDs, statuses, submitt
 
 	for rows.Next() {
 		var r JobRecord
-		if err := rows.Scan(&r.JobID, &r.Status, &r.LastUpdate, &r.ProcessID, &r.Submitter); err != nil {
+		if err := rows.Scan(&r.JobID, &r.Status, &r.LastUpdate, &r.ProcessID, &r.Submitter, &r.SubmitterID); err != nil {
+			return nil, err
+		}
+		res = append(res, r)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetAllJobs retrieves records of all jobs ordered by ID, archived jobs included
+func (db *PostgresDB) GetAllJobs(limit, offset int) ([]JobRecord, error) {
+	query := `SELECT id, status, updated, process_id, submitter, submitter_id FROM jobs
+    UNION ALL SELECT id, status, updated, process_id, submitter, submitter_id FROM jobs_archive
+    ORDER BY id LIMIT $1 OFFSET $2`
+
+	rows, err := db.Handle.Query(query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []JobRecord{}
+	for rows.Next() {
+		var r JobRecord
+		if err := rows.Scan(&r.JobID, &r.Status, &r.LastUpdate, &r.ProcessID, &r.Submitter, &r.SubmitterID); err != nil {
+			return nil, err
+		}
+		res = append(res, r)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// SetRequestedOutputs sets outputs requested for a job, results of the job only include these
+func (db *PostgresDB) SetRequestedOutputs(jid string, outputIDs []string) error {
+	requestedOutputs, err := encodeRequestedOutputs(outputIDs)
+	if err != nil {
+		return err
+	}
+	_, err = db.Handle.Exec(`UPDATE jobs SET requested_outputs = $2 WHERE id = $1`, jid, requestedOutputs)
+	return err
+}
+
+// SetProcessSnapshot sets metadata of the process a job was submitted to
+func (db *PostgresDB) SetProcessSnapshot(jid string, snapshot ProcessSnapshot) error {
+	processSnapshot, err := encodeProcessSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+	_, err = db.Handle.Exec(`UPDATE jobs SET process_snapshot = $2 WHERE id = $1`, jid, processSnapshot)
+	return err
+}
+
+// CountSubmissions counts jobs of owner created after since, archived jobs included,
+// and returns creation time of the oldest of these
+func (db *PostgresDB) CountSubmissions(owner Owner, since time.Time) (count int, oldest time.Time, err error) {
+	// same rule as Owner.Owns
+	query := `SELECT COUNT(*), MIN(created) FROM (
+        SELECT created FROM jobs WHERE ((submitter_id <> '' AND submitter_id = $1) OR (submitter_id = '' AND submitter = $2)) AND created > $3
+        UNION ALL SELECT created FROM jobs_archive WHERE ((submitter_id <> '' AND submitter_id = $1) OR (submitter_id = '' AND submitter = $2)) AND created > $3
+    ) AS submissions`
+	var oldestCreated sql.NullTime
+	if err = db.Handle.QueryRow(query, owner.SubmitterID, owner.Submitter, since).Scan(&count, &oldestCreated); err != nil {
+		return 0, time.Time{}, err
+	}
+	return count, oldestCreated.Time, nil
+}
+
+// ArchiveJobs moves terminated jobs updated before before, or not among the keep most recently updated jobs, to jobs_archive.
+// A zero before or keep disables that condition. Returns number of archived jobs.
+func (pgDB *PostgresDB) ArchiveJobs(before time.Time, keep int) (int, error) {
+	conditions := []string{}
+	args := []interface{}{SUCCESSFUL, FAILED, DISMISSED}
+	if !before.IsZero() {
+		args = append(args, before)
+		conditions = append(conditions, fmt.Sprintf("updated < $%d", len(args)))
+	}
+	if keep > 0 {
+		args = append(args, keep)
+		conditions = append(conditions, fmt.Sprintf("id NOT IN (SELECT id FROM jobs ORDER BY updated DESC LIMIT $%d)", len(args)))
+	}
+	if len(conditions) == 0 {
+		return 0, nil
+	}
+
+	// single statement so that jobs are never deleted without being archived
+	query := `WITH moved AS (
+        DELETE FROM jobs WHERE status IN ($1, $2, $3) AND (` + strings.Join(conditions, " OR ") + `)
+        RETURNING id, status, updated, mode, host, process_id, submitter, submitter_id, created, expires, requested_outputs, process_snapshot
+    )
+    INSERT INTO jobs_archive (id, status, updated, mode, host, process_id, submitter, submitter_id, created, expires, requested_outputs, process_snapshot)
+    SELECT * FROM moved ON CONFLICT (id) DO NOTHING`
+
+	res, err := pgDB.Handle.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// AddBatch adds jobs to a batch in a single transaction
+func (pgDB *PostgresDB) AddBatch(batchID string, jobIDs []string) error {
+	tx, err := pgDB.Handle.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, jid := range jobIDs {
+		_, err = tx.Exec(`INSERT INTO batch_jobs (batch_id, job_id) VALUES ($1, $2)`, batchID, jid)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetBatchJobs retrieves records of all jobs in a batch, archived jobs included
+func (pgDB *PostgresDB) GetBatchJobs(batchID string) ([]JobRecord, error) {
+	query := `SELECT j.id, j.status, j.updated, j.process_id, j.submitter, j.submitter_id, false AS archived FROM jobs j
+    INNER JOIN batch_jobs b ON j.id = b.job_id WHERE b.batch_id = $1
+    UNION ALL SELECT a.id, a.status, a.updated, a.process_id, a.submitter, a.submitter_id, true AS archived FROM jobs_archive a
+    INNER JOIN batch_jobs b ON a.id = b.job_id WHERE b.batch_id = $1 ORDER BY 3 DESC`
+
+	res := []JobRecord{}
+
+	rows, err := pgDB.Handle.Query(query, batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r JobRecord
+		if err := rows.Scan(&r.JobID, &r.Status, &r.LastUpdate, &r.ProcessID, &r.Submitter, &r.SubmitterID, &r.Archived); err != nil {
 			return nil, err
 		}
 		res = append(res, r)
@@ -69,26 +69,77 @@ func (sqliteDB *SQLiteDB) createTables() error {
 		mode TEXT NOT NULL,
 		host TEXT NOT NULL,
 		process_id TEXT NOT NULL,
-		submitter TEXT NOT NULL DEFAULT ''
+		submitter TEXT NOT NULL DEFAULT '',
+		submitter_id TEXT NOT NULL DEFAULT '',
+		created TIMESTAMP,
+		expires TIMESTAMP,
+		requested_outputs TEXT NOT NULL DEFAULT '',
+		process_snapshot TEXT NOT NULL DEFAULT ''
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_jobs_updated ON jobs(updated);
 	CREATE INDEX IF NOT EXISTS idx_jobs_process_id ON jobs(process_id);
 	CREATE INDEX IF NOT EXISTS idx_jobs_submitter ON jobs(submitter);
+
+	CREATE TABLE IF NOT EXISTS batch_jobs (
+		batch_id TEXT NOT NULL,
+		job_id TEXT NOT NULL,
+		PRIMARY KEY (batch_id, job_id)
+	);
+
+	-- terminated jobs moved out of jobs to keep it small, only looked up by id so no other indices
+	CREATE TABLE IF NOT EXISTS jobs_archive (
+		id TEXT PRIMARY KEY,
+		status TEXT NOT NULL,
+		updated TIMESTAMP NOT NULL,
+		mode TEXT NOT NULL,
+		host TEXT NOT NULL,
+		process_id TEXT NOT NULL,
+		submitter TEXT NOT NULL DEFAULT '',
+		submitter_id TEXT NOT NULL DEFAULT '',
+		created TIMESTAMP,
+		expires TIMESTAMP,
+		requested_outputs TEXT NOT NULL DEFAULT '',
+		process_snapshot TEXT NOT NULL DEFAULT ''
+	);
 	`
 
 	_, err := sqliteDB.Handle.Exec(queryJobs)
 	if err != nil {
 		return fmt.Errorf("error creating tables: %s", err)
 	}
+
+	// Migrate databases created before submitter_id column was added.
+	// SQLite does not support ADD COLUMN IF NOT EXISTS, so duplicate column error is ignored.
+	_, err = sqliteDB.Handle.Exec(`ALTER TABLE jobs ADD COLUMN submitter_id TEXT NOT NULL DEFAULT ''`)
+	if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+		return fmt.Errorf("error migrating tables: %s", err)
+	}
+	// Jobs added before created column was added have no creation time.
+	_, err = sqliteDB.Handle.Exec(`ALTER TABLE jobs ADD COLUMN created TIMESTAMP`)
+	if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+		return fmt.Errorf("error migrating tables: %s", err)
+	}
+	_, err = sqliteDB.Handle.Exec(`ALTER TABLE jobs ADD COLUMN expires TIMESTAMP`)
+	if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+		return fmt.Errorf("error migrating tables: %s", err)
+	}
+	for _, table := range []string{"jobs", "jobs_archive"} {
+		for _, column := range []string{"requested_outputs", "process_snapshot"} {
+			_, err = sqliteDB.Handle.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` TEXT NOT NULL DEFAULT ''`)
+			if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+				return fmt.Errorf("error migrating tables: %s", err)
+			}
+		}
+	}
 	return nil
 }
 
 // Add job to the database. Will return error if job exist.
-func (sqliteDB *SQLiteDB) addJob(jid, status, mode, host, processID, submitter string, updated time.Time) error {
-	query := `INSERT INTO jobs (id, status, updated, mode, host, process_id, submitter) VALUES (?, ?, ?, ?, ?, ?, ?)`
+func (sqliteDB *SQLiteDB) addJob(jid, status, mode, host, processID, submitter, submitterID string, updated time.Time) error {
+	query := `INSERT INTO jobs (id, status, updated, mode, host, process_id, submitter, submitter_id, created) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	_, err := sqliteDB.Handle.Exec(query, jid, status, updated, mode, host, processID, submitter)
+	_, err := sqliteDB.Handle.Exec(query, jid, status, updated, mode, host, processID, submitter, submitterID, updated)
 	if err != nil {
 		return err
 	}
@@ -97,24 +148,27 @@ func (sqliteDB *SQLiteDB) addJob(jid, status, mode, host, processID, submitter s
 
 // Update status and time of a job.
 func (sqliteDB *SQLiteDB) updateJobRecord(jid, status string, now time.Time) error {
-	query := `UPDATE jobs SET status = ?, updated = ? WHERE id = ?`
-	_, err := sqliteDB.Handle.Exec(query, status, now, jid)
+	query := `UPDATE jobs SET status = ?, updated = ?, expires = ? WHERE id = ?`
+	_, err := sqliteDB.Handle.Exec(query, status, now, resultsExpiry(status, now), jid)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-// Get Job Record from database given a job id.
+// Get Job Record from database given a job id, archived jobs included.
 // If job do not exists, or error encountered bool would be false.
 // Similar behavior as key exist in hashmap.
 func (sqliteDB *SQLiteDB) GetJob(jid string) (JobRecord, bool, error) {
-	query := `SELECT * FROM jobs WHERE id = ?`
+	query := `SELECT id, status, updated, mode, host, process_id, submitter, submitter_id, expires, requested_outputs, process_snapshot, 0 FROM jobs WHERE id = ?
+	UNION ALL SELECT id, status, updated, mode, host, process_id, submitter, submitter_id, expires, requested_outputs, process_snapshot, 1 FROM jobs_archive WHERE id = ?`
 
 	jr := JobRecord{}
+	var expires sql.NullTime
+	var requestedOutputs, processSnapshot string
 
-	row := sqliteDB.Handle.QueryRow(query, jid)
-	err := row.Scan(&jr.JobID, &jr.Status, &jr.LastUpdate, &jr.Mode, &jr.Host, &jr.ProcessID, &jr.Submitter)
+	row := sqliteDB.Handle.QueryRow(query, jid, jid)
+	err := row.Scan(&jr.JobID, &jr.Status, &jr.LastUpdate, &jr.Mode, &jr.Host, &jr.ProcessID, &jr.Submitter, &jr.SubmitterID, &expires, &requestedOutputs, &processSnapshot, &jr.Archived)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return JobRecord{}, false, nil
@@ -123,16 +177,25 @@ func (sqliteDB *SQLiteDB) GetJob(jid string) (JobRecord, bool, error) {
 			return JobRecord{}, false, err
 		}
 	}
+	if expires.Valid {
+		jr.ExpiresAt = &expires.Time
+	}
+	if jr.RequestedOutputs, err = decodeRequestedOutputs(requestedOutputs); err != nil {
+		return JobRecord{}, false, err
+	}
+	if jr.Process, err = decodeProcessSnapshot(processSnapshot); err != nil {
+		return JobRecord{}, false, err
+	}
 	return jr, true, nil
 }
 
-// Check if a job exists in database.
+// Check if a job exists in database, archived jobs included.
 func (sqliteDB *SQLiteDB) CheckJobExist(jid string) (bool, error) {
-	query := `SELECT id FROM jobs WHERE id = ?`
+	query := `SELECT id FROM jobs WHERE id = ? UNION ALL SELECT id FROM jobs_archive WHERE id = ?`
 
 	js := JobRecord{}
 
-	row := sqliteDB.Handle.QueryRow(query, jid)
+	row := sqliteDB.Handle.QueryRow(query, jid, jid)
 	err := row.Scan(&js.JobID)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -145,8 +208,8 @@ func (sqliteDB *SQLiteDB) CheckJobExist(jid string) (bool, error) {
 }
 
 // Assumes query parameters are valid
-func (sqliteDB *SQLiteDB) GetJobs(limit, offset int, processIDs, statuses []string, submitters []string) ([]JobRecord, error) {
-	baseQuery := `SELECT id, status, updated, process_id, submitter FROM jobs`
+func (sqliteDB *SQLiteDB) GetJobs(limit, offset int, processIDs, statuses []string, submitters []string, owner *Owner) ([]JobRecord, error) {
+	baseQuery := `SELECT id, status, updated, process_id, submitter, submitter_id FROM jobs`
 	whereClauses := []string{}
 	args := []interface{}{}
 
@@ -174,6 +237,12 @@ func (sqliteDB *SQLiteDB) GetJobs(limit, offset int, processIDs, statuses []stri
 		}
 	}
 
+	// same rule as Owner.Owns
+	if owner != nil {
+		whereClauses = append(whereClauses, "((submitter_id <> '' AND submitter_id = ?) OR (submitter_id = '' AND submitter = ?))")
+		args = append(args, owner.SubmitterID, owner.Submitter)
+	}
+
 	if len(whereClauses) > 0 {
 		baseQuery += " WHERE " + strings.Join(whereClauses, " AND ")
 	}
@@ -191,7 +260,193 @@ func (sqliteDB *SQLiteDB) GetJobs(limit, offset int, processIDs, statuses []stri
 
 	for rows.Next() {
 		var r JobRecord
-		if err := rows.Scan(&r.JobID, &r.Status, &r.LastUpdate, &r.ProcessID, &r.Submitter); err != nil {
+		if err := rows.Scan(&r.JobID, &r.Status, &r.LastUpdate, &r.ProcessID, &r.Submitter, &r.SubmitterID); err != nil {
+			return nil, err
+		}
+		res = append(res, r)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetAllJobs retrieves records of all jobs ordered by ID, archived jobs included
+func (sqliteDB *SQLiteDB) GetAllJobs(limit, offset int) ([]JobRecord, error) {
+	query := `SELECT id, status, updated, process_id, submitter, submitter_id FROM jobs
+    UNION ALL SELECT id, status, updated, process_id, submitter, submitter_id FROM jobs_archive
+    ORDER BY id LIMIT ? OFFSET ?`
+
+	rows, err := sqliteDB.Handle.Query(query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []JobRecord{}
+	for rows.Next() {
+		var r JobRecord
+		if err := rows.Scan(&r.JobID, &r.Status, &r.LastUpdate, &r.ProcessID, &r.Submitter, &r.SubmitterID); err != nil {
+			return nil, err
+		}
+		res = append(res, r)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Get timings of jobs updated after since.
+func (sqliteDB *SQLiteDB) getJobTimings(since time.Time) ([]jobTiming, error) {
+	query := `SELECT process_id, status, created, updated FROM jobs WHERE updated > ?`
+
+	rows, err := sqliteDB.Handle.Query(query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []jobTiming{}
+	for rows.Next() {
+		var t jobTiming
+		var created sql.NullTime
+		if err := rows.Scan(&t.ProcessID, &t.Status, &created, &t.Updated); err != nil {
+			return nil, err
+		}
+		if created.Valid {
+			t.Created = created.Time
+		}
+		res = append(res, t)
+	}
+	return res, rows.Err()
+}
+
+// Set outputs requested for a job, results of the job only include these.
+func (sqliteDB *SQLiteDB) SetRequestedOutputs(jid string, outputIDs []string) error {
+	requestedOutputs, err := encodeRequestedOutputs(outputIDs)
+	if err != nil {
+		return err
+	}
+	_, err = sqliteDB.Handle.Exec(`UPDATE jobs SET requested_outputs = ? WHERE id = ?`, requestedOutputs, jid)
+	return err
+}
+
+// Set metadata of the process a job was submitted to.
+func (sqliteDB *SQLiteDB) SetProcessSnapshot(jid string, snapshot ProcessSnapshot) error {
+	processSnapshot, err := encodeProcessSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+	_, err = sqliteDB.Handle.Exec(`UPDATE jobs SET process_snapshot = ? WHERE id = ?`, processSnapshot, jid)
+	return err
+}
+
+// Count jobs of owner created after since, archived jobs included, and creation time of the oldest of these.
+func (sqliteDB *SQLiteDB) CountSubmissions(owner Owner, since time.Time) (count int, oldest time.Time, err error) {
+	// same rule as Owner.Owns
+	query := `SELECT created FROM jobs WHERE ((submitter_id <> '' AND submitter_id = ?) OR (submitter_id = '' AND submitter = ?)) AND created > ?
+	UNION ALL SELECT created FROM jobs_archive WHERE ((submitter_id <> '' AND submitter_id = ?) OR (submitter_id = '' AND submitter = ?)) AND created > ?
+	ORDER BY created`
+
+	rows, err := sqliteDB.Handle.Query(query, owner.SubmitterID, owner.Submitter, since, owner.SubmitterID, owner.Submitter, since)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var created time.Time
+		if err := rows.Scan(&created); err != nil {
+			return 0, time.Time{}, err
+		}
+		if count == 0 {
+			oldest = created
+		}
+		count++
+	}
+	return count, oldest, rows.Err()
+}
+
+// Move terminated jobs updated before before, or not among the keep most recently updated jobs, to jobs_archive.
+// A zero before or keep disables that condition. Returns number of archived jobs.
+func (sqliteDB *SQLiteDB) ArchiveJobs(before time.Time, keep int) (int, error) {
+	conditions := []string{}
+	args := []interface{}{SUCCESSFUL, FAILED, DISMISSED}
+	if !before.IsZero() {
+		conditions = append(conditions, "updated < ?")
+		args = append(args, before)
+	}
+	if keep > 0 {
+		conditions = append(conditions, "id NOT IN (SELECT id FROM jobs ORDER BY updated DESC LIMIT ?)")
+		args = append(args, keep)
+	}
+	if len(conditions) == 0 {
+		return 0, nil
+	}
+	where := "status IN (?, ?, ?) AND (" + strings.Join(conditions, " OR ") + ")"
+
+	tx, err := sqliteDB.Handle.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT OR IGNORE INTO jobs_archive (id, status, updated, mode, host, process_id, submitter, submitter_id, created, expires, requested_outputs, process_snapshot)
+	SELECT id, status, updated, mode, host, process_id, submitter, submitter_id, created, expires, requested_outputs, process_snapshot FROM jobs WHERE `+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec(`DELETE FROM jobs WHERE id IN (SELECT id FROM jobs_archive) AND `+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), tx.Commit()
+}
+
+// Add jobs to a batch, all inserts are done in a single transaction.
+func (sqliteDB *SQLiteDB) AddBatch(batchID string, jobIDs []string) error {
+	tx, err := sqliteDB.Handle.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, jid := range jobIDs {
+		_, err = tx.Exec(`INSERT INTO batch_jobs (batch_id, job_id) VALUES (?, ?)`, batchID, jid)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Get records of all jobs in a batch, archived jobs included. Returns empty slice if batch does not exist.
+func (sqliteDB *SQLiteDB) GetBatchJobs(batchID string) ([]JobRecord, error) {
+	query := `SELECT j.id, j.status, j.updated, j.process_id, j.submitter, j.submitter_id, 0 AS archived FROM jobs j
+	INNER JOIN batch_jobs b ON j.id = b.job_id WHERE b.batch_id = ?
+	UNION ALL SELECT a.id, a.status, a.updated, a.process_id, a.submitter, a.submitter_id, 1 AS archived FROM jobs_archive a
+	INNER JOIN batch_jobs b ON a.id = b.job_id WHERE b.batch_id = ? ORDER BY 3 DESC`
+
+	res := []JobRecord{}
+
+	rows, err := sqliteDB.Handle.Query(query, batchID, batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r JobRecord
+		if err := rows.Scan(&r.JobID, &r.Status, &r.LastUpdate, &r.ProcessID, &r.Submitter, &r.SubmitterID, &r.Archived); err != nil {
 			return nil, err
 		}
 		res = append(res, r)
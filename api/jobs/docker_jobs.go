@@ -2,8 +2,6 @@ package jobs
 
 import (
 	"app/controllers"
-	"app/utils"
-	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -29,13 +27,41 @@ type DockerJob struct {
 	ProcessName    string `json:"processID"`
 	ProcessVersion string `json:"processVersion"`
 	Submitter      string
+	SubmitterID    string
 	EnvVars        []string
 	Cmd            []string `json:"commandOverride"`
-	UpdateTime     time.Time
-	Status         string `json:"status"`
+	jobStatus
+	// Grace period between SIGTERM and SIGKILL when the job is dismissed
+	StopTimeout time.Duration
+	// Env variables provided at submission time, these take precedence over EnvVars
+	EnvOverrides map[string]string
+	// Storage location results are written to, passed to the process as OUTPUT_LOCATION
+	OutputLocation string
+	// X-Request-ID of the execution request that created the job
+	RequestID string
+	// Custom fields of the execution request, written to the metadata document
+	Metadata map[string]interface{}
+	// Results are checked against output schemas of the process before the job is marked successful, nil skips the check
+	ResultsCheck *ResultsCheck
+	// User the container runs as, in the form UID[:GID], empty means the user declared by the image
+	User string
+	// Job is failed if the container does not write any logs for this long, 0 means no limit
+	InactivityTimeout time.Duration
+	// Container is not removed if the job fails, so that it can be inspected
+	KeepFailedContainer bool
+	// Directory of staged file inputs, removed when the job closes
+	InputsDir string
+	// Href inputs downloaded to InputsDir when the job starts
+	Downloads []InputDownload
+	// Order in which jobs held by the paused scheduler are started, nil is the lowest priority
+	Priority *int
+	// Path of InputsDir on the docker host, bind mounted read-only into the container at InputsDir
+	InputsHostDir string
 
 	logger  *log.Logger
 	logFile *os.File
+	// Resources consumed by the container, nil if not collected
+	resourceUsage *resourceUsage
 
 	Resources
 	DB         Database
@@ -63,6 +89,10 @@ func (j *DockerJob) SUBMITTER() string {
 	return j.Submitter
 }
 
+func (j *DockerJob) SUBMITTERID() string {
+	return j.SubmitterID
+}
+
 func (j *DockerJob) CMD() []string {
 	return j.Cmd
 }
@@ -74,7 +104,7 @@ func (j *DockerJob) IMAGE() string {
 // Update container logs
 func (j *DockerJob) UpdateProcessLogs() (err error) {
 	// If old status is one of the terminated status, close has already been called and container logs fetched, container killed
-	switch j.Status {
+	switch j.CurrentStatus() {
 	case SUCCESSFUL, DISMISSED, FAILED:
 		return
 	}
@@ -86,28 +116,12 @@ func (j *DockerJob) UpdateProcessLogs() (err error) {
 		return
 	}
 
-	if len(containerLogs) == 0 || containerLogs == nil {
+	if len(containerLogs.Combined) == 0 {
 		return
 	}
 
-	// Create a new file or overwrite if it exists
-	file, err := os.Create(fmt.Sprintf("%s/%s.process.jsonl", os.Getenv("TMP_JOB_LOGS_DIR"), j.UUID))
-	if err != nil {
-		return
-	}
-	defer file.Close()
-
-	writer := bufio.NewWriter(file)
-	defer writer.Flush()
-
-	for i, line := range containerLogs {
-		if i != len(containerLogs)-1 {
-			_, err = writer.WriteString(line + "\n")
-		} else {
-			_, err = writer.WriteString(line)
-		}
-	}
-
+	// Create new files or overwrite if they exist
+	err = j.writeContainerLogs(containerLogs)
 	return
 }
 
@@ -132,30 +146,8 @@ func (j *DockerJob) LogMessage(m string, level log.Level) {
 	}
 }
 
-func (j *DockerJob) LastUpdate() time.Time {
-	return j.UpdateTime
-}
-
-func (j *DockerJob) NewStatusUpdate(status string, updateTime time.Time) {
-
-	// If old status is one of the terminated status, it should not update status.
-	switch j.Status {
-	case SUCCESSFUL, DISMISSED, FAILED:
-		return
-	}
-
-	j.Status = status
-	if updateTime.IsZero() {
-		j.UpdateTime = time.Now()
-	} else {
-		j.UpdateTime = updateTime
-	}
-	j.DB.updateJobRecord(j.UUID, status, j.UpdateTime)
-	j.logger.Infof("Status changed to %s.", status)
-}
-
-func (j *DockerJob) CurrentStatus() string {
-	return j.Status
+func (j *DockerJob) NewStatusUpdate(status string, updateTime time.Time) bool {
+	return j.jobStatus.update(j, j.DB, j.ResultsCheck, j.logger, status, updateTime)
 }
 
 func (j *DockerJob) ProviderID() string {
@@ -205,6 +197,9 @@ func (j *DockerJob) Create() error {
 	if err != nil {
 		return err
 	}
+	if j.RequestID != "" {
+		j.logger.Infof("Request ID: %s", j.RequestID)
+	}
 	j.logger.Info("Container Commands: ", j.CMD())
 
 	ctx, cancelFunc := context.WithCancel(context.TODO())
@@ -212,7 +207,7 @@ func (j *DockerJob) Create() error {
 	j.ctxCancel = cancelFunc
 
 	// At this point job is ready to be added to database
-	err = j.DB.addJob(j.UUID, "accepted", "", "local", j.ProcessName, j.Submitter, time.Now())
+	err = j.DB.addJob(j.UUID, "accepted", "", "local", j.ProcessName, j.Submitter, j.SubmitterID, time.Now())
 	if err != nil {
 		j.ctxCancel()
 		return err
@@ -246,6 +241,22 @@ func (j *DockerJob) Run() {
 		}
 	}()
 
+	if !waitForScheduler(j.ctx, j.logger, j.Priority) {
+		return
+	}
+	if !j.jobStatus.markStarted() {
+		j.logger.Info("Job was dismissed while queued, it is not started.")
+		// Kill closes the job, wait for it so that it is not closed twice
+		<-j.ctx.Done()
+		return
+	}
+
+	if err := downloadInputs(j.ctx, j.Downloads, j.logger); err != nil {
+		j.logger.Errorf("Could not stage inputs. Error: %s", err.Error())
+		j.NewStatusUpdate(FAILED, time.Time{})
+		return
+	}
+
 	c, err := controllers.NewDockerController()
 	if err != nil {
 		j.logger.Errorf("Failed creating NewDockerController. Error: %s", err.Error())
@@ -258,6 +269,9 @@ func (j *DockerJob) Run() {
 	for _, eVar := range j.EnvVars {
 		envVars[eVar] = os.Getenv(eVar)
 	}
+	for k, v := range j.EnvOverrides {
+		envVars[k] = v
+	}
 
 	j.logger.Infof("Registered %v env vars", len(envVars))
 	resources := controllers.DockerResources{}
@@ -272,7 +286,12 @@ func (j *DockerJob) Run() {
 	}
 
 	// start container
-	containerID, err := c.ContainerRun(j.ctx, j.Image, j.Cmd, []controllers.VolumeMount{}, envVars, resources)
+	labels := map[string]string{controllers.JobIDLabel: j.UUID}
+	volumes := []controllers.VolumeMount{}
+	if _, err := os.Stat(j.InputsDir); j.InputsDir != "" && err == nil {
+		volumes = append(volumes, controllers.VolumeMount{HostPath: j.InputsDir, BindSource: j.InputsHostDir, ReadOnly: true})
+	}
+	containerID, err := c.ContainerRun(j.ctx, j.Image, j.Cmd, volumes, envVars, resources, j.User, labels)
 	if err != nil {
 		j.logger.Errorf("Failed to run container. Error: %s", err.Error())
 		j.NewStatusUpdate(FAILED, time.Time{})
@@ -286,6 +305,12 @@ func (j *DockerJob) Run() {
 		return
 	}
 
+	if j.InactivityTimeout > 0 {
+		go j.monitorInactivity(c)
+	}
+	usage := make(chan *resourceUsage, 1)
+	go j.monitorUsage(c, usage)
+
 	// wait for process to finish
 	exitCode, err := c.ContainerWait(j.ctx, j.ContainerID)
 	if err != nil {
@@ -302,20 +327,34 @@ func (j *DockerJob) Run() {
 	}
 
 	j.logger.Info("Container process finished successfully.")
-	j.NewStatusUpdate(SUCCESSFUL, time.Time{})
-	go j.WriteMetaData()
+	if !j.NewStatusUpdate(SUCCESSFUL, time.Time{}) {
+		// job was dismissed or failed while it finished
+		return
+	}
+	select {
+	case j.resourceUsage = <-usage:
+	case <-time.After(5 * time.Second):
+		j.logger.Debug("Resource usage not available in time, metadata is written without it.")
+	}
+	// metadata must be written before Close removes the container, job times are read from the container.
+	// Sync responses are returned after Run, so these wait for the resource usage, for a free storage worker
+	// when STORAGE_WORKERS are busy, and for the metadata upload.
+	j.WriteMetaData()
 }
 
 // kill local container
 func (j *DockerJob) Kill() error {
 	j.logger.Info("Received dismiss signal.")
-	switch j.CurrentStatus() {
-	case SUCCESSFUL, FAILED, DISMISSED:
-		// if these jobs have been loaded from previous snapshot they would not have context etc
+	if j.jobStatus.dismissQueued(j, j.DB, j.logger) {
+		// nothing was started, closing only removes the job from the queue and active jobs
+		j.logger.Info("Job dismissed before it started, there is nothing to stop.")
+		go j.Close()
+		return nil
+	}
+	// fails if the job is already completed, failed, or dismissed, or was terminated concurrently
+	if !j.NewStatusUpdate(DISMISSED, time.Time{}) {
 		return fmt.Errorf("can't call delete on an already completed, failed, or dismissed job")
 	}
-
-	j.NewStatusUpdate(DISMISSED, time.Time{})
 	// If a dismiss status is updated the job is considered dismissed at this point
 	// Close being graceful or not does not matter.
 
@@ -331,10 +370,13 @@ func (j *DockerJob) WriteMetaData() {
 	j.wg.Add(1)
 	defer j.wg.Done()
 	defer j.logger.Info("Finished metadata writing routine.")
+	release := acquireStorageWorker()
+	defer release()
 
 	c, err := controllers.NewDockerController()
 	if err != nil {
 		j.logger.Errorf("Could not create controller. Error: %s", err.Error())
+		return
 	}
 
 	p := process{j.ProcessID(), j.ProcessVersionID()}
@@ -358,9 +400,14 @@ func (j *DockerJob) WriteMetaData() {
 		Process:         p,
 		Image:           i,
 		Commands:        j.Cmd,
+		OutputLocation:  j.OutputLocation,
+		RequestID:       j.RequestID,
+		Custom:          j.Metadata,
 		GeneratedAtTime: g,
 		StartedAtTime:   s,
 		EndedAtTime:     e,
+		ResourceUsage:   j.resourceUsage,
+		Results:         metaDataResultsIntegrity(j, j.StorageSvc),
 	}
 
 	jsonBytes, err := json.Marshal(md)
@@ -371,9 +418,9 @@ func (j *DockerJob) WriteMetaData() {
 
 	metadataDir := os.Getenv("STORAGE_METADATA_PREFIX")
 	mdLocation := fmt.Sprintf("%s/%s.json", metadataDir, j.UUID)
-	err = utils.WriteToS3(j.StorageSvc, jsonBytes, mdLocation, "application/json", 0)
+	err = writeToStorage(j.StorageSvc, j.UUID, jsonBytes, mdLocation, "application/json")
 	if err != nil {
-		return
+		j.logger.Errorf("Error writing metadata, write will be retried: %s", err.Error())
 	}
 }
 
@@ -391,18 +438,84 @@ func (j *DockerJob) WriteMetaData() {
 // 	return
 // }
 
-func (j *DockerJob) fetchContainerLogs() ([]string, error) {
+func (j *DockerJob) fetchContainerLogs() (controllers.ContainerLogs, error) {
 	c, err := controllers.NewDockerController()
 	if err != nil {
-		return nil, fmt.Errorf("could not create controller to fetch container logs")
+		return controllers.ContainerLogs{}, fmt.Errorf("could not create controller to fetch container logs")
 	}
 	containerLogs, err := c.ContainerLog(context.TODO(), j.ContainerID)
 	if err != nil {
-		return nil, fmt.Errorf("could not fetch container logs")
+		return controllers.ContainerLogs{}, fmt.Errorf("could not fetch container logs")
 	}
 	return containerLogs, nil
 }
 
+// Write combined container logs to the process logs file, and stdout and stderr to their own files
+func (j *DockerJob) writeContainerLogs(containerLogs controllers.ContainerLogs) error {
+	err := writeLocalLogs(j.UUID, "process", containerLogs.Combined)
+	if err != nil {
+		return err
+	}
+	err = writeLocalLogs(j.UUID, "stdout", containerLogs.Stdout)
+	if err != nil {
+		return err
+	}
+	return writeLocalLogs(j.UUID, "stderr", containerLogs.Stderr)
+}
+
+// Fail the job if its container does not write any logs for InactivityTimeout, so that hung processes are caught
+// before maxRunningTime. Returns when the job is closed.
+func (j *DockerJob) monitorInactivity(c *controllers.DockerController) {
+	interval := j.InactivityTimeout / 10
+	if interval < time.Second {
+		interval = time.Second
+	} else if interval > 30*time.Second {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastOutput := time.Now()
+	for {
+		select {
+		case <-j.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		t, err := c.ContainerLastLogTime(j.ctx, j.ContainerID)
+		if err != nil {
+			j.logger.Debugf("Could not check container output. Error: %s", err.Error())
+		} else if t.After(lastOutput) {
+			lastOutput = t
+		}
+
+		if time.Since(lastOutput) > j.InactivityTimeout {
+			FailJob(j, fmt.Sprintf("Container has not written any logs for longer than inactivityTimeout of %s, job considered hung.", j.InactivityTimeout))
+			return
+		}
+	}
+}
+
+// Follow resource usage of the container until it stops, usage is sent on done, nil if it could not be collected.
+// Collection is best effort and never affects the job.
+func (j *DockerJob) monitorUsage(c *controllers.DockerController, done chan<- *resourceUsage) {
+	u, err := c.ContainerUsage(j.ctx, j.ContainerID)
+	if err != nil {
+		j.logger.Debugf("Could not collect resource usage. Error: %s", err.Error())
+		done <- nil
+		return
+	}
+	if u.PeakMemory == 0 && u.CPUTime == 0 {
+		done <- nil
+		return
+	}
+	done <- &resourceUsage{
+		PeakMemory: float64(u.PeakMemory) / (1024 * 1024),
+		CPUTime:    float64(u.CPUTime) / 1e9,
+	}
+}
+
 func (j *DockerJob) RunFinished() {
 	// do nothing because for local docker jobs decrementing wgRun is handeled by Run Fucntion
 	// This prevents wgDone being called twice and causing panics
@@ -420,39 +533,41 @@ func (j *DockerJob) Close() {
 		if err != nil {
 			j.logger.Errorf("Could not create controller. Error: %s", err.Error())
 		} else {
+			keep := j.KeepFailedContainer && j.CurrentStatus() == FAILED
+			// give the process a chance to clean up and flush output before it is removed. Jobs can also fail while
+			// the container is still running, such as on timeout, and the failed containers cleanup only removes
+			// exited containers, so kept containers are stopped too.
+			if j.CurrentStatus() == DISMISSED || keep {
+				err = c.ContainerStop(context.TODO(), j.ContainerID, int(j.StopTimeout.Seconds()))
+				if err != nil {
+					j.logger.Errorf("Could not stop container. Error: %s", err.Error())
+					if keep {
+						j.logger.Warnf("Failed container %s could not be stopped, removing it instead of keeping it.", j.ContainerID)
+						keep = false
+					}
+				}
+			}
+
 			containerLogs, err := c.ContainerLog(context.TODO(), j.ContainerID)
 			if err != nil {
 				j.logger.Errorf("Could not fetch container logs. Error: %s", err.Error())
 			}
 
-			file, err := os.Create(fmt.Sprintf("%s/%s.process.jsonl", os.Getenv("TMP_JOB_LOGS_DIR"), j.UUID))
-			if err != nil {
-				j.logger.Errorf("Could not create process logs file. Error: %s", err.Error())
-				return
+			if err = j.writeContainerLogs(containerLogs); err != nil {
+				j.logger.Errorf("Could not write process logs. Error: %s", err.Error())
 			}
 
-			writer := bufio.NewWriter(file)
-
-			for i, line := range containerLogs {
-				if i != len(containerLogs)-1 {
-					_, err = writer.WriteString(line + "\n")
-				} else {
-					_, err = writer.WriteString(line)
-				}
+			if keep {
+				j.logger.Infof("Container %s of the failed job is kept for inspection, it is removed by the failed containers cleanup.", j.ContainerID)
+			} else {
+				err = c.ContainerRemove(context.TODO(), j.ContainerID)
 				if err != nil {
-					j.logger.Errorf("Could not write log %s to file.", line)
+					j.logger.Errorf("Could not remove container. Error: %s", err.Error())
 				}
 			}
-
-			writer.Flush()
-			file.Close()
-
-			err = c.ContainerRemove(context.TODO(), j.ContainerID)
-			if err != nil {
-				j.logger.Errorf("Could not remove container. Error: %s", err.Error())
-			}
 		}
 	}
+	removeStagedInputs(j.InputsDir, j.logger)
 	j.DoneChan <- j // At this point job can be safely removed from active jobs
 
 	go func() {
@@ -0,0 +1,177 @@
+package jobs
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// InputDownload is an href input downloaded by the job when it starts, before the process is run
+type InputDownload struct {
+	InputID string
+	Href    string
+	// Content-Type the response must have, empty accepts any
+	MediaType string
+	// Expected digest in the form <md5|sha256>:<hex digest>, empty skips verification
+	Checksum string
+	// File the content is written to, the process receives this path as the input value
+	Path string
+}
+
+// Hosts href inputs can be downloaded from, entries starting with `*.` match all subdomains
+var hrefAllowedHosts []string
+
+// SetHrefAllowedHosts sets the hosts href inputs can be downloaded from, no href input is accepted if empty.
+// Must be called at startup before any job is created.
+func SetHrefAllowedHosts(hosts []string) {
+	hrefAllowedHosts = hosts
+}
+
+// Only https urls of allowed hosts can be downloaded, so that clients can not make the server call
+// internal services such as instance metadata endpoints.
+func checkHref(href string) error {
+	u, err := url.Parse(href)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return fmt.Errorf("href must be an https url")
+	}
+	if u.User != nil {
+		return fmt.Errorf("href must not contain credentials")
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range hrefAllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || (strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:])) {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %s is not allowed for href inputs", u.Hostname())
+}
+
+// Hash matching the algorithm of a checksum and the expected hex digest
+func parseChecksum(checksum string) (hash.Hash, string, error) {
+	algo, sum, found := strings.Cut(checksum, ":")
+	if !found || sum == "" {
+		return nil, "", fmt.Errorf("checksum must be in the form '<md5|sha256>:<hex digest>'")
+	}
+	switch strings.ToLower(algo) {
+	case "md5":
+		return md5.New(), strings.ToLower(sum), nil
+	case "sha256":
+		return sha256.New(), strings.ToLower(sum), nil
+	default:
+		return nil, "", fmt.Errorf("unsupported checksum algorithm %s, must be one of [md5, sha256]", algo)
+	}
+}
+
+// Validate checks that the href can be downloaded and the checksum is well formed,
+// so that invalid inputs are rejected at submission instead of failing the job.
+func (d InputDownload) Validate() error {
+	if err := checkHref(d.Href); err != nil {
+		return fmt.Errorf("input %s: %s", d.InputID, err.Error())
+	}
+	if d.Checksum != "" {
+		if _, _, err := parseChecksum(d.Checksum); err != nil {
+			return fmt.Errorf("input %s: %s", d.InputID, err.Error())
+		}
+	}
+	return nil
+}
+
+// Redirects are followed only to allowed hosts
+var inputDownloadClient = &http.Client{
+	Timeout: 10 * time.Minute,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return checkHref(req.URL.String())
+	},
+}
+
+// Download href inputs of a job, stops at the first input that can not be downloaded or does not match its checksum.
+// Downloaded files are left in place, they are removed with the job inputs directory when the job closes.
+func downloadInputs(ctx context.Context, downloads []InputDownload, logger *log.Logger) error {
+	for _, d := range downloads {
+		if err := downloadInput(ctx, d); err != nil {
+			return fmt.Errorf("input %s: %s", d.InputID, err.Error())
+		}
+		logger.Infof("Staged input %s from %s.", d.InputID, d.Href)
+	}
+	return nil
+}
+
+func downloadInput(ctx context.Context, d InputDownload) error {
+	if err := checkHref(d.Href); err != nil {
+		return err
+	}
+
+	var h hash.Hash
+	var expected string
+	if d.Checksum != "" {
+		var err error
+		h, expected, err = parseChecksum(d.Checksum)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.Href, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := inputDownloadClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error downloading %s: %s", d.Href, err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error downloading %s: %s", d.Href, resp.Status)
+	}
+
+	if d.MediaType != "" {
+		mt, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		if err != nil || !strings.EqualFold(mt, d.MediaType) {
+			return fmt.Errorf("unexpected content type '%s' of %s, expected '%s'", resp.Header.Get("Content-Type"), d.Href, d.MediaType)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(d.Path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(d.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var body io.Reader = resp.Body
+	if h != nil {
+		body = io.TeeReader(resp.Body, h)
+	}
+	if _, err = io.Copy(f, body); err != nil {
+		return fmt.Errorf("could not stage %s: %s", d.Href, err.Error())
+	}
+
+	if h != nil {
+		if actual := hex.EncodeToString(h.Sum(nil)); actual != expected {
+			return fmt.Errorf("checksum mismatch for %s, expected %s got %s", d.Href, expected, actual)
+		}
+	}
+	return nil
+}
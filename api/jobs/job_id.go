@@ -0,0 +1,95 @@
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const uuidPattern = `[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`
+
+// Layout of the {timestamp} placeholder of job IDs, in UTC
+const jobIDTimestampLayout = "20060102T150405Z"
+
+// Format of IDs of new jobs, set through SetJobIDFormat
+var jobIDFormat = "{uuid}"
+
+// Patterns matched by job ID placeholders
+var jobIDPlaceholders = map[string]string{
+	"{uuid}":      uuidPattern,
+	"{processID}": `[A-Za-z0-9]+`,
+	"{timestamp}": `[0-9]{8}T[0-9]{6}Z`,
+	"{rand}":      `[0-9a-f]{8}`,
+}
+
+// Matches whole IDs of the job ID format
+var fullJobIDPattern = regexp.MustCompile("^" + uuidPattern + "$")
+
+var (
+	jobIDPlaceholder   = regexp.MustCompile(`\{[A-Za-z]+\}`)
+	validJobIDLiterals = regexp.MustCompile(`^[A-Za-z0-9_-]*$`)
+	nonAlphanumeric    = regexp.MustCompile(`[^A-Za-z0-9]`)
+)
+
+// SetJobIDFormat sets the format of IDs of new jobs, with {uuid}, {processID}, {timestamp} and {rand} placeholders.
+// The format must contain {uuid} or {rand} so that IDs are unique, other characters must be letters, numbers,
+// hyphens or underscores so that IDs are URL safe and can be part of AWS Batch job names.
+// Must be called at startup before any job is created.
+func SetJobIDFormat(format string) error {
+	if !strings.Contains(format, "{uuid}") && !strings.Contains(format, "{rand}") {
+		return fmt.Errorf("job ID format must contain {uuid} or {rand}")
+	}
+
+	var pattern strings.Builder
+	last := 0
+	for _, loc := range jobIDPlaceholder.FindAllStringIndex(format, -1) {
+		literal := format[last:loc[0]]
+		if !validJobIDLiterals.MatchString(literal) {
+			return fmt.Errorf("job ID format can only have letters, numbers, hyphens, underscores and placeholders, found '%s'", literal)
+		}
+		p, ok := jobIDPlaceholders[format[loc[0]:loc[1]]]
+		if !ok {
+			return fmt.Errorf("unknown placeholder %s in job ID format, must be one of [{uuid}, {processID}, {timestamp}, {rand}]", format[loc[0]:loc[1]])
+		}
+		pattern.WriteString(regexp.QuoteMeta(literal) + p)
+		last = loc[1]
+	}
+	if !validJobIDLiterals.MatchString(format[last:]) {
+		return fmt.Errorf("job ID format can only have letters, numbers, hyphens, underscores and placeholders, found '%s'", format[last:])
+	}
+	pattern.WriteString(regexp.QuoteMeta(format[last:]))
+
+	jobIDFormat = format
+	jobIDPattern = regexp.MustCompile(pattern.String())
+	fullJobIDPattern = regexp.MustCompile("^" + pattern.String() + "$")
+	return nil
+}
+
+// NewJobID returns an ID for a new job of the process in the configured format.
+// IDs containing only random parts shorter than a UUID should be checked for uniqueness, see JobIDsMayCollide.
+func NewJobID(processID string) string {
+	var r [4]byte
+	rand.Read(r[:])
+
+	return strings.NewReplacer(
+		"{uuid}", uuid.New().String(),
+		"{processID}", nonAlphanumeric.ReplaceAllString(processID, ""),
+		"{timestamp}", time.Now().UTC().Format(jobIDTimestampLayout),
+		"{rand}", hex.EncodeToString(r[:]),
+	).Replace(jobIDFormat)
+}
+
+// IsJobID reports if id is an ID of the configured format
+func IsJobID(id string) bool {
+	return fullJobIDPattern.MatchString(id)
+}
+
+// JobIDsMayCollide reports if IDs of the configured format are not guaranteed unique by a UUID
+func JobIDsMayCollide() bool {
+	return !strings.Contains(jobIDFormat, "{uuid}")
+}
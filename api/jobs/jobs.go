@@ -2,6 +2,7 @@ package jobs
 
 import (
 	"app/utils"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -29,6 +30,7 @@ type Job interface {
 	ProcessID() string
 	ProcessVersionID() string
 	SUBMITTER() string
+	SUBMITTERID() string
 
 	// UpdateProcessLogs must provide most upto date process logs
 	// for containerized processes, first fetch the current container logs
@@ -45,8 +47,8 @@ type Job interface {
 	// If a zero-value time is provided as updateTime, the current time (time.Now()) should be set as the UpdateTime.
 	// Otherwise, the provided updateTime should be set as the UpdateTime.
 	// This function should also update the job record in the database with the new status and UpdateTime.
-	// If old status is one of the terminated status, it should not update status.
-	NewStatusUpdate(string, time.Time)
+	// Only valid transitions from the current status are applied, see jobStatus. Returns false if the update was rejected.
+	NewStatusUpdate(string, time.Time) bool
 
 	// Create must change job status to accepted.
 	// Must create log files.
@@ -77,6 +79,44 @@ type JobRecord struct {
 	Host       string    `json:"host,omitempty"`
 	Mode       string    `json:"mode,omitempty"`
 	Submitter  string    `json:"submitter"`
+	// SubmitterID is the `sub` claim of the token used to submit the job
+	SubmitterID string `json:"submitterID,omitempty"`
+	// Results of successful jobs are no longer retained after ExpiresAt, nil means they never expire
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	// Archived jobs have been moved out of the jobs table, they are not listed but can still be fetched by ID
+	Archived bool `json:"archived,omitempty"`
+	// Outputs requested in the execution request, results only include these, empty means all outputs
+	RequestedOutputs []string `json:"requestedOutputs,omitempty"`
+	// Process metadata at submission, nil for jobs recorded before snapshots were taken
+	Process *ProcessSnapshot `json:"-"`
+}
+
+// Owner identifies the caller jobs belong to. Jobs recorded with a submitter ID belong to the caller with that ID,
+// jobs recorded without one belong to the caller with their submitter email.
+type Owner struct {
+	SubmitterID string
+	Submitter   string
+}
+
+// Owns reports if a job of submitter and submitterID belongs to the owner
+func (o Owner) Owns(submitter, submitterID string) bool {
+	if submitterID != "" {
+		return submitterID == o.SubmitterID
+	}
+	return submitter == o.Submitter
+}
+
+// ProcessSnapshot holds metadata of the process a job was submitted to, taken at submission
+// so that the job can be handled after the process is updated or removed.
+type ProcessSnapshot struct {
+	Version string `json:"version,omitempty"`
+	// Template of the result hook of the process, empty if it has none
+	ResultHook string `json:"resultHook,omitempty"`
+	// Seconds a job may run before it is considered stuck, nil means no limit
+	MaxRunningTime *int   `json:"maxRunningTime,omitempty"`
+	StuckAction    string `json:"stuckAction,omitempty"`
+	// Media type of results that do not declare their own, empty means the default of the server
+	DefaultResultContentType string `json:"defaultResultContentType,omitempty"`
 }
 
 type LogEntry struct {
@@ -111,6 +151,25 @@ type JobLogs struct {
 	Status      string     `json:"status"`
 	ProcessLogs []LogEntry `json:"process_logs"`
 	ServerLogs  []LogEntry `json:"server_logs"`
+	// Stdout and Stderr of the process as separate streams, process logs have both streams combined.
+	// Only available for docker and subprocess jobs.
+	Stdout []LogEntry `json:"stdout,omitempty"`
+	Stderr []LogEntry `json:"stderr,omitempty"`
+}
+
+// Log files of the output streams of a process, these are optional as not all hosts capture streams separately
+var streamLogKeys = []string{"stdout", "stderr"}
+
+// Write lines to a local log file of a job, overwriting the file if it exists
+func writeLocalLogs(jid, key string, lines []string) error {
+	file, err := os.Create(fmt.Sprintf("%s/%s.%s.jsonl", os.Getenv("TMP_JOB_LOGS_DIR"), jid, key))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(strings.Join(lines, "\n"))
+	return err
 }
 
 // Prettify JobLogs by replacing nil with empty []LogEntry{}
@@ -132,6 +191,31 @@ const (
 	DISMISSED  string = "dismissed"
 )
 
+// FailJob marks an active job as FAILED with reason in its logs and terminates it at its provider.
+// Jobs that are already in a terminated status are left unchanged.
+func FailJob(j Job, reason string) {
+	switch j.CurrentStatus() {
+	case SUCCESSFUL, DISMISSED, FAILED:
+		return
+	}
+
+	j.LogMessage(reason, logrus.ErrorLevel)
+	if bj, ok := j.(*AWSBatchJob); ok {
+		// local jobs are terminated by Close, batch jobs must be terminated explicitly
+		if err := bj.terminate(); err != nil {
+			j.LogMessage(fmt.Sprintf("Could not terminate job on AWS Batch. Error: %s", err.Error()), logrus.ErrorLevel)
+		}
+	}
+	if !j.NewStatusUpdate(FAILED, time.Time{}) {
+		return
+	}
+
+	go func() {
+		j.Close()
+		j.RunFinished()
+	}()
+}
+
 // FetchResults by parsing logs
 // Assumes last log will be results always
 func FetchResults(svc *s3.S3, jid string) (interface{}, error) {
@@ -147,14 +231,17 @@ func FetchResults(svc *s3.S3, jid string) (interface{}, error) {
 		return nil, fmt.Errorf("no process logs available")
 	}
 
-	lastLog := processLogs[lastLogIdx]
-	lastLogMsg := lastLog.Msg
-	lastLogMsg = strings.ReplaceAll(lastLogMsg, "'", "\"")
+	return parsePluginResults(processLogs[lastLogIdx])
+}
+
+// Parse results from a log entry of the form {"plugin_results": {....}}
+func parsePluginResults(l LogEntry) (interface{}, error) {
+	logMsg := strings.ReplaceAll(l.Msg, "'", "\"")
 
 	var data map[string]interface{}
-	err = json.Unmarshal([]byte(lastLogMsg), &data)
+	err := json.Unmarshal([]byte(logMsg), &data)
 	if err != nil {
-		return nil, fmt.Errorf(`unable to parse results, expected {"plugin_results": {....}}, found : %s. Error: %s`, lastLog, err.Error())
+		return nil, fmt.Errorf(`unable to parse results, expected {"plugin_results": {....}}, found : %s. Error: %s`, l, err.Error())
 	}
 
 	pluginResults, ok := data["plugin_results"]
@@ -165,6 +252,52 @@ func FetchResults(svc *s3.S3, jid string) (interface{}, error) {
 	return pluginResults, nil
 }
 
+// FetchPartialResults returns the latest results written to the process logs of a running job so far.
+// Process logs must be updated before calling this. Returns error "not found" if no results have been written yet.
+func FetchPartialResults(svc *s3.S3, jid string) (interface{}, error) {
+	logs, err := FetchLogs(svc, jid, true)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(logs.ProcessLogs) - 1; i >= 0; i-- {
+		if !strings.Contains(logs.ProcessLogs[i].Msg, "plugin_results") {
+			continue
+		}
+		if results, err := parsePluginResults(logs.ProcessLogs[i]); err == nil {
+			return results, nil
+		}
+	}
+	return nil, fmt.Errorf("not found")
+}
+
+// FetchStdoutResults reads the process logs of a recently finished job from local disk
+// and returns them as results. The complete stdout of the process must be a valid JSON document.
+func FetchStdoutResults(jid string) (interface{}, error) {
+	// stdout is read from its own log file when it is captured separately, so that stderr does not end up in results
+	localPath := fmt.Sprintf("%s/%s.stdout.jsonl", os.Getenv("TMP_JOB_LOGS_DIR"), jid)
+	if _, err := os.Stat(localPath); err != nil {
+		localPath = fmt.Sprintf("%s/%s.process.jsonl", os.Getenv("TMP_JOB_LOGS_DIR"), jid)
+	}
+	stdout, err := os.ReadFile(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read process stdout: %s", err.Error())
+	}
+
+	stdout = bytes.TrimSpace(stdout)
+	if !json.Valid(stdout) {
+		return nil, fmt.Errorf("process stdout is not valid JSON")
+	}
+
+	var data interface{}
+	err = json.Unmarshal(stdout, &data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse process stdout. Error: %s", err.Error())
+	}
+
+	return data, nil
+}
+
 // // If JobID exists but results file doesn't then it raises an error
 // // Assumes jobID is valid
 // func FetchResults(svc *s3.S3, jid string) (interface{}, error) {
@@ -217,23 +350,36 @@ func FetchLogs(svc *s3.S3, jid string, onlyContainer bool) (JobLogs, error) {
 	localDir := os.Getenv("TMP_JOB_LOGS_DIR") // Local directory where logs are stored
 
 	keys := []struct {
-		key    string
-		target *[]LogEntry
+		key      string
+		target   *[]LogEntry
+		optional bool
 	}{
 		{
 			"process",
 			&result.ProcessLogs,
+			false,
 		},
 		{
 			"server",
 			&result.ServerLogs,
+			false,
+		},
+		{
+			"stdout",
+			&result.Stdout,
+			true,
+		},
+		{
+			"stderr",
+			&result.Stderr,
+			true,
 		},
 	}
 
 	for _, k := range keys {
 		// First, check locally
 
-		if k.key == "server" && onlyContainer {
+		if k.key != "process" && onlyContainer {
 			continue
 		}
 
@@ -252,6 +398,9 @@ func FetchLogs(svc *s3.S3, jid string, onlyContainer bool) (JobLogs, error) {
 			return JobLogs{}, err
 		}
 		if !exists {
+			if k.optional {
+				continue
+			}
 			return JobLogs{}, fmt.Errorf("%s log file not found on storage", k.key)
 		}
 		logs, err := utils.GetS3LinesData(storageKey, svc)
@@ -266,8 +415,45 @@ func FetchLogs(svc *s3.S3, jid string, onlyContainer bool) (JobLogs, error) {
 	return result, nil
 }
 
-// Upload log files from local disk to storage service
+// Duration results of successful jobs are retained for, 0 means forever
+var resultsTTL time.Duration
+
+// SetResultsTTL sets the duration results of jobs are retained for after the job is successful, 0 means forever.
+// Must be called at startup before any job is created.
+func SetResultsTTL(ttl time.Duration) {
+	resultsTTL = ttl
+}
+
+// ResultsExpired reports if results of the job are past their retention
+func (jr JobRecord) ResultsExpired() bool {
+	return jr.ExpiresAt != nil && time.Now().After(*jr.ExpiresAt)
+}
+
+// Expiry of results of a job updated to status at updated, nil if results do not expire
+func resultsExpiry(status string, updated time.Time) *time.Time {
+	if status != SUCCESSFUL || resultsTTL <= 0 {
+		return nil
+	}
+	expiresAt := updated.Add(resultsTTL)
+	return &expiresAt
+}
+
+// Gzip process logs, which carry the results of jobs, before uploading them to storage
+var compressResults bool
+
+// SetCompressResults enables or disables compression of results written to storage.
+// Must be called at startup before any job is created.
+func SetCompressResults(compress bool) {
+	compressResults = compress
+}
+
+// Upload log files from local disk to storage service, nothing is uploaded if no storage service is configured
 func UploadLogsToStorage(svc *s3.S3, jid, pid string) {
+	if svc == nil {
+		return
+	}
+	release := acquireStorageWorker()
+	defer release()
 
 	localDir := os.Getenv("TMP_JOB_LOGS_DIR") // Local directory where logs are stored
 
@@ -275,23 +461,149 @@ func UploadLogsToStorage(svc *s3.S3, jid, pid string) {
 		"process",
 		"server",
 	}
+	keys = append(keys, streamLogKeys...)
 
 	for _, k := range keys {
 		localPath := fmt.Sprintf("%s/%s.%s.jsonl", localDir, jid, k)
 		bytes, err := os.ReadFile(localPath)
+		if os.IsNotExist(err) && utils.StringInSlice(k, streamLogKeys) {
+			continue
+		}
 		if err != nil {
 			log.Error(err.Error())
 		}
 
 		storageKey := fmt.Sprintf("%s/%s.%s.jsonl", os.Getenv("STORAGE_LOGS_PREFIX"), jid, k)
-		err = utils.WriteToS3(svc, bytes, storageKey, "text/plain", 0)
+		if k == "process" && compressResults {
+			err = writeGzipToStorage(svc, jid, bytes, storageKey, "text/plain")
+		} else {
+			err = writeToStorage(svc, jid, bytes, storageKey, "text/plain")
+		}
 		if err != nil {
 			log.Error(err.Error())
 		}
 	}
 }
 
+// RecordCreateFailure persists a job whose Create() failed as FAILED so that it remains queryable.
+// The creation error is appended to the server logs of the job and logs are uploaded to storage.
+// jr must have JobID, ProcessID, Host and Submitter details set.
+func RecordCreateFailure(db Database, svc *s3.S3, jr JobRecord, createErr error) error {
+	localDir := os.Getenv("TMP_JOB_LOGS_DIR")
+
+	// Create may have failed before log files were created
+	processLogs, err := os.OpenFile(fmt.Sprintf("%s/%s.process.jsonl", localDir, jr.JobID), os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %s", err.Error())
+	}
+	processLogs.Close()
+
+	serverLogs, err := os.OpenFile(fmt.Sprintf("%s/%s.server.jsonl", localDir, jr.JobID), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %s", err.Error())
+	}
+	logger := logrus.New()
+	logger.SetOutput(serverLogs)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.Errorf("Failed to create job. Error: %s", createErr.Error())
+	logger.Infof("Status changed to %s.", FAILED)
+	serverLogs.Close()
+
+	// Create may have failed after the job was added to database
+	_, exist, err := db.GetJob(jr.JobID)
+	if err != nil {
+		return err
+	}
+	if exist {
+		err = db.updateJobRecord(jr.JobID, FAILED, time.Now())
+	} else {
+		err = db.addJob(jr.JobID, FAILED, "", jr.Host, jr.ProcessID, jr.Submitter, jr.SubmitterID, time.Now())
+	}
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		UploadLogsToStorage(svc, jr.JobID, jr.ProcessID)
+		time.Sleep(time.Hour)
+		DeleteLocalLogs(svc, jr.JobID, jr.ProcessID)
+	}()
+	return nil
+}
+
+// RecordCacheHit persists a job that reuses results of the successful job sourceJobID instead of executing as SUCCESSFUL.
+// Results are written to the process logs of the job so that they can be fetched like results of any other job,
+// metadata of the source job is copied with cacheHitOf set to the source job. Logs are uploaded to storage.
+// jr must have JobID, ProcessID, Mode, Host and Submitter details set.
+func RecordCacheHit(db Database, svc *s3.S3, jr JobRecord, sourceJobID string, results interface{}) error {
+	localDir := os.Getenv("TMP_JOB_LOGS_DIR")
+
+	b, err := json.Marshal(map[string]interface{}{"plugin_results": results})
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(fmt.Sprintf("%s/%s.process.jsonl", localDir, jr.JobID), append(b, '\n'), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write log file: %s", err.Error())
+	}
+
+	serverLogs, err := os.OpenFile(fmt.Sprintf("%s/%s.server.jsonl", localDir, jr.JobID), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %s", err.Error())
+	}
+	logger := logrus.New()
+	logger.SetOutput(serverLogs)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.Infof("Cache hit, reusing results of job %s. Process was not executed.", sourceJobID)
+	logger.Infof("Status changed to %s.", SUCCESSFUL)
+	serverLogs.Close()
+
+	md := make(map[string]interface{})
+	if data, err := FetchMeta(svc, sourceJobID); err == nil {
+		if m, ok := data.(map[string]interface{}); ok {
+			md = m
+		}
+	}
+	md["apiJobId"] = jr.JobID
+	md["cacheHitOf"] = sourceJobID
+	if b, err = json.Marshal(md); err == nil {
+		release := acquireStorageWorker()
+		err = writeToStorage(svc, jr.JobID, b, fmt.Sprintf("%s/%s.json", os.Getenv("STORAGE_METADATA_PREFIX"), jr.JobID), "application/json")
+		release()
+	}
+	if err != nil {
+		log.Errorf("Failed to write metadata of cache hit job %s: %s", jr.JobID, err.Error())
+	}
+
+	err = db.addJob(jr.JobID, SUCCESSFUL, jr.Mode, jr.Host, jr.ProcessID, jr.Submitter, jr.SubmitterID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		UploadLogsToStorage(svc, jr.JobID, jr.ProcessID)
+		time.Sleep(time.Hour)
+		DeleteLocalLogs(svc, jr.JobID, jr.ProcessID)
+	}()
+	return nil
+}
+
+// Remove the staged file inputs directory of a job, empty dir means the job has no staged inputs.
+func removeStagedInputs(dir string, logger *logrus.Logger) {
+	if dir == "" {
+		return
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		logger.Errorf("Could not remove staged inputs %s. Error: %s", dir, err.Error())
+	}
+}
+
+// Delete local log files of a job once they are uploaded.
+// Without a storage service local logs are the only copy, so they are kept.
 func DeleteLocalLogs(svc *s3.S3, jid, pid string) {
+	if svc == nil {
+		return
+	}
 	localDir := os.Getenv("TMP_JOB_LOGS_DIR") // Local directory where logs are stored
 
 	// List of log types
@@ -299,10 +611,14 @@ func DeleteLocalLogs(svc *s3.S3, jid, pid string) {
 		"process",
 		"server",
 	}
+	keys = append(keys, streamLogKeys...)
 
 	for _, k := range keys {
 		localPath := fmt.Sprintf("%s/%s.%s.jsonl", localDir, jid, k)
 		err := os.Remove(localPath)
+		if os.IsNotExist(err) && utils.StringInSlice(k, streamLogKeys) {
+			continue
+		}
 		if err != nil {
 			log.Error(fmt.Sprintf("Failed to delete local file %s: %v", localPath, err))
 		}
@@ -24,15 +24,14 @@ type MessageQueue struct {
 // This function should not block the routine as it is being called by message queue
 func ProcessStatusMessageUpdate(sm StatusMessage) {
 
-	// Multiple calls should not trigger multiple close or metadata routines
-	// A better way to achieve this would be through locks.
-	switch (*sm.Job).CurrentStatus() {
-	case SUCCESSFUL, DISMISSED, FAILED:
+	// Multiple calls should not trigger multiple close or metadata routines,
+	// only the update that terminates the job is applied.
+	if !(*sm.Job).NewStatusUpdate(sm.Status, sm.LastUpdate) {
 		return
 	}
-	(*sm.Job).NewStatusUpdate(sm.Status, sm.LastUpdate)
 
-	switch sm.Status {
+	// results check may have failed a successful job
+	switch (*sm.Job).CurrentStatus() {
 	case SUCCESSFUL:
 		go (*sm.Job).WriteMetaData()
 		fallthrough
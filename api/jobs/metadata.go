@@ -1,10 +1,11 @@
 package jobs
 
 import (
+	"app/utils"
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
+	"reflect"
 	"strings"
 	"time"
 
@@ -33,9 +34,79 @@ type metaData struct {
 	// ComputeEnvironmentURI    string    // ARN
 	// ComputeEnvironmentDigest string    // required for reproducibility, will need to be custom implemented
 	Commands        []string  `json:"commands"`
+	OutputLocation  string    `json:"outputLocation,omitempty"`
+	RequestID       string    `json:"requestId,omitempty"`
 	GeneratedAtTime time.Time `json:"generatedAtTime"` // not implemented
 	StartedAtTime   time.Time `json:"startedAtTime"`   // not implemented
 	EndedAtTime     time.Time `json:"endedAtTime"`
+	// Best effort, omitted when usage could not be collected
+	ResourceUsage *resourceUsage `json:"resourceUsage,omitempty"`
+	// Digests of results, omitted when results could not be fetched
+	Results *ResultsIntegrity `json:"results,omitempty"`
+	// Custom fields of the execution request, written at the top level of the document
+	Custom map[string]interface{} `json:"-"`
+}
+
+// Names of fields of metaData, custom fields can not use these or names starting with @ reserved by JSON-LD
+var reservedMetadataFields = func() []string {
+	t := reflect.TypeOf(metaData{})
+	names := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}()
+
+// ValidateCustomMetadata checks custom metadata of an execution request, encoded as JSON it must not exceed maxSize bytes.
+func ValidateCustomMetadata(custom map[string]interface{}, maxSize int) error {
+	for k := range custom {
+		if k == "" || strings.HasPrefix(k, "@") || utils.StringInSlice(k, reservedMetadataFields) {
+			return fmt.Errorf("metadata field '%s' is reserved", k)
+		}
+	}
+
+	b, err := json.Marshal(custom)
+	if err != nil {
+		return fmt.Errorf("invalid metadata: %s", err.Error())
+	}
+	if len(b) > maxSize {
+		return fmt.Errorf("metadata of %d bytes exceeds the maximum allowed size of %d bytes", len(b), maxSize)
+	}
+	return nil
+}
+
+func (md metaData) MarshalJSON() ([]byte, error) {
+	// fields has the fields of metaData without this method, so that it is marshalled as a struct
+	type fields metaData
+	b, err := json.Marshal(fields(md))
+	if err != nil || len(md.Custom) == 0 {
+		return b, err
+	}
+
+	var doc map[string]json.RawMessage
+	if err = json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	merged := make(map[string]interface{}, len(doc)+len(md.Custom))
+	for k, v := range md.Custom {
+		merged[k] = v
+	}
+	// reserved fields are rejected at submission, these are never overwritten
+	for k, v := range doc {
+		merged[k] = v
+	}
+	return json.Marshal(merged)
+}
+
+// Resources consumed by a job, units match those of process resources
+type resourceUsage struct {
+	// Highest memory used at any time, in megabytes, omitted when unknown
+	PeakMemory float64 `json:"peakMemory,omitempty"`
+	// CPU time across all cores, in seconds
+	CPUTime float64 `json:"cpuTime"`
 }
 
 // Get image digest from ecr
@@ -43,6 +114,7 @@ func getECRImageDigest(imgURI string) (string, error) {
 	var imgDgst string
 
 	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            aws.Config{HTTPClient: registryClient},
 		SharedConfigState: session.SharedConfigEnable,
 	})
 	if err != nil {
@@ -116,11 +188,7 @@ func getDkrHubImageDigest(imgURI string, arch string) (string, error) {
 
 	url := fmt.Sprintf("https://hub.docker.com/v2/repositories/%s/tags/%s/images", imageName, imageTag)
 
-	client := http.Client{
-		Timeout: 10 * time.Second, // Set a timeout for the request
-	}
-
-	response, err := client.Get(url)
+	response, err := registryClient.Get(url)
 	if err != nil {
 		return "", fmt.Errorf("error sending request: %s", err)
 
@@ -137,6 +205,9 @@ func getDkrHubImageDigest(imgURI string, arch string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("error parsing JSON: %s", err)
 	}
+	if len(result) == 0 {
+		return "", fmt.Errorf("no images found for %s", imgURI)
+	}
 
 	// Currently it gets just the first image, while there can be more than 1. This is incorrect
 	digest, ok := result[0].(map[string]interface{})["digest"].(string)
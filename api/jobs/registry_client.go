@@ -0,0 +1,60 @@
+package jobs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Client used for image digest lookups of job metadata on Docker Hub and ECR
+var registryClient = &http.Client{Timeout: 10 * time.Second}
+
+// RegistryClientOptions configure HTTP calls to image registries
+type RegistryClientOptions struct {
+	// Timeout of a single lookup, 0 means no timeout
+	Timeout time.Duration
+	// Proxy URL for registry calls, empty means HTTP_PROXY, HTTPS_PROXY and NO_PROXY env variables are used
+	ProxyURL string
+	// PEM file of CA certificates trusted in addition to the system ones, for proxies that intercept TLS
+	CAFile string
+	// Skip verification of registry certificates, only meant for testing
+	InsecureSkipVerify bool
+}
+
+// SetRegistryClientOptions sets how image registries are called to look up image digests.
+// Must be called at startup before any job is created.
+func SetRegistryClientOptions(opts RegistryClientOptions) error {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if opts.ProxyURL != "" {
+		proxy, err := url.Parse(opts.ProxyURL)
+		if err != nil || proxy.Host == "" {
+			return fmt.Errorf("invalid proxy URL '%s'", opts.ProxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: opts.InsecureSkipVerify}
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return fmt.Errorf("could not read CA file: %s", err.Error())
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in CA file %s", opts.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	registryClient = &http.Client{Timeout: opts.Timeout, Transport: transport}
+	return nil
+}
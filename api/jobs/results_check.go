@@ -0,0 +1,33 @@
+package jobs
+
+import "github.com/sirupsen/logrus"
+
+// ResultsCheck validates results of a job before it is marked successful
+type ResultsCheck struct {
+	// Validate fetches and checks results of the job, process logs are updated before it is called
+	Validate func(jid string) error
+	// Fail the job if results are not valid, otherwise the mismatch is only logged
+	Fail bool
+}
+
+// Returns the status a job finishing with status must be marked with.
+// Only successful jobs are checked and these are marked failed if their results are invalid and rc.Fail is set.
+func (rc *ResultsCheck) status(j Job, status string) string {
+	if rc == nil || status != SUCCESSFUL {
+		return status
+	}
+
+	if err := j.UpdateProcessLogs(); err != nil {
+		j.LogMessage("Could not update process logs to check results: "+err.Error(), logrus.WarnLevel)
+	}
+	err := rc.Validate(j.JobID())
+	if err == nil {
+		return status
+	}
+	if rc.Fail {
+		j.LogMessage("Results do not match output schemas, failing job: "+err.Error(), logrus.ErrorLevel)
+		return FAILED
+	}
+	j.LogMessage("Results do not match output schemas: "+err.Error(), logrus.WarnLevel)
+	return status
+}
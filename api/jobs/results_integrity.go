@@ -0,0 +1,95 @@
+package jobs
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/sirupsen/logrus"
+)
+
+// Key digests of results are signed with, nil means digests are not signed
+var resultsSigningKey ed25519.PrivateKey
+
+// SetResultsSigningKey sets the Ed25519 key digests of results are signed with from its base64 encoded 32 byte seed.
+// Must be called at startup before any job is created.
+func SetResultsSigningKey(seed string) error {
+	b, err := base64.StdEncoding.DecodeString(seed)
+	if err != nil {
+		return err
+	}
+	if len(b) != ed25519.SeedSize {
+		return fmt.Errorf("key seed must be %d bytes, got %d", ed25519.SeedSize, len(b))
+	}
+	resultsSigningKey = ed25519.NewKeyFromSeed(b)
+	return nil
+}
+
+// ResultsIntegrity allows clients to verify results of a job.
+// Digests are hex encoded SHA-256 of results encoded as compact JSON with sorted object keys,
+// results are those written by the process, before any resultHook is applied.
+type ResultsIntegrity struct {
+	SHA256 string `json:"sha256"`
+	// Digest of each output, when results are an object keyed by output ID
+	Outputs map[string]string `json:"outputs,omitempty"`
+	// Base64 encoded Ed25519 signature of SHA256, set if a signing key is configured
+	Signature string `json:"signature,omitempty"`
+	// Base64 encoded public key the signature can be verified with
+	PublicKey string `json:"publicKey,omitempty"`
+}
+
+// NewResultsIntegrity computes digests of results and signs them if a signing key is configured
+func NewResultsIntegrity(results interface{}) (*ResultsIntegrity, error) {
+	sum, err := jsonSHA256(results)
+	if err != nil {
+		return nil, err
+	}
+	ri := ResultsIntegrity{SHA256: sum}
+
+	if outputs, ok := results.(map[string]interface{}); ok {
+		ri.Outputs = make(map[string]string, len(outputs))
+		for id, v := range outputs {
+			if ri.Outputs[id], err = jsonSHA256(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if resultsSigningKey != nil {
+		ri.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(resultsSigningKey, []byte(sum)))
+		ri.PublicKey = base64.StdEncoding.EncodeToString(resultsSigningKey.Public().(ed25519.PublicKey))
+	}
+	return &ri, nil
+}
+
+// json.Marshal sorts keys of maps, so equal values always have the same digest
+func jsonSHA256(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Integrity of results of a successful job for its metadata, nil if results can not be fetched
+func metaDataResultsIntegrity(j Job, svc *s3.S3) *ResultsIntegrity {
+	if err := j.UpdateProcessLogs(); err != nil {
+		j.LogMessage("Could not update process logs to add results digest to metadata: "+err.Error(), logrus.WarnLevel)
+	}
+	results, err := FetchResults(svc, j.JobID())
+	if err != nil {
+		j.LogMessage("Results digest not added to metadata, could not fetch results: "+err.Error(), logrus.WarnLevel)
+		return nil
+	}
+	ri, err := NewResultsIntegrity(results)
+	if err != nil {
+		j.LogMessage("Results digest not added to metadata: "+err.Error(), logrus.WarnLevel)
+		return nil
+	}
+	return ri
+}
@@ -0,0 +1,129 @@
+package jobs
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Holds jobs in accepted status while paused, held jobs start once it is resumed in order of priority, then submission.
+// AWS Batch jobs are held before they are submitted to AWS Batch.
+var scheduler = struct {
+	sync.Mutex
+	pausedSince time.Time
+	// jobs held since the scheduler was paused, in order of submission
+	held    []*heldJob
+	waiting int
+}{}
+
+type heldJob struct {
+	priority int
+	// closed by the scheduler to start the job
+	release chan struct{}
+	// closed by the job once it stopped waiting
+	done chan struct{}
+}
+
+// SchedulerState describes whether new jobs are started
+type SchedulerState struct {
+	Paused      bool       `json:"paused"`
+	PausedSince *time.Time `json:"pausedSince,omitempty"`
+	// Number of jobs held in accepted status
+	Waiting int `json:"waiting"`
+}
+
+// PauseScheduler stops starting new jobs, jobs submitted while paused remain in accepted status.
+// Jobs already running are not affected.
+func PauseScheduler() SchedulerState {
+	scheduler.Lock()
+	defer scheduler.Unlock()
+
+	if scheduler.pausedSince.IsZero() {
+		scheduler.pausedSince = time.Now()
+	}
+	return schedulerState()
+}
+
+// ResumeScheduler starts the held jobs and new jobs again.
+// Held jobs are started one at a time, higher priority jobs first and jobs of the same priority in order of submission.
+func ResumeScheduler() SchedulerState {
+	scheduler.Lock()
+	defer scheduler.Unlock()
+
+	if !scheduler.pausedSince.IsZero() {
+		held := scheduler.held
+		sort.SliceStable(held, func(i, k int) bool { return held[i].priority > held[k].priority })
+		go releaseHeldJobs(held)
+
+		scheduler.held = nil
+		scheduler.pausedSince = time.Time{}
+	}
+	return schedulerState()
+}
+
+// Start held jobs in order, each job is released once the previous one stopped waiting
+func releaseHeldJobs(held []*heldJob) {
+	for _, h := range held {
+		close(h.release)
+		<-h.done
+	}
+}
+
+// GetSchedulerState returns the current state of the scheduler.
+func GetSchedulerState() SchedulerState {
+	scheduler.Lock()
+	defer scheduler.Unlock()
+	return schedulerState()
+}
+
+// Must be called holding the scheduler lock
+func schedulerState() SchedulerState {
+	s := SchedulerState{Paused: !scheduler.pausedSince.IsZero(), Waiting: scheduler.waiting}
+	if s.Paused {
+		pausedSince := scheduler.pausedSince
+		s.PausedSince = &pausedSince
+	}
+	return s
+}
+
+// Wait until the scheduler is not paused, jobs with a higher priority are released first. Returns false if ctx is done before that.
+func waitForScheduler(ctx context.Context, logger *log.Logger, priority *int) bool {
+	scheduler.Lock()
+	if scheduler.pausedSince.IsZero() {
+		scheduler.Unlock()
+		return true
+	}
+	h := &heldJob{release: make(chan struct{}), done: make(chan struct{})}
+	if priority != nil {
+		h.priority = *priority
+	}
+	scheduler.held = append(scheduler.held, h)
+	scheduler.waiting++
+	scheduler.Unlock()
+
+	logger.Info("Scheduler is paused, job will start once it is resumed.")
+	defer func() {
+		scheduler.Lock()
+		scheduler.waiting--
+		// jobs dismissed while paused are not released on resume
+		for i := range scheduler.held {
+			if scheduler.held[i] == h {
+				scheduler.held = append(scheduler.held[:i], scheduler.held[i+1:]...)
+				break
+			}
+		}
+		scheduler.Unlock()
+		close(h.done)
+	}()
+
+	select {
+	case <-h.release:
+		logger.Info("Scheduler resumed.")
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
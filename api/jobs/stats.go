@@ -0,0 +1,103 @@
+package jobs
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// jobTiming holds the fields of a job record needed to compute statistics.
+// Created is zero for jobs added before creation times were recorded.
+type jobTiming struct {
+	ProcessID string
+	Status    string
+	Created   time.Time
+	Updated   time.Time
+}
+
+// DurationStats summarizes durations of terminated jobs in seconds
+type DurationStats struct {
+	Count int     `json:"count"`
+	Avg   float64 `json:"avg"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+	Max   float64 `json:"max"`
+}
+
+// ProcessStats are statistics of the jobs of a single process
+type ProcessStats struct {
+	Counts map[string]int `json:"counts"`
+	// Failed jobs out of successful and failed jobs, dismissed jobs are not counted
+	FailureRate float64        `json:"failureRate"`
+	Duration    *DurationStats `json:"duration,omitempty"`
+}
+
+// JobStats are statistics of all jobs updated within a time window
+type JobStats struct {
+	Since     time.Time               `json:"since"`
+	Total     int                     `json:"total"`
+	Counts    map[string]int          `json:"counts"`
+	Processes map[string]ProcessStats `json:"processes"`
+}
+
+// ComputeJobStats computes statistics of jobs updated after since.
+// Durations are measured from creation to last update of successful and failed jobs.
+func ComputeJobStats(db Database, since time.Time) (JobStats, error) {
+	timings, err := db.getJobTimings(since)
+	if err != nil {
+		return JobStats{}, err
+	}
+
+	stats := JobStats{Since: since, Total: len(timings), Counts: map[string]int{}, Processes: map[string]ProcessStats{}}
+	durations := map[string][]float64{}
+	for _, t := range timings {
+		stats.Counts[t.Status]++
+
+		ps, ok := stats.Processes[t.ProcessID]
+		if !ok {
+			ps = ProcessStats{Counts: map[string]int{}}
+		}
+		ps.Counts[t.Status]++
+		stats.Processes[t.ProcessID] = ps
+
+		if (t.Status == SUCCESSFUL || t.Status == FAILED) && !t.Created.IsZero() {
+			durations[t.ProcessID] = append(durations[t.ProcessID], t.Updated.Sub(t.Created).Seconds())
+		}
+	}
+
+	for pid, ps := range stats.Processes {
+		if terminated := ps.Counts[SUCCESSFUL] + ps.Counts[FAILED]; terminated > 0 {
+			ps.FailureRate = float64(ps.Counts[FAILED]) / float64(terminated)
+		}
+		if d := durations[pid]; len(d) > 0 {
+			ps.Duration = durationStats(d)
+		}
+		stats.Processes[pid] = ps
+	}
+	return stats, nil
+}
+
+func durationStats(d []float64) *DurationStats {
+	sort.Float64s(d)
+	var sum float64
+	for _, v := range d {
+		sum += v
+	}
+	// nearest rank percentile
+	percentile := func(p float64) float64 {
+		i := int(math.Ceil(p/100*float64(len(d)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return d[i]
+	}
+	return &DurationStats{
+		Count: len(d),
+		Avg:   sum / float64(len(d)),
+		P50:   percentile(50),
+		P90:   percentile(90),
+		P99:   percentile(99),
+		Max:   d[len(d)-1],
+	}
+}
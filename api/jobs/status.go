@@ -0,0 +1,120 @@
+package jobs
+
+import (
+	"app/utils"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Statuses a job can move to from each status, terminated statuses are final.
+// A job that has no status yet can move to any status.
+var statusTransitions = map[string][]string{
+	ACCEPTED: {RUNNING, SUCCESSFUL, FAILED, DISMISSED},
+	RUNNING:  {SUCCESSFUL, FAILED, DISMISSED},
+}
+
+func validTransition(from, to string) bool {
+	if from == "" {
+		return true
+	}
+	return utils.StringInSlice(to, statusTransitions[from])
+}
+
+// jobStatus is embedded in jobs to hold their status, it provides CurrentStatus and LastUpdate of the Job interface.
+// Updates are compare and set against statusTransitions, so that concurrent updates such as a kill and the end of a
+// run can not both be applied and a job can never move backward or out of a terminated status.
+type jobStatus struct {
+	mu         sync.Mutex
+	UpdateTime time.Time
+	Status     string `json:"status"`
+	// set once the job leaves the queue and may have resources at its provider
+	started bool
+}
+
+func (s *jobStatus) CurrentStatus() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Status
+}
+
+func (s *jobStatus) LastUpdate() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.UpdateTime
+}
+
+// Update status of j to status if it is a valid transition, see NewStatusUpdate of Job.
+// Successful statuses are checked against rc first, which may fail the job.
+func (s *jobStatus) update(j Job, db Database, rc *ResultsCheck, logger *log.Logger, status string, updateTime time.Time) bool {
+	if !s.canMoveTo(status, logger) {
+		return false
+	}
+	// results are checked without holding the lock as logs and results are fetched
+	status = rc.status(j, status)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// the status may have changed while results were checked, e.g. the job was dismissed
+	if !validTransition(s.Status, status) {
+		logRejectedTransition(logger, s.Status, status)
+		return false
+	}
+
+	s.Status = status
+	if updateTime.IsZero() {
+		s.UpdateTime = time.Now()
+	} else {
+		s.UpdateTime = updateTime
+	}
+	db.updateJobRecord(j.JobID(), status, s.UpdateTime)
+	logger.Infof("Status changed to %s.", status)
+	return true
+}
+
+// Mark the job as started, returns false if it was terminated while queued and must not be started.
+func (s *jobStatus) markStarted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !validTransition(s.Status, RUNNING) {
+		return false
+	}
+	s.started = true
+	return true
+}
+
+// Dismiss j if it has not been started, returns false if it was started or is no longer accepted.
+// A job dismissed this way has nothing to stop at its provider.
+func (s *jobStatus) dismissQueued(j Job, db Database, logger *log.Logger) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started || s.Status != ACCEPTED {
+		return false
+	}
+
+	s.Status = DISMISSED
+	s.UpdateTime = time.Now()
+	db.updateJobRecord(j.JobID(), DISMISSED, s.UpdateTime)
+	logger.Infof("Status changed to %s.", DISMISSED)
+	return true
+}
+
+func (s *jobStatus) canMoveTo(status string, logger *log.Logger) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !validTransition(s.Status, status) {
+		logRejectedTransition(logger, s.Status, status)
+		return false
+	}
+	return true
+}
+
+// Repeated statuses are expected from providers that report status periodically, other rejections are illegal transitions
+func logRejectedTransition(logger *log.Logger, from, to string) {
+	if from == to {
+		logger.Debugf("Status update to %s ignored, job is already %s.", to, from)
+		return
+	}
+	logger.Warnf("Illegal status transition from %s to %s rejected.", from, to)
+}
@@ -0,0 +1,207 @@
+package jobs
+
+import (
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func testLogger() *log.Logger {
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// Job with the given status, only JobID of the job is used by status updates
+func testJob(status string) *SubprocessJob {
+	j := &SubprocessJob{UUID: "job"}
+	j.Status = status
+	return j
+}
+
+// A kill and the monitor ending the run race to terminate the job, exactly one of them must win
+// and the status must be the one of the winning update.
+func TestStatusUpdateConcurrentKillAndMonitor(t *testing.T) {
+	db := NewMemoryDB()
+	logger := testLogger()
+
+	for i := 0; i < 100; i++ {
+		j := testJob(RUNNING)
+
+		statuses := []string{DISMISSED, SUCCESSFUL, FAILED, DISMISSED}
+		applied := make([]bool, len(statuses))
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		for k, status := range statuses {
+			wg.Add(1)
+			go func(k int, status string) {
+				defer wg.Done()
+				<-start
+				applied[k] = j.jobStatus.update(j, db, nil, logger, status, time.Time{})
+			}(k, status)
+		}
+		close(start)
+		wg.Wait()
+
+		var winners []string
+		for k, ok := range applied {
+			if ok {
+				winners = append(winners, statuses[k])
+			}
+		}
+		if len(winners) != 1 {
+			t.Fatalf("applied updates = %v, want exactly one", winners)
+		}
+		if got := j.CurrentStatus(); got != winners[0] {
+			t.Fatalf("status = %s, want %s of the applied update", got, winners[0])
+		}
+	}
+}
+
+// Updates reported late by a provider must not move a job back
+func TestStatusUpdateConcurrentRunningAndTerminal(t *testing.T) {
+	db := NewMemoryDB()
+	logger := testLogger()
+
+	for i := 0; i < 100; i++ {
+		j := testJob(ACCEPTED)
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			j.jobStatus.update(j, db, nil, logger, RUNNING, time.Time{})
+		}()
+		go func() {
+			defer wg.Done()
+			j.jobStatus.update(j, db, nil, logger, FAILED, time.Time{})
+		}()
+		wg.Wait()
+
+		if got := j.CurrentStatus(); got != FAILED {
+			t.Fatalf("status = %s, want %s", got, FAILED)
+		}
+	}
+}
+
+func TestValidTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{"", ACCEPTED, true},
+		{"", RUNNING, true},
+		{ACCEPTED, RUNNING, true},
+		{ACCEPTED, SUCCESSFUL, true},
+		{ACCEPTED, FAILED, true},
+		{ACCEPTED, DISMISSED, true},
+		{ACCEPTED, ACCEPTED, false},
+		{RUNNING, SUCCESSFUL, true},
+		{RUNNING, FAILED, true},
+		{RUNNING, DISMISSED, true},
+		{RUNNING, ACCEPTED, false},
+		{RUNNING, RUNNING, false},
+		{SUCCESSFUL, FAILED, false},
+		{SUCCESSFUL, RUNNING, false},
+		{SUCCESSFUL, DISMISSED, false},
+		{FAILED, SUCCESSFUL, false},
+		{FAILED, ACCEPTED, false},
+		{DISMISSED, RUNNING, false},
+		{DISMISSED, FAILED, false},
+	}
+
+	for _, tt := range tests {
+		if got := validTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("validTransition(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestStatusUpdateRejectsIllegalTransitions(t *testing.T) {
+	db := NewMemoryDB()
+	logger := testLogger()
+
+	j := testJob(SUCCESSFUL)
+	updated := j.LastUpdate()
+	if j.jobStatus.update(j, db, nil, logger, RUNNING, time.Time{}) {
+		t.Error("update from successful to running was applied")
+	}
+	if got := j.CurrentStatus(); got != SUCCESSFUL {
+		t.Errorf("status = %s, want %s", got, SUCCESSFUL)
+	}
+	if !j.LastUpdate().Equal(updated) {
+		t.Error("last update changed by a rejected update")
+	}
+
+	j = testJob(RUNNING)
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if !j.jobStatus.update(j, db, nil, logger, SUCCESSFUL, at) {
+		t.Error("update from running to successful was rejected")
+	}
+	if !j.LastUpdate().Equal(at) {
+		t.Errorf("last update = %s, want %s", j.LastUpdate(), at)
+	}
+}
+
+func TestDismissQueued(t *testing.T) {
+	db := NewMemoryDB()
+	logger := testLogger()
+
+	j := testJob(ACCEPTED)
+	if !j.jobStatus.dismissQueued(j, db, logger) {
+		t.Fatal("dismissQueued() of a queued job returned false")
+	}
+	if got := j.CurrentStatus(); got != DISMISSED {
+		t.Errorf("status = %s, want %s", got, DISMISSED)
+	}
+	if j.jobStatus.markStarted() {
+		t.Error("markStarted() of a job dismissed while queued returned true")
+	}
+
+	// started jobs may have resources at their provider and must be killed
+	j = testJob(ACCEPTED)
+	if !j.jobStatus.markStarted() {
+		t.Fatal("markStarted() of a queued job returned false")
+	}
+	if j.jobStatus.dismissQueued(j, db, logger) {
+		t.Error("dismissQueued() of a started job returned true")
+	}
+	if got := j.CurrentStatus(); got != ACCEPTED {
+		t.Errorf("status = %s, want %s", got, ACCEPTED)
+	}
+
+	j = testJob(RUNNING)
+	if j.jobStatus.dismissQueued(j, db, logger) {
+		t.Error("dismissQueued() of a running job returned true")
+	}
+}
+
+// A dismiss racing the start of a queued job either dismisses it before it starts or leaves it to be killed
+func TestDismissQueuedConcurrentStart(t *testing.T) {
+	db := NewMemoryDB()
+	logger := testLogger()
+
+	for i := 0; i < 100; i++ {
+		j := testJob(ACCEPTED)
+
+		var started, dismissed bool
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			started = j.jobStatus.markStarted()
+		}()
+		go func() {
+			defer wg.Done()
+			dismissed = j.jobStatus.dismissQueued(j, db, logger)
+		}()
+		wg.Wait()
+
+		if started == dismissed {
+			t.Fatalf("started = %v, dismissed = %v, want exactly one", started, dismissed)
+		}
+	}
+}
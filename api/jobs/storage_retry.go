@@ -0,0 +1,233 @@
+package jobs
+
+import (
+	"app/utils"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/labstack/gommon/log"
+)
+
+// Delay before the first retry of a failed storage write, doubled for every following attempt up to maxRetryBackoff
+const (
+	retryBackoff    = 30 * time.Second
+	maxRetryBackoff = time.Hour
+)
+
+// pendingWrite is a failed storage write persisted to disk so that it survives restarts until it is retried
+type pendingWrite struct {
+	JobID       string `json:"jobID"`
+	Key         string `json:"key"`
+	ContentType string `json:"contentType"`
+	// Data is stored encoded, e.g. gzip compressed
+	ContentEncoding string    `json:"contentEncoding,omitempty"`
+	Data            []byte    `json:"data"`
+	Attempts        int       `json:"attempts"`
+	NextAttempt     time.Time `json:"nextAttempt"`
+}
+
+// Directory pending writes are persisted to, empty means failed writes are not retried.
+// Writes that still fail after maxAttempts are moved to the failed sub directory for operators to inspect.
+var storageRetries = struct {
+	dir         string
+	maxAttempts int
+}{}
+
+// Writes of the same key are serialized, so that a retry can not overwrite a newer write of its key.
+// Locks are shared by keys with the same hash prefix so that their number is bounded.
+var keyLocks [64]sync.Mutex
+
+func lockKey(key string) func() {
+	sum := sha256.Sum256([]byte(key))
+	l := &keyLocks[int(sum[0])%len(keyLocks)]
+	l.Lock()
+	return l.Unlock
+}
+
+// SetStorageRetries enables retrying failed storage writes of job metadata and logs, pending writes are persisted in dir.
+// Must be called at startup before any job is created.
+func SetStorageRetries(dir string, maxAttempts int) error {
+	if dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "failed"), 0755); err != nil {
+		return err
+	}
+	storageRetries.dir = dir
+	storageRetries.maxAttempts = maxAttempts
+	return nil
+}
+
+// Write to storage, persisting the write to be retried later if it fails.
+// Later writes of the same key replace its pending write, or drop it if they succeed.
+// Nothing is written without a storage service, metadata is then not persisted and logs remain on local disk.
+func writeToStorage(svc *s3.S3, jid string, b []byte, key, contType string) error {
+	return storeWithRetry(svc, pendingWrite{JobID: jid, Key: key, ContentType: contType, Data: b})
+}
+
+// Same as writeToStorage but b is written gzip compressed
+func writeGzipToStorage(svc *s3.S3, jid string, b []byte, key, contType string) error {
+	gz, err := utils.Gzip(b)
+	if err != nil {
+		return err
+	}
+	return storeWithRetry(svc, pendingWrite{JobID: jid, Key: key, ContentType: contType, ContentEncoding: "gzip", Data: gz})
+}
+
+func storeWithRetry(svc *s3.S3, pw pendingWrite) error {
+	if svc == nil {
+		return nil
+	}
+	if storageRetries.dir == "" {
+		return utils.WriteEncodedToS3(svc, pw.Data, pw.Key, pw.ContentType, pw.ContentEncoding, 0)
+	}
+
+	unlock := lockKey(pw.Key)
+	defer unlock()
+	err := utils.WriteEncodedToS3(svc, pw.Data, pw.Key, pw.ContentType, pw.ContentEncoding, 0)
+	if err == nil {
+		// an earlier failed write of the key is stale now
+		if rerr := os.Remove(pendingWritePath(pw.Key)); rerr != nil && !os.IsNotExist(rerr) {
+			log.Errorf("Could not remove stale pending write of %s: %s", pw.Key, rerr.Error())
+		}
+		return nil
+	}
+
+	pw.Attempts = 1
+	pw.NextAttempt = time.Now().Add(retryBackoff)
+	if perr := savePendingWrite(pw); perr != nil {
+		log.Errorf("Could not persist failed write of %s for retry: %s", pw.Key, perr.Error())
+	}
+	return err
+}
+
+// Path of the pending write of a storage key
+func pendingWritePath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(storageRetries.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func savePendingWrite(pw pendingWrite) error {
+	b, err := json.Marshal(pw)
+	if err != nil {
+		return err
+	}
+	// write to a temporary file first so that a crash does not leave a partial pending write
+	tmp := pendingWritePath(pw.Key) + ".tmp"
+	if err = os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, pendingWritePath(pw.Key))
+}
+
+// RetryStorageWrites retries pending writes that are due. Writes that fail after the maximum attempts are moved to
+// the failed directory and reported, these need operator attention.
+// Only the key being retried is locked, so that writes of other keys are not held up by slow retries.
+func RetryStorageWrites(svc *s3.S3) {
+	if storageRetries.dir == "" || svc == nil {
+		return
+	}
+
+	paths, err := filepath.Glob(filepath.Join(storageRetries.dir, "*.json"))
+	if err != nil {
+		log.Errorf("Could not list pending storage writes: %s", err.Error())
+		return
+	}
+
+	for _, path := range paths {
+		pw, err := readPendingWrite(path)
+		if err != nil {
+			log.Errorf("Could not read pending storage write %s: %s", path, err.Error())
+			continue
+		}
+		if time.Now().Before(pw.NextAttempt) {
+			continue
+		}
+		retryPendingWrite(svc, path, pw.Key)
+	}
+}
+
+func readPendingWrite(path string) (pendingWrite, error) {
+	var pw pendingWrite
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return pw, err
+	}
+	err = json.Unmarshal(data, &pw)
+	return pw, err
+}
+
+// Retry the pending write of key at path. The write is read again once the key is locked,
+// it may have been replaced or dropped by a newer write of the key in the meantime.
+func retryPendingWrite(svc *s3.S3, path, key string) {
+	unlock := lockKey(key)
+	defer unlock()
+
+	pw, err := readPendingWrite(path)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		log.Errorf("Could not read pending storage write %s: %s", path, err.Error())
+		return
+	}
+	if time.Now().Before(pw.NextAttempt) {
+		return
+	}
+
+	// storage workers are not used, retries run one at a time
+	err = utils.WriteEncodedToS3(svc, pw.Data, pw.Key, pw.ContentType, pw.ContentEncoding, 0)
+	if err == nil {
+		log.Infof("Wrote %s of job %s to storage after %d failed attempts", pw.Key, pw.JobID, pw.Attempts)
+		os.Remove(path)
+		return
+	}
+
+	pw.Attempts++
+	if pw.Attempts >= storageRetries.maxAttempts {
+		failedPath := filepath.Join(storageRetries.dir, "failed", filepath.Base(path))
+		if rerr := os.Rename(path, failedPath); rerr != nil {
+			log.Errorf("Could not move pending storage write %s to failed: %s", path, rerr.Error())
+		}
+		log.Errorf("Giving up writing %s of job %s to storage after %d attempts, write kept at %s for operator attention. Error: %s",
+			pw.Key, pw.JobID, pw.Attempts, failedPath, err.Error())
+		return
+	}
+
+	backoff := retryBackoff << (pw.Attempts - 1)
+	if backoff > maxRetryBackoff || backoff <= 0 {
+		backoff = maxRetryBackoff
+	}
+	pw.NextAttempt = time.Now().Add(backoff)
+	if err = savePendingWrite(pw); err != nil {
+		log.Errorf("Could not update pending storage write %s: %s", path, err.Error())
+	}
+}
+
+// StorageRetryStats returns the number of pending storage writes and writes that failed permanently
+func StorageRetryStats() (pending, failed int) {
+	if storageRetries.dir == "" {
+		return 0, 0
+	}
+	count := func(dir string) int {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return 0
+		}
+		var n int
+		for _, e := range entries {
+			if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+				n++
+			}
+		}
+		return n
+	}
+	return count(storageRetries.dir), count(filepath.Join(storageRetries.dir, "failed"))
+}
@@ -0,0 +1,17 @@
+package jobs
+
+// Bounds the number of background metadata writing and log flushing routines that access storage at the same time,
+// so that a burst of finishing jobs does not hit storage all at once.
+var storageWorkers = make(chan struct{}, 10)
+
+// SetStorageWorkers sets the number of background routines that can access storage at the same time.
+// Must be called at startup before any job is created.
+func SetStorageWorkers(n int) {
+	storageWorkers = make(chan struct{}, n)
+}
+
+// Wait for a free storage worker, the returned function must be called to release it
+func acquireStorageWorker() func() {
+	storageWorkers <- struct{}{}
+	return func() { <-storageWorkers }
+}
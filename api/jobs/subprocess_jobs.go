@@ -1,10 +1,10 @@
 package jobs
 
 import (
-	"app/utils"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"sync"
@@ -27,10 +27,24 @@ type SubprocessJob struct {
 	ProcessName    string `json:"processID"`
 	ProcessVersion string `json:"processVersion"`
 	Submitter      string
+	SubmitterID    string
 	EnvVars        []string
 	Cmd            []string `json:"commandOverride"`
-	UpdateTime     time.Time
-	Status         string `json:"status"`
+	jobStatus
+	// Storage location results are written to, passed to the process as OUTPUT_LOCATION
+	OutputLocation string
+	// X-Request-ID of the execution request that created the job
+	RequestID string
+	// Custom fields of the execution request, written to the metadata document
+	Metadata map[string]interface{}
+	// Results are checked against output schemas of the process before the job is marked successful, nil skips the check
+	ResultsCheck *ResultsCheck
+	// Directory of staged file inputs, removed when the job closes
+	InputsDir string
+	// Href inputs downloaded to InputsDir when the job starts
+	Downloads []InputDownload
+	// Order in which jobs held by the paused scheduler are started, nil is the lowest priority
+	Priority *int
 
 	execCmd *exec.Cmd
 
@@ -63,6 +77,10 @@ func (j *SubprocessJob) SUBMITTER() string {
 	return j.Submitter
 }
 
+func (j *SubprocessJob) SUBMITTERID() string {
+	return j.SubmitterID
+}
+
 func (j *SubprocessJob) CMD() []string {
 	return j.Cmd
 }
@@ -84,30 +102,8 @@ func (j *SubprocessJob) LogMessage(m string, level log.Level) {
 	}
 }
 
-func (j *SubprocessJob) LastUpdate() time.Time {
-	return j.UpdateTime
-}
-
-func (j *SubprocessJob) NewStatusUpdate(status string, updateTime time.Time) {
-
-	// If old status is one of the terminated status, it should not update status.
-	switch j.Status {
-	case SUCCESSFUL, DISMISSED, FAILED:
-		return
-	}
-
-	j.Status = status
-	if updateTime.IsZero() {
-		j.UpdateTime = time.Now()
-	} else {
-		j.UpdateTime = updateTime
-	}
-	j.DB.updateJobRecord(j.UUID, status, j.UpdateTime)
-	j.logger.Infof("Status changed to %s.", status)
-}
-
-func (j *SubprocessJob) CurrentStatus() string {
-	return j.Status
+func (j *SubprocessJob) NewStatusUpdate(status string, updateTime time.Time) bool {
+	return j.jobStatus.update(j, j.DB, j.ResultsCheck, j.logger, status, updateTime)
 }
 
 func (j *SubprocessJob) ProviderID() string {
@@ -156,6 +152,9 @@ func (j *SubprocessJob) Create() error {
 	if err != nil {
 		return err
 	}
+	if j.RequestID != "" {
+		j.logger.Infof("Request ID: %s", j.RequestID)
+	}
 	j.logger.Info("Subprocess Commands: ", j.CMD())
 
 	ctx, cancelFunc := context.WithCancel(context.TODO())
@@ -163,7 +162,7 @@ func (j *SubprocessJob) Create() error {
 	j.ctxCancel = cancelFunc
 
 	// At this point job is ready to be added to database
-	err = j.DB.addJob(j.UUID, "accepted", "", "local", j.ProcessName, j.Submitter, time.Now())
+	err = j.DB.addJob(j.UUID, "accepted", "", "local", j.ProcessName, j.Submitter, j.SubmitterID, time.Now())
 	if err != nil {
 		j.ctxCancel()
 		return err
@@ -195,6 +194,22 @@ func (j *SubprocessJob) Run() {
 		}
 	}()
 
+	if !waitForScheduler(j.ctx, j.logger, j.Priority) {
+		return
+	}
+	if !j.jobStatus.markStarted() {
+		j.logger.Info("Job was dismissed while queued, it is not started.")
+		// Kill closes the job, wait for it so that it is not closed twice
+		<-j.ctx.Done()
+		return
+	}
+
+	if err := downloadInputs(j.ctx, j.Downloads, j.logger); err != nil {
+		j.logger.Errorf("Could not stage inputs. Error: %s", err.Error())
+		j.NewStatusUpdate(FAILED, time.Time{})
+		return
+	}
+
 	// Prepare the command
 	j.execCmd = exec.CommandContext(j.ctx, j.Cmd[0], j.Cmd[1:]...)
 	j.execCmd.Env = append(os.Environ(), j.EnvVars...)
@@ -208,9 +223,25 @@ func (j *SubprocessJob) Run() {
 	}
 	defer logFile.Close()
 
-	// Redirect stdout and stderr to the log file
-	j.execCmd.Stdout = logFile
-	j.execCmd.Stderr = logFile
+	stdoutFile, err := os.Create(fmt.Sprintf("%s/%s.stdout.jsonl", os.Getenv("TMP_JOB_LOGS_DIR"), j.UUID))
+	if err != nil {
+		j.logger.Errorf("Failed to create log file: %s", err.Error())
+		j.NewStatusUpdate(FAILED, time.Time{})
+		return
+	}
+	defer stdoutFile.Close()
+
+	stderrFile, err := os.Create(fmt.Sprintf("%s/%s.stderr.jsonl", os.Getenv("TMP_JOB_LOGS_DIR"), j.UUID))
+	if err != nil {
+		j.logger.Errorf("Failed to create log file: %s", err.Error())
+		j.NewStatusUpdate(FAILED, time.Time{})
+		return
+	}
+	defer stderrFile.Close()
+
+	// Redirect stdout and stderr to the combined log file and to a log file of their own
+	j.execCmd.Stdout = io.MultiWriter(logFile, stdoutFile)
+	j.execCmd.Stderr = io.MultiWriter(logFile, stderrFile)
 
 	// Start the command
 	err = j.execCmd.Start()
@@ -239,20 +270,24 @@ func (j *SubprocessJob) Run() {
 	}
 
 	j.logger.Info("Subprocess finished successfully.")
-	j.NewStatusUpdate(SUCCESSFUL, time.Time{})
-	go j.WriteMetaData()
+	if j.NewStatusUpdate(SUCCESSFUL, time.Time{}) {
+		go j.WriteMetaData()
+	}
 }
 
 // Kill subprocess
 func (j *SubprocessJob) Kill() error {
 	j.logger.Info("Received dismiss signal.")
-	switch j.CurrentStatus() {
-	case SUCCESSFUL, FAILED, DISMISSED:
-		// if these jobs have been loaded from previous snapshot they would not have context etc
+	if j.jobStatus.dismissQueued(j, j.DB, j.logger) {
+		// nothing was started, closing only removes the job from the queue and active jobs
+		j.logger.Info("Job dismissed before it started, there is nothing to stop.")
+		go j.Close()
+		return nil
+	}
+	// fails if the job is already completed, failed, or dismissed, or was terminated concurrently
+	if !j.NewStatusUpdate(DISMISSED, time.Time{}) {
 		return fmt.Errorf("can't call delete on an already completed, failed, or dismissed job")
 	}
-
-	j.NewStatusUpdate(DISMISSED, time.Time{})
 	// If a dismiss status is updated the job is considered dismissed at this point
 	// Close being graceful or not does not matter.
 
@@ -268,16 +303,28 @@ func (j *SubprocessJob) WriteMetaData() {
 	j.wg.Add(1)
 	defer j.wg.Done()
 	defer j.logger.Info("Finished metadata writing routine.")
+	release := acquireStorageWorker()
+	defer release()
 
 	p := process{j.ProcessID(), j.ProcessVersionID()}
+	var usage *resourceUsage
+	if j.execCmd != nil && j.execCmd.ProcessState != nil {
+		ps := j.execCmd.ProcessState
+		usage = &resourceUsage{CPUTime: (ps.UserTime() + ps.SystemTime()).Seconds()}
+	}
 	md := metaData{
 		Context:         "https://github.com/Dewberry/process-api/blob/main/context.jsonld",
 		JobID:           j.UUID,
 		Process:         p,
 		Commands:        j.Cmd,
-		GeneratedAtTime: j.UpdateTime,
-		StartedAtTime:   j.UpdateTime,
-		EndedAtTime:     j.UpdateTime,
+		OutputLocation:  j.OutputLocation,
+		RequestID:       j.RequestID,
+		Custom:          j.Metadata,
+		GeneratedAtTime: j.LastUpdate(),
+		StartedAtTime:   j.LastUpdate(),
+		EndedAtTime:     j.LastUpdate(),
+		ResourceUsage:   usage,
+		Results:         metaDataResultsIntegrity(j, j.StorageSvc),
 	}
 
 	jsonBytes, err := json.Marshal(md)
@@ -288,9 +335,9 @@ func (j *SubprocessJob) WriteMetaData() {
 
 	metadataDir := os.Getenv("STORAGE_METADATA_PREFIX")
 	mdLocation := fmt.Sprintf("%s/%s.json", metadataDir, j.UUID)
-	err = utils.WriteToS3(j.StorageSvc, jsonBytes, mdLocation, "application/json", 0)
+	err = writeToStorage(j.StorageSvc, j.UUID, jsonBytes, mdLocation, "application/json")
 	if err != nil {
-		return
+		j.logger.Errorf("Error writing metadata, write will be retried: %s", err.Error())
 	}
 }
 
@@ -314,6 +361,7 @@ func (j *SubprocessJob) Close() {
 	// 	}
 	// }
 
+	removeStagedInputs(j.InputsDir, j.logger)
 	j.DoneChan <- j // At this point job can be safely removed from active jobs
 
 	go func() {
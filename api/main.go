@@ -4,6 +4,7 @@ import (
 	"app/auth"
 	_ "app/docs"
 	"app/handlers"
+	"app/processes"
 	"fmt"
 	"path/filepath"
 	"strconv"
@@ -114,6 +115,79 @@ func initLogger() (log.Level, *lumberjack.Logger) {
 	return lvl, logWriter
 }
 
+// Default timeouts of routes that do not use REQUEST_TIMEOUT, 0 means no timeout. Sync execution waits for the job
+// to finish, batch execution creates many jobs and result downloads may stream large files. Overridden by REQUEST_TIMEOUTS.
+var defaultRouteTimeouts = map[string]time.Duration{
+	"/processes/:processID/execution":       0,
+	"/processes/:processID/execution/batch": 0,
+	"/execution":                            0,
+	"/jobs/:jobID/results/:outputID":        0,
+}
+
+// Parse comma separated `route=duration` pairs, such as `/jobs/:jobID/logs=5m`, over the default route timeouts
+func parseRouteTimeouts(s string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration, len(defaultRouteTimeouts))
+	for route, d := range defaultRouteTimeouts {
+		timeouts[route] = d
+	}
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		route, val, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("'%s' must be in the form route=duration", pair)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(val))
+		if err != nil {
+			return nil, fmt.Errorf("route %s: %s", route, err.Error())
+		}
+		timeouts[strings.TrimSpace(route)] = d
+	}
+	return timeouts, nil
+}
+
+// Cancel requests that take longer than the timeout of their route with a 503, routes without one use defaultTimeout.
+// Long lived responses such as event streams, ZIP archives and NDJSON results are not timed out,
+// they are flushed as they are written which the timeout handler writer does not support.
+func requestTimeoutMiddleware(defaultTimeout time.Duration, routeTimeouts map[string]time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		// one timeout handler per distinct duration
+		timed := make(map[time.Duration]echo.HandlerFunc)
+		for _, d := range routeTimeouts {
+			timed[d] = nil
+		}
+		timed[defaultTimeout] = nil
+		for d := range timed {
+			if d > 0 {
+				timed[d] = middleware.TimeoutWithConfig(middleware.TimeoutConfig{
+					ErrorMessage: `{"message":"request timed out"}`,
+					Timeout:      d,
+				})(next)
+			}
+		}
+
+		return func(c echo.Context) error {
+			accept := c.Request().Header.Get(echo.HeaderAccept)
+			if strings.Contains(accept, "text/event-stream") {
+				return next(c)
+			}
+			if c.Path() == "/jobs/:jobID/results" && (strings.Contains(accept, "application/zip") || strings.Contains(accept, "application/x-ndjson")) {
+				return next(c)
+			}
+
+			d, ok := routeTimeouts[c.Path()]
+			if !ok {
+				d = defaultTimeout
+			}
+			if h := timed[d]; h != nil {
+				return h(c)
+			}
+			return next(c)
+		}
+	}
+}
+
 const (
 	authLevelNone    = 0
 	authLevelPartial = 1
@@ -150,6 +224,11 @@ func initAuth(e *echo.Echo, protected *echo.Group) int {
 			if err != nil {
 				log.Fatalf("Error creating KeyCloak auth service: %s", err.Error())
 			}
+		case "oidc":
+			as, err = auth.NewOIDCAuthStrategy()
+			if err != nil {
+				log.Fatalf("Error creating OIDC auth service: %s", err.Error())
+			}
 		default:
 			log.Fatal("unsupported auth service provider type")
 		}
@@ -197,16 +276,11 @@ func copyPlugins(dstDir string) error {
 		return fmt.Errorf("specified directory to load plugins from does not exist: %s", pluginsLoadDir)
 	}
 
-	// Match only .yml and .yaml files one level down
-	ymls, err := filepath.Glob(fmt.Sprintf("%s/*/*.yml", pluginsLoadDir))
-	if err != nil {
-		return err
-	}
-	yamls, err := filepath.Glob(fmt.Sprintf("%s/*/*.yaml", pluginsLoadDir))
+	// Match only .yml, .yaml and .json files one level down
+	allYamls, err := processes.ProcessFiles(pluginsLoadDir)
 	if err != nil {
 		return err
 	}
-	allYamls := append(ymls, yamls...)
 
 	for _, srcFile := range allYamls {
 		fileName := filepath.Base(srcFile)
@@ -262,6 +336,31 @@ func main() {
 	// Goroutines
 	go rh.StatusUpdateRoutine()
 	go rh.JobCompletionRoutine()
+	go rh.TerminalJobsPurgeRoutine()
+	go rh.StuckJobsRoutine()
+	go rh.StorageRetryRoutine()
+	if rh.Config.FailedContainerTTL > 0 {
+		go rh.FailedContainersCleanupRoutine()
+	}
+	if rh.Config.ProcessRegistryURL != "" {
+		go rh.ProcessRegistryRoutine()
+	}
+	if rh.Config.JobArchiveAfter > 0 || rh.Config.JobArchiveMaxJobs > 0 {
+		go rh.JobArchiveRoutine()
+	}
+	if rh.Config.PrepullImages {
+		go rh.PrepullImages()
+	}
+
+	switch reconcile := resolveValue("STORAGE_RECONCILE", ""); reconcile {
+	case "":
+	case "report":
+		go rh.ReconcileStorage(false)
+	case "cleanup":
+		go rh.ReconcileStorage(true)
+	default:
+		log.Fatalf("Invalid STORAGE_RECONCILE: %s, must be one of ['', 'report', 'cleanup']", reconcile)
+	}
 
 	// Set server configuration
 	e := echo.New()
@@ -276,6 +375,16 @@ func main() {
 	}))
 	e.Renderer = &rh.T
 
+	reqTimeout, err := time.ParseDuration(resolveValue("REQUEST_TIMEOUT", "60s"))
+	if err != nil {
+		log.Fatalf("Error parsing REQUEST_TIMEOUT: %s", err.Error())
+	}
+	routeTimeouts, err := parseRouteTimeouts(resolveValue("REQUEST_TIMEOUTS", ""))
+	if err != nil {
+		log.Fatalf("Error parsing REQUEST_TIMEOUTS: %s", err.Error())
+	}
+	e.Use(requestTimeoutMiddleware(reqTimeout, routeTimeouts))
+
 	// Create a group for all routes that need to be protected when AUTH_LEVEL = protected
 	pg := e.Group("")
 	authLvl := initAuth(e, pg)
@@ -285,28 +394,48 @@ func main() {
 	e.GET("/", rh.LandingPage)
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 	e.GET("/conformance", rh.Conformance)
+	e.GET("/providers", rh.ProvidersHandler)
+	pg.GET("/metrics", rh.MetricsHandler)
+	pg.GET("/stats", rh.StatsHandler)
 
 	// Processes
 	e.GET("/processes", rh.ProcessListHandler)
 	e.GET("/processes/:processID", rh.ProcessDescribeHandler)
+	e.GET("/processes/:processID/inputs/schema", rh.ProcessInputsSchemaHandler)
+	pg.GET("/processes/:processID/definition", rh.ProcessDefinitionHandler)
 	pg.POST("/processes/:processID", rh.AddProcessHandler)
 	pg.PUT("/processes/:processID", rh.UpdateProcessHandler)
 	pg.DELETE("/processes/:processID", rh.DeleteProcessHandler)
 
-	pg.POST("/processes/:processID/execution", rh.Execution)
+	pg.POST("/processes/:processID/execution", rh.Execution, handlers.ValidateExecuteRequest)
+	pg.POST("/processes/:processID/execution/batch", rh.BatchExecution, handlers.ValidateBatchExecuteRequest)
+	if rh.Config.DefaultProcess != "" {
+		pg.POST("/execution", rh.DefaultExecution, handlers.ValidateExecuteRequest)
+	}
 
 	// TODO
 	// pg.Post("processes/:processID/new, rh.RegisterNewProcess)
 	// pg.Delete("processes/:processID", rh.RegisterNewProcess)
 
-	// Jobs
-	e.GET("/jobs", rh.ListJobsHandler) // changed for hotfix, should be pg.GET when clients are updated
-	e.GET("/jobs/:jobID", rh.JobStatusHandler)
-	e.GET("/jobs/:jobID/results", rh.JobResultsHandler)
-	e.GET("/jobs/:jobID/logs", rh.JobLogsHandler)
-	e.GET("/jobs/:jobID/metadata", rh.JobMetaDataHandler)
+	// Jobs are scoped to their submitter, so the user headers must be verified
+	pg.GET("/jobs", rh.ListJobsHandler)
+	pg.GET("/jobs/:jobID", rh.JobStatusHandler)
+	pg.HEAD("/jobs/:jobID", rh.JobStatusHeadHandler)
+	pg.GET("/jobs/:jobID/results", rh.JobResultsHandler)
+	pg.HEAD("/jobs/:jobID/results", rh.JobResultsHeadHandler)
+	pg.GET("/jobs/:jobID/results/:outputID", rh.JobResultDownloadHandler)
+	pg.GET("/jobs/:jobID/logs", rh.JobLogsHandler)
+	pg.GET("/jobs/:jobID/metadata", rh.JobMetaDataHandler)
+	pg.GET("/batches/:batchID", rh.BatchStatusHandler)
 	pg.DELETE("/jobs/:jobID", rh.JobDismissHandler)
 
+	// Admin
+	pg.DELETE("/admin/provider-jobs/:providerID", rh.ProviderJobKillHandler)
+	pg.GET("/admin/providers/:providerType/jobs", rh.ProviderJobsHandler)
+	pg.DELETE("/admin/providers/:providerType/jobs", rh.ProviderJobsHandler)
+	pg.GET("/admin/scheduler", rh.SchedulerHandler)
+	pg.POST("/admin/scheduler/:action", rh.SchedulerHandler)
+
 	// Callbacks
 	pg.PUT("/jobs/:jobID/status", rh.JobStatusUpdateHandler)
 	// e.POST("/jobs/:jobID/results", rh.JobResultsUpdateHandler)
@@ -317,6 +446,24 @@ func main() {
 		Output: lw,
 	}))
 
+	// X-Request-ID of the request is kept, or generated if absent, and echoed in the response
+	e.Use(middleware.RequestID())
+
+	accessLog, err := strconv.ParseBool(resolveValue("ACCESS_LOG", "false"))
+	if err != nil {
+		log.Fatalf("Error parsing ACCESS_LOG: %s", err.Error())
+	}
+	if accessLog {
+		logInputs, err := strconv.ParseBool(resolveValue("ACCESS_LOG_INPUTS", "false"))
+		if err != nil {
+			log.Fatalf("Error parsing ACCESS_LOG_INPUTS: %s", err.Error())
+		}
+		e.Use(handlers.AccessLog(handlers.AccessLogConfig{
+			LogInputs:  logInputs,
+			RedactKeys: strings.Split(resolveValue("ACCESS_LOG_REDACT_KEYS", "password,secret,token,key"), ","),
+		}))
+	}
+
 	// Start server
 	go func() {
 		log.Info("server starting on port: ", port)
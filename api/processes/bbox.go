@@ -0,0 +1,141 @@
+package processes
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CRS of bounding boxes that do not specify one, longitude/latitude order
+const CRS84 = "http://www.opengis.net/def/crs/OGC/1.3/CRS84"
+
+const epsg4326 = "http://www.opengis.net/def/crs/EPSG/0/4326"
+
+// Spellings of the geographic CRSs that are normalized to CRS84
+var crsAliases = map[string]string{
+	"crs84":                      CRS84,
+	"ogc:crs84":                  CRS84,
+	strings.ToLower(CRS84):       CRS84,
+	"epsg:4326":                  epsg4326,
+	strings.ToLower(epsg4326):    epsg4326,
+	"urn:ogc:def:crs:epsg::4326": epsg4326,
+}
+
+// BBox is the normalized representation of a bounding box input passed to processes.
+// Coordinates are ordered as [minX, minY, maxX, maxY] or [minX, minY, minZ, maxX, maxY, maxZ].
+type BBox struct {
+	BBox []float64 `json:"bbox"`
+	CRS  string    `json:"crs"`
+}
+
+// NormalizeBBoxInputs validates inputs having bbox data type and replaces them with their normalized representation.
+// Bounding boxes can be provided as an array of 4 or 6 numbers or as an object {"bbox": [...], "crs": "..."}.
+// CRS defaults to CRS84, and must be CRS84, EPSG:4326 or one of the supportedCRS of the input.
+// EPSG:4326 bounding boxes are in latitude/longitude order and are converted to CRS84.
+// Geographic bounding boxes crossing the antimeridian are given with minX greater than maxX.
+func (p Process) NormalizeBBoxInputs(inputs map[string]interface{}) error {
+	for _, def := range p.Inputs {
+		if !strings.EqualFold(def.Input.LiteralDataDomain.DataType, "bbox") {
+			continue
+		}
+		val, ok := inputs[def.ID]
+		if !ok {
+			continue
+		}
+
+		// a list of bounding boxes unless it is a single array of coordinates
+		if list, ok := val.([]interface{}); ok && len(list) > 0 && !isNumber(list[0]) {
+			normalized := make([]interface{}, len(list))
+			for i, item := range list {
+				bb, err := normalizeBBox(item, def.Input.LiteralDataDomain.SupportedCRS)
+				if err != nil {
+					return fmt.Errorf("input %s[%d]: %s", def.ID, i, err.Error())
+				}
+				normalized[i] = bb
+			}
+			inputs[def.ID] = normalized
+			continue
+		}
+
+		bb, err := normalizeBBox(val, def.Input.LiteralDataDomain.SupportedCRS)
+		if err != nil {
+			return fmt.Errorf("input %s: %s", def.ID, err.Error())
+		}
+		inputs[def.ID] = bb
+	}
+	return nil
+}
+
+func normalizeBBox(val interface{}, supportedCRS []string) (BBox, error) {
+	var coords interface{}
+	crs := CRS84
+
+	switch v := val.(type) {
+	case []interface{}:
+		coords = v
+	case map[string]interface{}:
+		coords = v["bbox"]
+		if c, ok := v["crs"]; ok {
+			s, ok := c.(string)
+			if !ok || s == "" {
+				return BBox{}, fmt.Errorf("crs must be a string")
+			}
+			crs = s
+		}
+	default:
+		return BBox{}, fmt.Errorf("bbox must be an array of 4 or 6 numbers or an object with bbox and crs")
+	}
+
+	arr, ok := coords.([]interface{})
+	if !ok || (len(arr) != 4 && len(arr) != 6) {
+		return BBox{}, fmt.Errorf("bbox must be an array of 4 or 6 numbers")
+	}
+	bbox := make([]float64, len(arr))
+	for i, c := range arr {
+		f, ok := c.(float64)
+		if !ok {
+			return BBox{}, fmt.Errorf("bbox must be an array of 4 or 6 numbers")
+		}
+		bbox[i] = f
+	}
+
+	dims := len(bbox) / 2
+	if canonical, ok := crsAliases[strings.ToLower(crs)]; ok {
+		crs = canonical
+	} else if !containsFold(supportedCRS, crs) {
+		return BBox{}, fmt.Errorf("unsupported crs %s, must be one of %v", crs, append([]string{CRS84, epsg4326}, supportedCRS...))
+	}
+
+	if crs == epsg4326 {
+		// latitude/longitude to longitude/latitude
+		bbox[0], bbox[1] = bbox[1], bbox[0]
+		bbox[dims], bbox[dims+1] = bbox[dims+1], bbox[dims]
+		crs = CRS84
+	}
+
+	// geographic bounding boxes crossing the antimeridian have a minimum longitude greater than the maximum
+	for i := 0; i < dims; i++ {
+		if bbox[i] > bbox[i+dims] && !(i == 0 && crs == CRS84) {
+			return BBox{}, fmt.Errorf("bbox lower corner must be before upper corner, got %v", bbox)
+		}
+	}
+
+	if crs == CRS84 && (bbox[0] < -180 || bbox[dims] > 180 || bbox[1] < -90 || bbox[dims+1] > 90) {
+		return BBox{}, fmt.Errorf("bbox %v is out of the bounds of %s, longitude must be within [-180, 180] and latitude within [-90, 90]", bbox, crs)
+	}
+
+	return BBox{BBox: bbox, CRS: crs}, nil
+}
+
+func isNumber(v interface{}) bool {
+	_, ok := v.(float64)
+	return ok
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package processes
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"text/template/parse"
+)
+
+// Each argument of a command template is a Go text/template rendered per job. Templates have access to .jobID,
+// .inputs keyed by input id and the toJSON function for object and array inputs. Inputs that are not provided
+// render as their default value, or empty if there is none.
+func (p Process) parseCommandTemplate() ([]*template.Template, error) {
+	tmpls := make([]*template.Template, len(p.CommandTemplate))
+	for i, arg := range p.CommandTemplate {
+		tmpl, err := template.New(fmt.Sprintf("commandTemplate[%d]", i)).Funcs(resultHookFuncs).Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return nil, err
+		}
+		tmpls[i] = tmpl
+	}
+	return tmpls, nil
+}
+
+// Check that command template arguments parse and only reference inputs of the process
+func (p Process) validateCommandTemplate() error {
+	tmpls, err := p.parseCommandTemplate()
+	if err != nil {
+		return err
+	}
+
+	inputIDs := make(map[string]bool, len(p.Inputs))
+	for _, inp := range p.Inputs {
+		inputIDs[inp.ID] = true
+	}
+
+	for i, tmpl := range tmpls {
+		var err error
+		walkFields(tmpl.Tree.Root, func(ident []string) {
+			if err != nil {
+				return
+			}
+			switch {
+			case ident[0] == "jobID" && len(ident) == 1:
+			case ident[0] == "inputs" && len(ident) > 1:
+				if !inputIDs[ident[1]] {
+					err = fmt.Errorf("commandTemplate[%d] references input %s which is not an input of the process", i, ident[1])
+				}
+			default:
+				err = fmt.Errorf("commandTemplate[%d] references .%s, only .jobID and .inputs.<id> are available", i, ident[0])
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Call fn with the identifiers of every field referenced in the template tree, e.g. [inputs region] for .inputs.region
+func walkFields(node parse.Node, fn func(ident []string)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			walkFields(c, fn)
+		}
+	case *parse.ActionNode:
+		walkFields(n.Pipe, fn)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Cmds {
+			walkFields(c, fn)
+		}
+	case *parse.CommandNode:
+		for _, a := range n.Args {
+			walkFields(a, fn)
+		}
+	case *parse.FieldNode:
+		fn(n.Ident)
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" && len(n.Ident) > 1 {
+			fn(n.Ident[1:])
+		}
+	case *parse.ChainNode:
+		walkFields(n.Node, fn)
+	case *parse.IfNode:
+		walkFields(n.Pipe, fn)
+		walkFields(n.List, fn)
+		walkFields(n.ElseList, fn)
+	// dot is rebound in the body of range and with, so fields there are not checked
+	case *parse.RangeNode:
+		walkFields(n.Pipe, fn)
+		walkFields(n.ElseList, fn)
+	case *parse.WithNode:
+		walkFields(n.Pipe, fn)
+		walkFields(n.ElseList, fn)
+	}
+}
+
+// RenderCommandTemplate renders the command template of the process for a job, the arguments are appended to its command
+func (p Process) RenderCommandTemplate(jobID string, inputs map[string]interface{}) ([]string, error) {
+	tmpls, err := p.parseCommandTemplate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid command template: %s", err.Error())
+	}
+
+	values := make(map[string]interface{}, len(p.Inputs))
+	for _, inp := range p.Inputs {
+		if v, ok := inputs[inp.ID]; ok {
+			values[inp.ID] = v
+		} else if inp.Input.LiteralDataDomain.DefaultValue != nil {
+			values[inp.ID] = inp.Input.LiteralDataDomain.DefaultValue
+		} else {
+			values[inp.ID] = ""
+		}
+	}
+	data := map[string]interface{}{
+		"jobID":  jobID,
+		"inputs": values,
+	}
+
+	args := make([]string, len(tmpls))
+	for i, tmpl := range tmpls {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("could not render command template: %s", err.Error())
+		}
+		args[i] = buf.String()
+	}
+	return args, nil
+}
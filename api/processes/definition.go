@@ -0,0 +1,105 @@
+package processes
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "REDACTED"
+
+// Keys whose string values are redacted from definitions
+var secretKey = regexp.MustCompile(`(?i)(secret|password|passwd|token|credential|api[_-]?key)`)
+
+// References to secrets in AWS, these are redacted even though they are not the secret itself
+var secretReferencePrefixes = []string{"arn:aws:secretsmanager:", "arn:aws:ssm:"}
+
+// Definition returns the process as loaded, with the same field names as yaml definitions.
+// Unlike the description it includes host and config of the process, such as resources resolved from an AWS Batch
+// job definition. Values of secret-like keys, secret references and passwords of URLs are redacted.
+func (p Process) Definition() (map[string]interface{}, error) {
+	b, err := yaml.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	var def map[string]interface{}
+	if err = yaml.Unmarshal(b, &def); err != nil {
+		return nil, err
+	}
+	redactSecrets(def)
+	return def, nil
+}
+
+func redactSecrets(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			if _, ok := item.(string); ok && secretKey.MatchString(k) {
+				val[k] = redacted
+				continue
+			}
+			val[k] = redactSecrets(item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = redactSecrets(item)
+		}
+	case string:
+		return redactString(val)
+	}
+	return v
+}
+
+func redactString(s string) string {
+	for _, prefix := range secretReferencePrefixes {
+		if strings.HasPrefix(s, prefix) {
+			return redacted
+		}
+	}
+	if u, err := url.Parse(s); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	return s
+}
+
+// RedactCommand returns a copy of command arguments with secrets redacted, using the same rules as Definition.
+// Values of secret-like flags, given as `--flag=value`, `KEY=value` or as the argument after the flag, are redacted.
+// JSON object arguments, such as inputs appended to the command, have values of secret-like keys redacted.
+func RedactCommand(args []string) []string {
+	redactedArgs := make([]string, len(args))
+	for i, arg := range args {
+		switch {
+		case i > 0 && isSecretFlag(args[i-1]):
+			redactedArgs[i] = redacted
+		case strings.HasPrefix(arg, "{"):
+			redactedArgs[i] = redactJSONArg(arg)
+		default:
+			if name, _, ok := strings.Cut(arg, "="); ok && secretKey.MatchString(name) {
+				redactedArgs[i] = name + "=" + redacted
+				continue
+			}
+			redactedArgs[i] = redactString(arg)
+		}
+	}
+	return redactedArgs
+}
+
+// Flag without a value whose next argument is a secret, ex: --password
+func isSecretFlag(arg string) bool {
+	return strings.HasPrefix(arg, "-") && !strings.Contains(arg, "=") && secretKey.MatchString(arg)
+}
+
+func redactJSONArg(arg string) string {
+	var v map[string]interface{}
+	if err := json.Unmarshal([]byte(arg), &v); err != nil {
+		return redactString(arg)
+	}
+	b, err := json.Marshal(redactSecrets(v))
+	if err != nil {
+		return redacted
+	}
+	return string(b)
+}
@@ -0,0 +1,140 @@
+package processes
+
+import "strings"
+
+// JSON Schema types for input data types
+var jsonSchemaTypes = map[string]string{
+	"string":  "string",
+	"integer": "integer",
+	"int":     "integer",
+	"number":  "number",
+	"float":   "number",
+	"double":  "number",
+	"boolean": "boolean",
+	"bool":    "boolean",
+	"object":  "object",
+	"array":   "array",
+}
+
+// InputsSchema returns a standalone JSON Schema document describing the inputs object of an execution request.
+// Inputs with minOccurs > 0 are required, inputs that can occur more than once accept a single value or an array of values.
+// Server inputs are left out, clients can not provide them.
+func (p Process) InputsSchema() map[string]interface{} {
+	inputs := p.clientInputs()
+	properties := make(map[string]interface{}, len(inputs))
+	required := make([]string, 0)
+	dependentRequired := make(map[string]interface{})
+	dependentSchemas := make(map[string]interface{})
+
+	for _, i := range inputs {
+		properties[i.ID] = i.schema()
+		if i.MinOccurs > 0 {
+			required = append(required, i.ID)
+		}
+		if len(i.Requires) > 0 {
+			dependentRequired[i.ID] = i.Requires
+		}
+		if len(i.Conflicts) > 0 {
+			dependentSchemas[i.ID] = map[string]interface{}{"not": map[string]interface{}{"anyOf": conflictsSchema(i.Conflicts)}}
+		}
+	}
+
+	schema := map[string]interface{}{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"$id":                  "/processes/" + p.Info.ID + "/inputs/schema",
+		"title":                p.Info.Title,
+		"description":          p.Info.Description,
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+	if len(dependentRequired) > 0 {
+		schema["dependentRequired"] = dependentRequired
+	}
+	if len(dependentSchemas) > 0 {
+		schema["dependentSchemas"] = dependentSchemas
+	}
+	return schema
+}
+
+// Schemas matching objects that have any of the conflicting inputs
+func conflictsSchema(conflicts []string) []interface{} {
+	schemas := make([]interface{}, len(conflicts))
+	for i, c := range conflicts {
+		schemas[i] = map[string]interface{}{"required": []string{c}}
+	}
+	return schemas
+}
+
+func (i Inputs) schema() map[string]interface{} {
+	value := make(map[string]interface{})
+
+	if i.Stage {
+		// staged inputs are references to files that are downloaded when the job starts
+		value["type"] = "object"
+		value["properties"] = map[string]interface{}{
+			"href":     map[string]interface{}{"type": "string", "format": "uri"},
+			"checksum": map[string]interface{}{"type": "string", "pattern": "^(md5|sha256):[0-9a-fA-F]+$"},
+			"type":     map[string]interface{}{"type": "string"},
+		}
+		value["required"] = []string{"href"}
+		if i.MediaType != "" {
+			value["contentMediaType"] = i.MediaType
+		}
+	} else if strings.EqualFold(i.Input.LiteralDataDomain.DataType, "bbox") {
+		coords := map[string]interface{}{
+			"type":  "array",
+			"items": map[string]interface{}{"type": "number"},
+			"oneOf": []interface{}{
+				map[string]interface{}{"minItems": 4, "maxItems": 4},
+				map[string]interface{}{"minItems": 6, "maxItems": 6},
+			},
+		}
+		value["oneOf"] = []interface{}{
+			coords,
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"bbox": coords,
+					"crs":  map[string]interface{}{"type": "string", "default": CRS84},
+				},
+				"required": []string{"bbox"},
+			},
+		}
+	} else {
+		domain := i.Input.LiteralDataDomain
+		if t, ok := jsonSchemaTypes[strings.ToLower(domain.DataType)]; ok {
+			value["type"] = t
+		}
+		if !domain.ValueDefinition.AnyValue && len(domain.ValueDefinition.PossibleValues) > 0 {
+			value["enum"] = domain.ValueDefinition.PossibleValues
+		}
+		if domain.DefaultValue != nil {
+			value["default"] = domain.DefaultValue
+		}
+	}
+
+	var s map[string]interface{}
+	if i.MaxOccurs == 1 {
+		s = value
+	} else {
+		// maxOccurs of 0 means unbounded
+		array := map[string]interface{}{"type": "array", "items": value}
+		if i.MinOccurs > 0 {
+			array["minItems"] = i.MinOccurs
+		}
+		if i.MaxOccurs > 1 {
+			array["maxItems"] = i.MaxOccurs
+		}
+		s = map[string]interface{}{"oneOf": []interface{}{value, array}}
+	}
+
+	if i.Title != "" {
+		s["title"] = i.Title
+	}
+	if i.Description != "" {
+		s["description"] = i.Description
+	}
+	return s
+}
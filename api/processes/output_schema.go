@@ -0,0 +1,220 @@
+package processes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Output schemas are a subset of JSON Schema: type (a name or a list of names), enum, properties, required,
+// additionalProperties (boolean only) and items. Other keywords are ignored.
+var schemaTypes = map[string]bool{
+	"string": true, "number": true, "integer": true, "boolean": true, "object": true, "array": true, "null": true,
+}
+
+// HasOutputSchemas reports if any output of the process declares a schema
+func (p Process) HasOutputSchemas() bool {
+	for _, o := range p.Outputs {
+		if o.Schema != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateOutputs checks results against the schemas of the outputs that declare one.
+// Results must be an object keyed by output id.
+func (p Process) ValidateOutputs(results interface{}) error {
+	if !p.HasOutputSchemas() {
+		return nil
+	}
+	outputs, ok := results.(map[string]interface{})
+	if !ok {
+		return errors.New("results are not an object keyed by output id")
+	}
+	for _, o := range p.Outputs {
+		if o.Schema == nil {
+			continue
+		}
+		v, ok := outputs[o.ID]
+		if !ok {
+			return fmt.Errorf("output %s is missing", o.ID)
+		}
+		if err := validateSchema(o.Schema, v, o.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateSchema(schema map[string]interface{}, v interface{}, path string) error {
+	if t, ok := schema["type"]; ok {
+		var types []interface{}
+		switch tv := t.(type) {
+		case string:
+			types = []interface{}{tv}
+		case []interface{}:
+			types = tv
+		}
+		matched := false
+		for _, name := range types {
+			if s, ok := name.(string); ok && hasSchemaType(s, v) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: expected type %v, found %s", path, t, schemaTypeOf(v))
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if schemaEqual(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value is not one of %v", path, enum)
+		}
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, r := range required {
+				if name, ok := r.(string); ok {
+					if _, ok := val[name]; !ok {
+						return fmt.Errorf("%s: required property %s is missing", path, name)
+					}
+				}
+			}
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			propSchema, ok := properties[k].(map[string]interface{})
+			if !ok {
+				if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+					return fmt.Errorf("%s: property %s is not allowed", path, k)
+				}
+				continue
+			}
+			if err := validateSchema(propSchema, val[k], path+"."+k); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range val {
+				if err := validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func hasSchemaType(name string, v interface{}) bool {
+	switch name {
+	case "integer":
+		f, ok := schemaNumber(v)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := schemaNumber(v)
+		return ok
+	default:
+		return schemaTypeOf(v) == name
+	}
+}
+
+// JSON Schema type name of a decoded JSON or yaml value
+func schemaTypeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	if _, ok := schemaNumber(v); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func schemaNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// Values in schemas are decoded from yaml and results from JSON, so numbers are compared by value
+func schemaEqual(a, b interface{}) bool {
+	if fa, ok := schemaNumber(a); ok {
+		fb, ok := schemaNumber(b)
+		return ok && fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// Check that types declared in a schema are known, nested schemas included
+func checkSchema(schema map[string]interface{}) error {
+	if t, ok := schema["type"]; ok {
+		var types []interface{}
+		switch tv := t.(type) {
+		case string:
+			types = []interface{}{tv}
+		case []interface{}:
+			types = tv
+		default:
+			return fmt.Errorf("type must be a string or a list of strings")
+		}
+		for _, name := range types {
+			if s, ok := name.(string); !ok || !schemaTypes[s] {
+				return fmt.Errorf("unknown type %v", name)
+			}
+		}
+	}
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		for k, ps := range properties {
+			nested, ok := ps.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("property %s: schema must be an object", k)
+			}
+			if err := checkSchema(nested); err != nil {
+				return fmt.Errorf("property %s: %s", k, err.Error())
+			}
+		}
+	}
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		if err := checkSchema(items); err != nil {
+			return fmt.Errorf("items: %s", err.Error())
+		}
+	}
+	return nil
+}
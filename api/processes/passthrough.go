@@ -0,0 +1,46 @@
+package processes
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// DecodeInputNumbers converts numbers of inputs decoded as json.Number to float64.
+// Inputs declared with object data type are left untouched, so that they are passed to the process verbatim,
+// json.Number is marshalled as written in the request, preserving precision of large integers and decimals.
+func (p Process) DecodeInputNumbers(inputs map[string]interface{}) {
+	objectInputs := make(map[string]bool)
+	for _, def := range p.Inputs {
+		if strings.EqualFold(def.Input.LiteralDataDomain.DataType, "object") {
+			objectInputs[def.ID] = true
+		}
+	}
+
+	for id, val := range inputs {
+		if !objectInputs[id] {
+			inputs[id] = decodeNumbers(val)
+		}
+	}
+}
+
+func decodeNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		if f, err := val.Float64(); err == nil {
+			return f
+		}
+		return val.String()
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = decodeNumbers(item)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = decodeNumbers(item)
+		}
+		return val
+	default:
+		return v
+	}
+}
@@ -0,0 +1,79 @@
+package processes
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func passthroughProcess() Process {
+	return Process{Inputs: []Inputs{
+		{ID: "config", Input: Input{LiteralDataDomain: LiteralDataDomain{DataType: "object"}}},
+		{ID: "count", Input: Input{LiteralDataDomain: LiteralDataDomain{DataType: "integer"}}},
+	}}
+}
+
+// Decode a request body the way execution handlers do
+func decodeInputs(t *testing.T, body string) map[string]interface{} {
+	t.Helper()
+	dec := json.NewDecoder(bytes.NewBufferString(body))
+	dec.UseNumber()
+	var inputs map[string]interface{}
+	if err := dec.Decode(&inputs); err != nil {
+		t.Fatalf("decoding inputs: %v", err)
+	}
+	return inputs
+}
+
+func TestDecodeInputNumbersPassesObjectInputsVerbatim(t *testing.T) {
+	// keys are sorted, as objects are marshalled with sorted keys
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{"nested", `{"a":{"b":{"c":[1,2,{"d":[true,null]}]}},"e":[]}`},
+		{"large integers", `{"id":9007199254740993,"ids":[12345678901234567890]}`},
+		{"decimals", `{"x":0.1000000000000000055511151231257827,"y":1e-7,"z":-0}`},
+		{"unicode", `{"accent":"é","emoji":"🌊","name":"Zürich ☃ 東京"}`},
+		{"empty", `{}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputs := decodeInputs(t, `{"config":`+tt.config+`,"count":3}`)
+			passthroughProcess().DecodeInputNumbers(inputs)
+
+			got, err := json.Marshal(inputs["config"])
+			if err != nil {
+				t.Fatalf("marshalling config: %v", err)
+			}
+
+			var want bytes.Buffer
+			if err := json.Compact(&want, []byte(tt.config)); err != nil {
+				t.Fatalf("compacting config: %v", err)
+			}
+			if string(got) != want.String() {
+				t.Errorf("config = %s, want %s", got, want.String())
+			}
+		})
+	}
+}
+
+func TestDecodeInputNumbersConvertsOtherInputs(t *testing.T) {
+	inputs := decodeInputs(t, `{"config":{"n":1},"count":3,"other":[1.5,{"n":2}]}`)
+	passthroughProcess().DecodeInputNumbers(inputs)
+
+	if v, ok := inputs["count"].(float64); !ok || v != 3 {
+		t.Errorf("count = %#v, want float64 3", inputs["count"])
+	}
+	other := inputs["other"].([]interface{})
+	if v, ok := other[0].(float64); !ok || v != 1.5 {
+		t.Errorf("other[0] = %#v, want float64 1.5", other[0])
+	}
+	if v, ok := other[1].(map[string]interface{})["n"].(float64); !ok || v != 2 {
+		t.Errorf("other[1].n = %#v, want float64 2", other[1])
+	}
+	if _, ok := inputs["config"].(map[string]interface{})["n"].(json.Number); !ok {
+		t.Errorf("config.n = %#v, want json.Number", inputs["config"].(map[string]interface{})["n"])
+	}
+}
@@ -1,17 +1,63 @@
 package processes
 
+// Conformance profiles a process description can be tailored to
+const (
+	// Full description, including extensions of this API such as command and resources
+	ProfileProcessDescription = "ogc-process-description"
+	// Only fields of the OGC process description schema, for clients that strictly validate against it
+	ProfileCore = "core"
+)
+
+// DescriptionProfiles are the valid profiles of Describe, the first one is the default
+var DescriptionProfiles = []string{ProfileProcessDescription, ProfileCore}
+
 type processDescription struct {
 	Info    `json:"info"`
 	Command []string  `json:"command,omitempty"`
 	Inputs  []Inputs  `json:"inputs"`
 	Outputs []Outputs `json:"outputs"`
 	Links   []Link    `json:"links"`
+	// Resources jobs get by default, these inform clients about the expected cost of the process
+	Resources *Resources `json:"resources,omitempty"`
 }
 
-func (p Process) Describe() (processDescription, error) {
+// Describe the process for the given profile, empty profile means the default profile.
+// Profile must be one of DescriptionProfiles.
+func (p Process) Describe(profile string) (processDescription, error) {
+	if profile == ProfileCore {
+		return p.describeCore(), nil
+	}
+
+	resources := p.DefaultResources()
 	pd := processDescription{
-		Info: p.Info, Command: p.Command, Inputs: p.Inputs, Outputs: p.Outputs,
+		Info: p.Info, Command: p.Command, Inputs: p.clientInputs(), Outputs: p.Outputs, Resources: &resources,
 	} // Links: p.createLinks()
 
+	pd.Links = make([]Link, 0, len(p.Info.Aliases))
+	for _, alias := range p.Info.Aliases {
+		pd.Links = append(pd.Links, Link{Href: "/processes/" + alias, Rel: "alternate", Title: "alias"})
+	}
+
 	return pd, nil
 }
+
+// Description without the extensions of this API
+func (p Process) describeCore() processDescription {
+	info := Info{
+		Version: p.Info.Version, ID: p.Info.ID, Title: p.Info.Title, Description: p.Info.Description,
+		JobControlOptions: p.Info.JobControlOptions, OutputTransmission: p.Info.OutputTransmission, Keywords: p.Info.Keywords,
+	}
+
+	clientInputs := p.clientInputs()
+	inputs := make([]Inputs, len(clientInputs))
+	for i, in := range clientInputs {
+		inputs[i] = Inputs{ID: in.ID, Title: in.Title, Description: in.Description, Input: in.Input, MinOccurs: in.MinOccurs, MaxOccurs: in.MaxOccurs}
+	}
+
+	outputs := make([]Outputs, len(p.Outputs))
+	for i, out := range p.Outputs {
+		outputs[i] = Outputs{ID: out.ID, Title: out.Title, Description: out.Description, Output: out.Output}
+	}
+
+	return processDescription{Info: info, Inputs: inputs, Outputs: outputs, Links: []Link{}}
+}
@@ -4,10 +4,19 @@ package processes
 
 import (
 	"app/controllers"
+	"app/utils"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"mime"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/labstack/gommon/log"
 	"gopkg.in/yaml.v3"
@@ -20,6 +29,26 @@ type Process struct {
 	Config  Config    `yaml:"config" json:"cofig"`
 	Inputs  []Inputs  `yaml:"inputs" json:"inputs"`
 	Outputs []Outputs `yaml:"outputs" json:"outputs"`
+	// OutputsSource "stdout" means the complete stdout of the process is the JSON results document
+	// and results are returned inline for sync jobs without fetching them from storage.
+	// Only supported for sync-only docker and subprocess processes, results of other jobs are read from their logs.
+	OutputsSource string `yaml:"outputsSource,omitempty" json:"outputsSource,omitempty"`
+	// DefaultResultContentType is the media type of results that do not declare their own, application/json if empty
+	DefaultResultContentType string `yaml:"defaultResultContentType,omitempty" json:"defaultResultContentType,omitempty"`
+	// ResultHook optionally transforms results of successful jobs before they are returned
+	ResultHook *ResultHook `yaml:"resultHook,omitempty" json:"resultHook,omitempty"`
+	// ValidationHook optionally validates inputs through an external service before jobs are created
+	ValidationHook *ValidationHook `yaml:"validationHook,omitempty" json:"validationHook,omitempty"`
+	// CommandTemplate arguments are rendered per job and appended to Command instead of the inputs JSON
+	CommandTemplate []string `yaml:"commandTemplate,omitempty" json:"commandTemplate,omitempty"`
+}
+
+// ResultContentType returns the media type of results that do not declare their own
+func (p Process) ResultContentType() string {
+	if p.DefaultResultContentType == "" {
+		return "application/json"
+	}
+	return p.DefaultResultContentType
 }
 
 type Link struct {
@@ -36,6 +65,44 @@ type Info struct {
 	Description        string   `yaml:"description" json:"description"`
 	JobControlOptions  []string `yaml:"jobControlOptions" json:"jobControlOptions"`
 	OutputTransmission []string `yaml:"outputTransmission" json:"outputTransmission"`
+	Keywords           []string `yaml:"keywords,omitempty" json:"keywords,omitempty"`
+	// Former IDs of a renamed process, these resolve to this process
+	Aliases []string `yaml:"aliases,omitempty" json:"aliases,omitempty"`
+	// Deprecated processes can still be executed, execution responses signal it with Deprecation and Sunset headers
+	Deprecated         bool   `yaml:"deprecated,omitempty" json:"deprecated,omitempty"`
+	DeprecationMessage string `yaml:"deprecationMessage,omitempty" json:"deprecationMessage,omitempty"`
+	// Date after which a deprecated process may be removed, in the form YYYY-MM-DD
+	Sunset string `yaml:"sunset,omitempty" json:"sunset,omitempty"`
+}
+
+// Layout of the sunset date of deprecated processes
+const sunsetLayout = "2006-01-02"
+
+// SunsetTime returns the sunset date of a deprecated process, ok is false if it has none
+func (i Info) SunsetTime() (t time.Time, ok bool) {
+	if !i.Deprecated || i.Sunset == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(sunsetLayout, i.Sunset)
+	return t, err == nil
+}
+
+// HasKeywords returns true if all of the given keywords are among the keywords of the process.
+// Matching is case-insensitive.
+func (i Info) HasKeywords(keywords []string) bool {
+	for _, kw := range keywords {
+		found := false
+		for _, pkw := range i.Keywords {
+			if strings.EqualFold(kw, pkw) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
 }
 
 type ValueDefinition struct {
@@ -46,6 +113,9 @@ type ValueDefinition struct {
 type LiteralDataDomain struct {
 	DataType        string          `yaml:"dataType" json:"dataType"`
 	ValueDefinition ValueDefinition `yaml:"valueDefinition" json:"valueDefinition,omitempty"`
+	DefaultValue    interface{}     `yaml:"defaultValue,omitempty" json:"defaultValue,omitempty"`
+	// CRSs accepted for bbox inputs in addition to CRS84 and EPSG:4326
+	SupportedCRS []string `yaml:"supportedCRS,omitempty" json:"supportedCRS,omitempty"`
 }
 
 type Input struct {
@@ -59,6 +129,16 @@ type Inputs struct {
 	Input       Input  `yaml:"input" json:"input"`
 	MinOccurs   int    `yaml:"minOccurs" json:"minOccurs"`
 	MaxOccurs   int    `yaml:"maxOccurs,omitempty" json:"maxOccurs,omitempty"`
+	// Stage href inputs by downloading them when the job starts, the process receives path of the downloaded file
+	Stage bool `yaml:"stage,omitempty" json:"stage,omitempty"`
+	// Expected media type of staged href inputs
+	MediaType string `yaml:"mediaType,omitempty" json:"mediaType,omitempty"`
+	// IDs of inputs that must be provided when this input is provided
+	Requires []string `yaml:"requires,omitempty" json:"requires,omitempty"`
+	// IDs of inputs that can not be provided together with this input
+	Conflicts []string `yaml:"conflicts,omitempty" json:"conflicts,omitempty"`
+	// Env variable the value of the input is read from, these inputs are set by the server and not described to clients
+	ServerEnv string `yaml:"serverEnv,omitempty" json:"serverEnv,omitempty"`
 }
 
 type Output struct {
@@ -71,6 +151,8 @@ type Outputs struct {
 	Description string `yaml:"description" json:"description"`
 	Output      Output `yaml:"output" json:"output"`
 	InputID     string `yaml:"inputId" json:"inputId,omitempty"`
+	// JSON Schema results of successful jobs are checked against, see outputValidation of the process config
+	Schema map[string]interface{} `yaml:"schema,omitempty" json:"schema,omitempty"`
 }
 
 type Resources struct {
@@ -88,12 +170,139 @@ type Host struct {
 type Config struct {
 	EnvVars   []string  `yaml:"envVars" json:"envVars,omitempty"`
 	Resources Resources `yaml:"maxResources" json:"maxResources,omitempty"`
+	// Env variables that can be overridden at submission time through `env` in the execution request
+	OverridableEnvVars []string `yaml:"overridableEnvVars,omitempty" json:"overridableEnvVars,omitempty"`
+	// Seconds to wait after SIGTERM before SIGKILL when a docker job is dismissed, nil means default of 10 seconds
+	StopTimeout *int `yaml:"stopTimeout,omitempty" json:"stopTimeout,omitempty"`
+	// Resources jobs get unless overridden in the execution request, nil means maxResources
+	DefaultResources *Resources `yaml:"defaultResources,omitempty" json:"defaultResources,omitempty"`
+	// Seconds after which a job still in running status is considered stuck, nil means jobs are never considered stuck
+	MaxRunningTime *int `yaml:"maxRunningTime,omitempty" json:"maxRunningTime,omitempty"`
+	// Action taken for stuck jobs, "warn" (default) logs a warning, "fail" terminates the job and marks it as failed
+	StuckAction string `yaml:"stuckAction,omitempty" json:"stuckAction,omitempty"`
+	// Scheduling priority of jobs unless overridden in the execution request
+	Priority *int `yaml:"priority,omitempty" json:"priority,omitempty"`
+	// Reuse results of a previous successful job with the same version, inputs and env overrides instead of executing
+	CacheResults bool `yaml:"cacheResults,omitempty" json:"cacheResults,omitempty"`
+	// Reuse cached results of jobs of other submitters, by default only jobs of the same submitter are reused
+	ShareCachedResults bool `yaml:"shareCachedResults,omitempty" json:"shareCachedResults,omitempty"`
+	// User containers of docker jobs run as, in the form UID[:GID], empty means the user declared by the image
+	User string `yaml:"user,omitempty" json:"user,omitempty"`
+	// Seconds without new log output after which a running docker job is considered hung and failed, nil means no limit
+	InactivityTimeout *int `yaml:"inactivityTimeout,omitempty" json:"inactivityTimeout,omitempty"`
+	// Action on results that do not match output schemas, "warn" (default) logs the mismatch, "fail" fails the job
+	OutputValidation string `yaml:"outputValidation,omitempty" json:"outputValidation,omitempty"`
+	// Restrict strings of inputs to characters that shells do not interpret, the command must not invoke a shell
+	SafeInputs bool `yaml:"safeInputs,omitempty" json:"safeInputs,omitempty"`
+	// Keep containers of failed docker jobs for inspection, these are removed after FAILED_CONTAINER_TTL
+	KeepFailedContainers bool `yaml:"keepFailedContainers,omitempty" json:"keepFailedContainers,omitempty"`
+	// Handling of client values for inputs with serverEnv, "reject" (default) fails the request, "ignore" drops the values
+	ServerInputOverride string `yaml:"serverInputOverride,omitempty" json:"serverInputOverride,omitempty"`
+}
+
+var validUser = regexp.MustCompile(`^[A-Za-z0-9_.-]+(:[A-Za-z0-9_.-]+)?$`)
+
+// Range of job priorities, same as scheduling priority of AWS Batch fair share queues
+const (
+	MinPriority = 0
+	MaxPriority = 9999
+)
+
+// ResolvePriority returns the requested priority or the default priority of the process, nil if neither is set.
+// Higher priority jobs are started first when the scheduler is resumed, and scheduled first by AWS Batch fair share queues.
+func (p Process) ResolvePriority(requested *int) (*int, error) {
+	if requested == nil {
+		return p.Config.Priority, nil
+	}
+	if *requested < MinPriority || *requested > MaxPriority {
+		return nil, fmt.Errorf("priority must be between %d and %d", MinPriority, MaxPriority)
+	}
+	return requested, nil
+}
+
+// CacheKey returns a hash identifying the results of executing this version of the process with the given inputs
+// and env overrides for submitter. Results are only reused for the same submitter, unless the process shares them,
+// in which case submitter is ignored. Keys of objects are sorted when encoding, so the hash does not depend on the order of inputs.
+func (p Process) CacheKey(inputs map[string]interface{}, envOverrides map[string]string, submitter string) (string, error) {
+	if p.Config.ShareCachedResults {
+		submitter = ""
+	}
+	b, err := json.Marshal(map[string]interface{}{
+		"processID": p.Info.ID,
+		"version":   p.Info.Version,
+		"inputs":    inputs,
+		"env":       envOverrides,
+		"submitter": submitter,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// DefaultResources returns the resources jobs of this process get unless overridden in the execution request.
+func (p Process) DefaultResources() Resources {
+	if p.Config.DefaultResources != nil {
+		return *p.Config.DefaultResources
+	}
+	return p.Config.Resources
+}
+
+// ResolveResources applies the non zero fields of requested over the default resources of the process.
+// Resources can not exceed maxResources of the process, where set.
+func (p Process) ResolveResources(requested *Resources) (Resources, error) {
+	r := p.DefaultResources()
+	if requested == nil {
+		return r, nil
+	}
+
+	if requested.CPUs < 0 || requested.Memory < 0 {
+		return r, errors.New("resources can not be negative")
+	}
+	if requested.CPUs > 0 {
+		r.CPUs = requested.CPUs
+	}
+	if requested.Memory > 0 {
+		r.Memory = requested.Memory
+	}
+
+	max := p.Config.Resources
+	if (max.CPUs > 0 && r.CPUs > max.CPUs) || (max.Memory > 0 && r.Memory > max.Memory) {
+		return r, fmt.Errorf("requested resources exceed maximum of %v cpus and %v MB memory", max.CPUs, max.Memory)
+	}
+	return r, nil
+}
+
+// ResourceOverrides returns the fields of defaultResources and requested that override the job definition of
+// aws-batch processes, requested taking precedence. Zero fields are left to the job definition.
+// Assumes requested has been checked with ResolveResources.
+func (p Process) ResourceOverrides(requested *Resources) Resources {
+	var r Resources
+	if p.Config.DefaultResources != nil {
+		r = *p.Config.DefaultResources
+	}
+	if requested != nil {
+		if requested.CPUs > 0 {
+			r.CPUs = requested.CPUs
+		}
+		if requested.Memory > 0 {
+			r.Memory = requested.Memory
+		}
+	}
+	return r
 }
 
 func (p Process) Type() string {
 	return p.Host.Type
 }
 
+// RequiresStorage reports if jobs of the process can not run without a storage service.
+// AWS Batch jobs keep their artifacts in the storage bucket and cached results are stored there.
+func (p Process) RequiresStorage() bool {
+	return p.Host.Type == "aws-batch" || p.Config.CacheResults
+}
+
 type inpOccurance struct {
 	occur    int
 	minOccur int
@@ -128,6 +337,80 @@ func (p Process) VerifyInputs(inp map[string]interface{}) error {
 		}
 	}
 
+	for _, i := range p.Inputs {
+		if _, provided := inp[i.ID]; !provided {
+			continue
+		}
+		for _, r := range i.Requires {
+			if _, ok := inp[r]; !ok {
+				return fmt.Errorf("input %s requires input %s", i.ID, r)
+			}
+		}
+		for _, c := range i.Conflicts {
+			if _, ok := inp[c]; ok {
+				return fmt.Errorf("inputs %s and %s can not be provided together", i.ID, c)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ResolveOutputTransmission returns the transmission mode for every output of the process.
+// The first option in process outputTransmission is the default, it is used for outputs not present in requested.
+// Requested modes must be allowed by the output, or by the process if the output does not declare any.
+func (p Process) ResolveOutputTransmission(requested map[string]string) (map[string]string, error) {
+	defaultMode := "value"
+	if len(p.Info.OutputTransmission) > 0 {
+		defaultMode = p.Info.OutputTransmission[0]
+	}
+
+	outputIDs := make(map[string]Outputs, len(p.Outputs))
+	for _, o := range p.Outputs {
+		outputIDs[o.ID] = o
+	}
+
+	for id := range requested {
+		if _, ok := outputIDs[id]; !ok {
+			return nil, fmt.Errorf("%s is not a valid output option for this process, use /processes/%s endpoint to get list of output options", id, p.Info.ID)
+		}
+	}
+
+	modes := make(map[string]string, len(p.Outputs))
+	for id, o := range outputIDs {
+		mode, ok := requested[id]
+		if !ok || mode == "" {
+			modes[id] = defaultMode
+			continue
+		}
+
+		allowed := o.Output.Formats
+		if len(allowed) == 0 {
+			allowed = p.Info.OutputTransmission
+		}
+		if len(allowed) == 0 {
+			allowed = []string{defaultMode}
+		}
+		if !utils.StringInSlice(mode, allowed) {
+			return nil, fmt.Errorf("transmissionMode %s is not supported for output %s, supported options are %v", mode, id, allowed)
+		}
+		modes[id] = mode
+	}
+
+	return modes, nil
+}
+
+// Check that all env variables to override at submission time are allowed by the process
+func (p Process) VerifyEnvOverrides(env map[string]string) error {
+	var disallowed []string
+	for k := range env {
+		if !utils.StringInSlice(k, p.Config.OverridableEnvVars) {
+			disallowed = append(disallowed, k)
+		}
+	}
+	if len(disallowed) > 0 {
+		return fmt.Errorf("env variables not allowed to be overridden for this process: %v", disallowed)
+	}
 	return nil
 }
 
@@ -146,25 +429,149 @@ func (p Process) VerifyLocalEnvars(container Config) error {
 
 // ProcessList describes processes
 // This is not a map since ProcessList Handler function wants order
+// Processes are added, updated and removed while requests read them, so lists are only accessed through methods
+// holding the lock. Readers get copies so that they never observe a partially updated list.
 type ProcessList struct {
-	List     []Process
-	InfoList []Info
+	mu       sync.RWMutex
+	list     []Process
+	infoList []Info
 }
 
+// Get returns the process with the given ID.
+// If no process has this ID, the process having it as an alias is returned.
 func (ps *ProcessList) Get(processID string) (Process, int, error) {
-	for i, p := range (*ps).List {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	return ps.get(processID)
+}
+
+func (ps *ProcessList) get(processID string) (Process, int, error) {
+	for i, p := range ps.list {
 		if p.Info.ID == processID {
 			return p, i, nil
 		}
 	}
+	for i, p := range ps.list {
+		if utils.StringInSlice(processID, p.Info.Aliases) {
+			return p, i, nil
+		}
+	}
 	return Process{}, 0, errors.New("process not found")
 }
 
+// Processes returns a copy of all processes in order
+func (ps *ProcessList) Processes() []Process {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	return append([]Process(nil), ps.list...)
+}
+
+// Infos returns a copy of the info of all processes in order
+func (ps *ProcessList) Infos() []Info {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	return append([]Info{}, ps.infoList...)
+}
+
+// Add appends a process, its ID and aliases must not be used by another process
+func (ps *ProcessList) Add(p Process) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if _, _, err := ps.get(p.Info.ID); err == nil {
+		return fmt.Errorf("process %s already exist", p.Info.ID)
+	}
+	if err := ps.verifyNames(p); err != nil {
+		return err
+	}
+	ps.list = append(ps.list, p)
+	ps.infoList = append(ps.infoList, p.Info)
+	return nil
+}
+
+// Replace replaces the process having the same ID as p, its aliases must not be used by another process
+func (ps *ProcessList) Replace(p Process) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if err := ps.verifyNames(p); err != nil {
+		return err
+	}
+	for i := range ps.list {
+		if ps.list[i].Info.ID == p.Info.ID {
+			ps.list[i] = p
+			ps.infoList[i] = p.Info
+			return nil
+		}
+	}
+	return errors.New("process not found")
+}
+
+// VerifyNames checks that the ID and aliases of p are not used by processes other than the one having the ID of p,
+// so that processes can be checked before they are persisted
+func (ps *ProcessList) VerifyNames(p Process) error {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	return ps.verifyNames(p)
+}
+
+func (ps *ProcessList) verifyNames(p Process) error {
+	for _, other := range ps.list {
+		if other.Info.ID == p.Info.ID {
+			continue
+		}
+		if err := verifyNames(p, other); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check that the ID and aliases of p are neither the ID nor an alias of other, otherwise one of them could not be resolved
+func verifyNames(p, other Process) error {
+	names := append([]string{p.Info.ID}, p.Info.Aliases...)
+	for _, name := range names {
+		if name == other.Info.ID || utils.StringInSlice(name, other.Info.Aliases) {
+			return fmt.Errorf("'%s' is already the ID or an alias of process %s", name, other.Info.ID)
+		}
+	}
+	return nil
+}
+
+// Remove removes the process with the given ID, aliases are not resolved
+func (ps *ProcessList) Remove(processID string) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	for i := range ps.list {
+		if ps.list[i].Info.ID == processID {
+			// new slices are built so that copies handed to readers are not shifted
+			ps.list = append(append([]Process(nil), ps.list[:i]...), ps.list[i+1:]...)
+			ps.infoList = append(append([]Info(nil), ps.infoList[:i]...), ps.infoList[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("process not found")
+}
+
 func MarshallProcess(f string) (Process, error) {
-	var p Process
 	data, err := os.ReadFile(f)
 	if err != nil {
-		return p, err
+		return Process{}, err
+	}
+	return parseProcess(data, strings.ToLower(filepath.Ext(f)) == ".json")
+}
+
+// Parse a yaml or JSON process definition
+func parseProcess(data []byte, isJSON bool) (Process, error) {
+	var p Process
+	var err error
+	// JSON definitions use the same field names as yaml ones, converting them to yaml keeps a single set of tags authoritative
+	if isJSON {
+		var v interface{}
+		if err = json.Unmarshal(data, &v); err != nil {
+			return Process{}, err
+		}
+		if data, err = yaml.Marshal(v); err != nil {
+			return Process{}, err
+		}
 	}
 	err = yaml.Unmarshal(data, &p)
 	if err != nil {
@@ -175,7 +582,7 @@ func MarshallProcess(f string) (Process, error) {
 	// the problem with doing this here is that if the job definition is updated while we are doing this, our process info will not update
 	switch p.Host.Type {
 	case "aws-batch":
-		c, err := controllers.NewAWSBatchController(os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_REGION"))
+		c, err := controllers.NewAWSBatchController(os.Getenv("AWS_REGION"))
 		if err != nil {
 			return Process{}, err
 		}
@@ -191,19 +598,35 @@ func MarshallProcess(f string) (Process, error) {
 	return p, nil
 }
 
-// Load all processes from yml files in the given directory and subdirectories
-func LoadProcesses(dir string) (ProcessList, error) {
-	var pl ProcessList
+// ProcessFiles returns process definition files (.yml, .yaml or .json) one level down in dir
+func ProcessFiles(dir string) ([]string, error) {
+	var files []string
+	for _, ext := range []string{"yml", "yaml", "json"} {
+		matches, err := filepath.Glob(fmt.Sprintf("%s/*/*.%s", dir, ext))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
 
-	ymls, err := filepath.Glob(fmt.Sprintf("%s/*/*.yml", dir))
-	if err != nil {
-		return pl, err
+// Check that the ID and aliases of p are not used by already loaded processes, IDs included
+func verifyLoadedNames(p Process, loaded []Process) error {
+	for _, other := range loaded {
+		if err := verifyNames(p, other); err != nil {
+			return err
+		}
 	}
-	yamls, err := filepath.Glob(fmt.Sprintf("%s/*/*.yaml", dir))
+	return nil
+}
+
+// Load all processes from yml, yaml and json files in the given directory and subdirectories
+func LoadProcesses(dir string) (*ProcessList, error) {
+	allYamls, err := ProcessFiles(dir)
 	if err != nil {
-		return pl, err
+		return nil, err
 	}
-	allYamls := append(ymls, yamls...)
 	processes := make([]Process, 0)
 
 	for _, y := range allYamls {
@@ -217,6 +640,12 @@ func LoadProcesses(dir string) (ProcessList, error) {
 			log.Errorf("could not register process %s Error: %v", filepath.Base(y), err.Error())
 			continue
 		}
+		// processes loaded first are kept, so that a new file can not take over names of a registered process
+		err = verifyLoadedNames(p, processes)
+		if err != nil {
+			log.Errorf("could not register process %s Error: %v", filepath.Base(y), err.Error())
+			continue
+		}
 		processes = append(processes, p)
 	}
 
@@ -225,10 +654,7 @@ func LoadProcesses(dir string) (ProcessList, error) {
 		infos[i] = p.Info
 	}
 
-	pl.List = processes
-	pl.InfoList = infos
-
-	return pl, nil
+	return &ProcessList{list: processes, infoList: infos}, nil
 }
 
 // Validate checks if the Process has all required fields properly set.
@@ -239,10 +665,25 @@ func (p *Process) Validate() error {
 	if p.Info.Title == "" {
 		return errors.New("process title is required")
 	}
+	if p.Info.Sunset != "" {
+		if !p.Info.Deprecated {
+			return errors.New("sunset is only allowed for deprecated processes")
+		}
+		if _, err := time.Parse(sunsetLayout, p.Info.Sunset); err != nil {
+			return fmt.Errorf("invalid sunset date %s, expected YYYY-MM-DD", p.Info.Sunset)
+		}
+	}
+
 	if p.Info.Version == "" {
 		return errors.New("version is required")
 	}
 
+	for _, alias := range p.Info.Aliases {
+		if alias == "" || alias == p.Info.ID {
+			return fmt.Errorf("invalid alias '%s', aliases must be non empty and different from process ID", alias)
+		}
+	}
+
 	// Validate jobControlOptions
 	validJobControlOptions := map[string]bool{
 		"sync-execute":  true,
@@ -265,6 +706,56 @@ func (p *Process) Validate() error {
 		}
 	}
 
+	// Validate outputsSource
+	if p.OutputsSource != "" && p.OutputsSource != "stdout" {
+		return fmt.Errorf("invalid outputsSource: %s; must be one of ['', stdout]", p.OutputsSource)
+	}
+	if p.OutputsSource == "stdout" {
+		if p.Host.Type != "docker" && p.Host.Type != "subprocess" {
+			return errors.New("stdout outputsSource is only supported for docker and subprocess host types")
+		}
+		if utils.StringInSlice("async-execute", p.Info.JobControlOptions) {
+			return errors.New("stdout outputsSource is only supported for processes with sync-execute job control option only")
+		}
+	}
+
+	if p.Config.ShareCachedResults && !p.Config.CacheResults {
+		return errors.New("shareCachedResults requires cacheResults")
+	}
+	if p.Config.CacheResults && p.OutputsSource == "stdout" {
+		return errors.New("cacheResults is not supported for processes with stdout outputsSource")
+	}
+
+	// Validate defaultResultContentType
+	if p.DefaultResultContentType != "" {
+		if _, _, err := mime.ParseMediaType(p.DefaultResultContentType); err != nil {
+			return fmt.Errorf("invalid defaultResultContentType: %s", err.Error())
+		}
+	}
+
+	// Validate resultHook
+	if p.ResultHook != nil {
+		if _, err := p.ResultHook.parse(); err != nil {
+			return fmt.Errorf("invalid resultHook template: %s", err.Error())
+		}
+	}
+
+	// Validate validationHook
+	if p.ValidationHook != nil {
+		if err := p.ValidationHook.validate(); err != nil {
+			return fmt.Errorf("invalid validationHook: %s", err.Error())
+		}
+	}
+
+	// Validate commandTemplate
+	if err := p.validateCommandTemplate(); err != nil {
+		return fmt.Errorf("invalid commandTemplate: %s", err.Error())
+	}
+
+	if p.Config.SafeInputs && invokesShell(p.Command) {
+		return errors.New("safeInputs processes must not run their command through a shell, use the exec form instead")
+	}
+
 	// Validate Host Type
 	if p.Host.Type != "docker" && p.Host.Type != "aws-batch" && p.Host.Type != "subprocess" {
 		return errors.New("host type must be 'docker' or 'aws-batch' or 'subprocess'")
@@ -275,23 +766,111 @@ func (p *Process) Validate() error {
 		return errors.New("container image is required for docker host type")
 	}
 
+	if p.Config.StopTimeout != nil && *p.Config.StopTimeout < 0 {
+		return errors.New("stopTimeout can not be negative")
+	}
+
+	if p.Config.User != "" {
+		if p.Host.Type != "docker" {
+			return errors.New("user is only supported for docker host type")
+		}
+		if !validUser.MatchString(p.Config.User) {
+			return fmt.Errorf("invalid user: %s; must be of the form UID[:GID]", p.Config.User)
+		}
+	}
+
+	if it := p.Config.InactivityTimeout; it != nil {
+		if p.Host.Type != "docker" {
+			return errors.New("inactivityTimeout is only supported for docker host type")
+		}
+		if *it <= 0 {
+			return errors.New("inactivityTimeout must be positive")
+		}
+	}
+
+	if p.Config.KeepFailedContainers && p.Host.Type != "docker" {
+		return errors.New("keepFailedContainers is only supported for docker host type")
+	}
+
+	if p.Config.MaxRunningTime != nil && *p.Config.MaxRunningTime <= 0 {
+		return errors.New("maxRunningTime must be positive")
+	}
+	if p.Config.StuckAction != "" && p.Config.StuckAction != "warn" && p.Config.StuckAction != "fail" {
+		return fmt.Errorf("invalid stuckAction: %s; must be one of [warn, fail]", p.Config.StuckAction)
+	}
+
+	if pr := p.Config.Priority; pr != nil && (*pr < MinPriority || *pr > MaxPriority) {
+		return fmt.Errorf("priority must be between %d and %d", MinPriority, MaxPriority)
+	}
+
+	if dr := p.Config.DefaultResources; dr != nil {
+		if dr.CPUs < 0 || dr.Memory < 0 {
+			return errors.New("defaultResources can not be negative")
+		}
+		max := p.Config.Resources
+		if (max.CPUs > 0 && dr.CPUs > max.CPUs) || (max.Memory > 0 && dr.Memory > max.Memory) {
+			return errors.New("defaultResources can not exceed maxResources")
+		}
+	}
+
 	// Validate AWS data (if applicable)
 	if p.Host.Type == "aws-batch" && (p.Host.JobQueue == "" || p.Host.JobDefinition == "") {
 		return errors.New("job information is required for aws-batch host type")
 	}
 
 	// Validate Inputs
+	inputIDs := make(map[string]bool, len(p.Inputs))
 	for i, input := range p.Inputs {
 		if input.ID == "" {
 			return fmt.Errorf("input %d: ID is required", i)
 		}
+		inputIDs[input.ID] = true
+	}
+	for _, input := range p.Inputs {
+		for _, id := range append(append([]string{}, input.Requires...), input.Conflicts...) {
+			if !inputIDs[id] || id == input.ID {
+				return fmt.Errorf("input %s: requires and conflicts must reference other inputs of the process, found %s", input.ID, id)
+			}
+		}
+		for _, id := range input.Requires {
+			if utils.StringInSlice(id, input.Conflicts) {
+				return fmt.Errorf("input %s: %s can not be both required and conflicting", input.ID, id)
+			}
+		}
+		if input.Stage && p.Host.Type == "aws-batch" {
+			return fmt.Errorf("input %s: staged inputs are not supported for aws-batch processes", input.ID)
+		}
+		if input.ServerEnv != "" {
+			if input.Stage {
+				return fmt.Errorf("input %s: server inputs can not be staged", input.ID)
+			}
+			if _, ok := os.LookupEnv(input.ServerEnv); !ok {
+				return fmt.Errorf("input %s: env variable %s not set", input.ID, input.ServerEnv)
+			}
+		}
+	}
+	if o := p.Config.ServerInputOverride; o != "" && o != ServerInputReject && o != ServerInputIgnore {
+		return fmt.Errorf("invalid serverInputOverride: %s; must be one of [reject, ignore]", o)
 	}
 
 	// Validate Outputs
+	if p.Config.OutputValidation != "" && p.Config.OutputValidation != "warn" && p.Config.OutputValidation != "fail" {
+		return errors.New("outputValidation must be 'warn' or 'fail'")
+	}
 	for i, output := range p.Outputs {
 		if output.ID == "" {
 			return fmt.Errorf("output %d: ID is required", i)
 		}
+		if output.Schema != nil {
+			if err := checkSchema(output.Schema); err != nil {
+				return fmt.Errorf("output %s: invalid schema: %s", output.ID, err.Error())
+			}
+		}
+		for _, transmission := range output.Output.Formats {
+			if !validOutputTransmission[transmission] {
+				return fmt.Errorf("output %s: invalid transmissionMode: %s; must be one of [reference, value]", output.ID, transmission)
+			}
+		}
 	}
 
 	return nil
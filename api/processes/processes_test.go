@@ -0,0 +1,197 @@
+package processes
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Processes of a reload generation, all having the generation as version
+func generation(gen int) []Process {
+	ps := make([]Process, 0, 3)
+	for _, id := range []string{"a", "b", "c"} {
+		ps = append(ps, Process{Info: Info{ID: id, Version: fmt.Sprint(gen)}})
+	}
+	return ps
+}
+
+// Readers must never observe a mix of two reloads or miss a process present in both,
+// run with -race to also check that the list is never accessed without the lock.
+func TestProcessListConcurrentReload(t *testing.T) {
+	ps := &ProcessList{}
+	ps.set(generation(0))
+
+	const reloads = 200
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for gen := 1; gen <= reloads; gen++ {
+			ps.set(generation(gen))
+			// processes added and removed through the API between reloads
+			ps.Add(Process{Info: Info{ID: "tmp", Version: "tmp"}})
+			ps.Remove("tmp")
+		}
+	}()
+
+	errs := make(chan error, 4)
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				if _, _, err := ps.Get("b"); err != nil {
+					errs <- fmt.Errorf("Get(b) during reload: %v", err)
+					return
+				}
+				if err := sameGeneration(ps.Processes()); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	p, _, err := ps.Get("a")
+	if err != nil || p.Info.Version != fmt.Sprint(reloads) {
+		t.Errorf("Get(a) after reloads = %v, %v, want version %d", p.Info.Version, err, reloads)
+	}
+	if n := len(ps.Infos()); n != 3 {
+		t.Errorf("len(Infos()) after reloads = %d, want 3", n)
+	}
+}
+
+func sameGeneration(list []Process) error {
+	var version string
+	for _, p := range list {
+		if p.Info.ID == "tmp" {
+			continue
+		}
+		if version == "" {
+			version = p.Info.Version
+		}
+		if p.Info.Version != version {
+			return fmt.Errorf("processes of versions %s and %s listed together", version, p.Info.Version)
+		}
+	}
+	return nil
+}
+
+// Copies handed to readers must not change when the list is updated afterwards
+func TestProcessListCopiesAreStable(t *testing.T) {
+	ps := &ProcessList{}
+	ps.set(generation(1))
+
+	list := ps.Processes()
+	infos := ps.Infos()
+
+	ps.Remove("a")
+	ps.Replace(Process{Info: Info{ID: "b", Version: "2"}})
+
+	for i, id := range []string{"a", "b", "c"} {
+		if list[i].Info.ID != id || list[i].Info.Version != "1" {
+			t.Errorf("list[%d] = %s@%s, want %s@1", i, list[i].Info.ID, list[i].Info.Version, id)
+		}
+		if infos[i].ID != id || infos[i].Version != "1" {
+			t.Errorf("infos[%d] = %s@%s, want %s@1", i, infos[i].ID, infos[i].Version, id)
+		}
+	}
+}
+
+func TestValidateStdoutOutputsSource(t *testing.T) {
+	tests := []struct {
+		host    string
+		options []string
+		wantErr bool
+	}{
+		{"docker", []string{"sync-execute"}, false},
+		{"subprocess", []string{"sync-execute"}, false},
+		{"docker", []string{"sync-execute", "async-execute"}, true},
+		{"subprocess", []string{"async-execute"}, true},
+		{"aws-batch", []string{"sync-execute"}, true},
+	}
+
+	for _, tt := range tests {
+		p := Process{
+			Info:          Info{ID: "run", Title: "Run", Version: "1.0.0", JobControlOptions: tt.options},
+			Host:          Host{Type: tt.host},
+			OutputsSource: "stdout",
+		}
+		// other fields of the host may be missing, only outputsSource errors matter here
+		err := p.Validate()
+		if gotErr := err != nil && strings.Contains(err.Error(), "outputsSource"); gotErr != tt.wantErr {
+			t.Errorf("Validate() of %s %v error = %v, want outputsSource error %v", tt.host, tt.options, err, tt.wantErr)
+		}
+	}
+}
+
+func aliased(id string, aliases ...string) Process {
+	return Process{Info: Info{ID: id, Version: "1", Aliases: aliases}}
+}
+
+func TestProcessListAddRejectsNameCollisions(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       Process
+		wantErr bool
+	}{
+		{"new names", aliased("c", "c-old"), false},
+		{"ID of another process", aliased("a"), true},
+		{"ID is an alias", aliased("a-old"), true},
+		{"alias is an ID", aliased("c", "b"), true},
+		{"alias is an alias", aliased("c", "b-old"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := &ProcessList{}
+			ps.set([]Process{aliased("a", "a-old"), aliased("b", "b-old")})
+			if err := ps.Add(tt.p); (err != nil) != tt.wantErr {
+				t.Errorf("Add() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProcessListReplaceRejectsNameCollisions(t *testing.T) {
+	ps := &ProcessList{}
+	ps.set([]Process{aliased("a", "a-old"), aliased("b", "b-old")})
+
+	if err := ps.Replace(aliased("a", "a-old", "a-v1")); err != nil {
+		t.Errorf("Replace() keeping own aliases error = %v", err)
+	}
+	if err := ps.Replace(aliased("a", "b-old")); err == nil {
+		t.Error("Replace() with an alias of another process succeeded")
+	}
+	if p, _, err := ps.Get("b-old"); err != nil || p.Info.ID != "b" {
+		t.Errorf("Get(b-old) = %s, %v, want b", p.Info.ID, err)
+	}
+}
+
+func TestVerifyLoadedNames(t *testing.T) {
+	loaded := []Process{aliased("a", "a-old")}
+	for _, p := range []Process{aliased("a"), aliased("b", "a"), aliased("a-old"), aliased("b", "a-old")} {
+		if err := verifyLoadedNames(p, loaded); err == nil {
+			t.Errorf("verifyLoadedNames(%s %v) succeeded, want collision with a", p.Info.ID, p.Info.Aliases)
+		}
+	}
+	if err := verifyLoadedNames(aliased("b", "b-old"), loaded); err != nil {
+		t.Errorf("verifyLoadedNames(b) error = %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package processes
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NormalizeQualifiedInputs resolves inputs provided in the OGC qualified forms.
+// Qualified values {"value": ..., "mediaType": ...} are replaced with their value.
+// Links {"href": ..., "type": ...} are kept as they are, staged inputs are downloaded and other inputs receive the link.
+// The media type of a qualified value or link must match the mediaType of the input if it declares one.
+// Inputs declared with object data type are left untouched, their values can not be told apart from qualified values.
+func (p Process) NormalizeQualifiedInputs(inputs map[string]interface{}) error {
+	for _, def := range p.Inputs {
+		if strings.EqualFold(def.Input.LiteralDataDomain.DataType, "object") {
+			continue
+		}
+		val, ok := inputs[def.ID]
+		if !ok {
+			continue
+		}
+
+		if list, ok := val.([]interface{}); ok {
+			for i, item := range list {
+				v, err := normalizeQualified(item, def)
+				if err != nil {
+					return fmt.Errorf("input %s[%d]: %s", def.ID, i, err.Error())
+				}
+				list[i] = v
+			}
+			continue
+		}
+
+		v, err := normalizeQualified(val, def)
+		if err != nil {
+			return fmt.Errorf("input %s: %s", def.ID, err.Error())
+		}
+		inputs[def.ID] = v
+	}
+	return nil
+}
+
+func normalizeQualified(val interface{}, def Inputs) (interface{}, error) {
+	obj, ok := val.(map[string]interface{})
+	if !ok {
+		return val, nil
+	}
+
+	if href, ok := obj["href"]; ok {
+		if s, ok := href.(string); !ok || s == "" {
+			return nil, fmt.Errorf("href must be a non empty string")
+		}
+		if err := checkMediaType(obj["type"], def.MediaType); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	}
+
+	if value, ok := obj["value"]; ok {
+		if def.Stage {
+			return nil, fmt.Errorf("input is staged and must be a link with href")
+		}
+		if err := checkMediaType(obj["mediaType"], def.MediaType); err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+
+	return obj, nil
+}
+
+// Check a media type given with an input against the mediaType declared by the input, an empty declared type accepts any
+func checkMediaType(given interface{}, declared string) error {
+	if given == nil {
+		return nil
+	}
+	s, ok := given.(string)
+	if !ok {
+		return fmt.Errorf("media type must be a string")
+	}
+	if declared != "" && !strings.EqualFold(s, declared) {
+		return fmt.Errorf("media type %s does not match %s of the input", s, declared)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package processes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/gommon/log"
+)
+
+var registryClient = http.Client{Timeout: 30 * time.Second}
+
+// LoadRegistryProcesses loads processes from an external registry at url.
+// The registry must respond to GET with a JSON array of process definitions, using the same fields as JSON process files.
+// Invalid definitions and definitions requiring storage when storageConfigured is false are skipped,
+// an error is returned only if the registry can not be read.
+func LoadRegistryProcesses(url string, storageConfigured bool) (*ProcessList, error) {
+	processes, err := fetchRegistryProcesses(url, storageConfigured)
+	if err != nil {
+		return nil, err
+	}
+	ps := &ProcessList{}
+	ps.set(processes)
+	return ps, nil
+}
+
+// SyncRegistry replaces processes of ps with processes of the registry at url every interval, it never returns.
+// If the registry is unavailable, the last known good processes are kept.
+func (ps *ProcessList) SyncRegistry(url string, interval time.Duration, storageConfigured bool) {
+	for {
+		time.Sleep(interval)
+
+		processes, err := fetchRegistryProcesses(url, storageConfigured)
+		if err != nil {
+			log.Errorf("could not sync processes from registry, keeping last known processes. Error: %s", err.Error())
+			continue
+		}
+		ps.set(processes)
+		log.Debugf("synced %d processes from registry", len(processes))
+	}
+}
+
+// Replace all processes
+func (ps *ProcessList) set(processes []Process) {
+	infos := make([]Info, len(processes))
+	for i, p := range processes {
+		infos[i] = p.Info
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.list = processes
+	ps.infoList = infos
+}
+
+func fetchRegistryProcesses(url string, storageConfigured bool) ([]Process, error) {
+	resp, err := registryClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registry responded with %s", resp.Status)
+	}
+
+	var definitions []json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&definitions); err != nil {
+		return nil, fmt.Errorf("invalid registry response: %s", err.Error())
+	}
+
+	processes := make([]Process, 0, len(definitions))
+	for i, d := range definitions {
+		p, err := parseProcess(d, true)
+		if err != nil {
+			log.Errorf("could not register process %d of registry Error: %v", i, err)
+			continue
+		}
+		if err = p.Validate(); err != nil {
+			log.Errorf("could not register process %d of registry Error: %v", i, err)
+			continue
+		}
+		if !storageConfigured && p.RequiresStorage() {
+			log.Errorf("could not register process %s of registry Error: process requires a storage service, storage service is not configured", p.Info.ID)
+			continue
+		}
+		if err = verifyLoadedNames(p, processes); err != nil {
+			log.Errorf("could not register process %s of registry Error: %v", p.Info.ID, err)
+			continue
+		}
+		processes = append(processes, p)
+	}
+	return processes, nil
+}
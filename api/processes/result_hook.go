@@ -0,0 +1,59 @@
+package processes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"text/template"
+)
+
+// ResultHook transforms results of successful jobs before they are returned, for example to wrap outputs in a STAC item.
+// Template is a Go text/template that must render a JSON document. It has access to .jobID, .processID and .results,
+// and the toJSON function. No other functions are available, so the hook can not perform I/O or run arbitrary code.
+type ResultHook struct {
+	Template string `yaml:"template" json:"template"`
+}
+
+var resultHookFuncs = template.FuncMap{
+	"toJSON": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+func (rh ResultHook) parse() (*template.Template, error) {
+	return template.New("resultHook").Funcs(resultHookFuncs).Option("missingkey=error").Parse(rh.Template)
+}
+
+// ApplyResultHook transforms results using the result hook of the process.
+// Results are returned unchanged if the process has no result hook.
+func (p Process) ApplyResultHook(jobID string, results interface{}) (interface{}, error) {
+	if p.ResultHook == nil {
+		return results, nil
+	}
+
+	tmpl, err := p.ResultHook.parse()
+	if err != nil {
+		return nil, fmt.Errorf("invalid result hook template: %s", err.Error())
+	}
+
+	data := map[string]interface{}{
+		"jobID":     jobID,
+		"processID": p.Info.ID,
+		"results":   results,
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		return nil, fmt.Errorf("could not apply result hook: %s", err.Error())
+	}
+
+	var transformed interface{}
+	err = json.Unmarshal(buf.Bytes(), &transformed)
+	if err != nil {
+		return nil, fmt.Errorf("result hook did not render valid JSON: %s", err.Error())
+	}
+
+	return transformed, nil
+}
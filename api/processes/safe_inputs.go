@@ -0,0 +1,95 @@
+package processes
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Characters allowed in strings of inputs of processes with safeInputs, none of them is interpreted by shells
+var safeInputValue = regexp.MustCompile(`^[A-Za-z0-9_.,:/@%+=-]*$`)
+
+var shells = []string{"sh", "bash", "dash", "zsh", "ksh", "ash", "csh", "tcsh", "fish"}
+
+// Options of shells that run the next argument as a script, such as -c or -lc
+var shellScriptFlag = regexp.MustCompile(`^-[a-z]*c[a-z]*$`)
+
+// VerifySafeInputs checks that inputs of processes with safeInputs can not alter the executed command.
+// Every string in inputs, including keys of objects, must only contain characters of safeInputValue and must not
+// start with '-' so that it can not be taken as an option. Staged inputs are skipped, they are replaced with paths of
+// the downloaded files.
+func (p Process) VerifySafeInputs(inputs map[string]interface{}) error {
+	if !p.Config.SafeInputs {
+		return nil
+	}
+	for _, def := range p.Inputs {
+		if def.Stage {
+			continue
+		}
+		val, ok := inputs[def.ID]
+		if !ok {
+			continue
+		}
+		if err := checkSafeValue(val); err != nil {
+			return fmt.Errorf("input %s: %s", def.ID, err.Error())
+		}
+	}
+	return nil
+}
+
+func checkSafeValue(v interface{}) error {
+	switch val := v.(type) {
+	case string:
+		return checkSafeString(val)
+	case map[string]interface{}:
+		for k, item := range val {
+			if err := checkSafeString(k); err != nil {
+				return err
+			}
+			if err := checkSafeValue(item); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range val {
+			if err := checkSafeValue(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkSafeString(s string) error {
+	if !safeInputValue.MatchString(s) {
+		return fmt.Errorf("'%s' contains characters that are not allowed, only letters, digits and _.,:/@%%+=- are allowed", s)
+	}
+	if strings.HasPrefix(s, "-") {
+		return fmt.Errorf("'%s' must not start with '-'", s)
+	}
+	return nil
+}
+
+// Reports if command runs its arguments through a shell, such as `sh -c`
+func invokesShell(command []string) bool {
+	if len(command) == 0 {
+		return false
+	}
+	isShell := false
+	for _, s := range shells {
+		if path.Base(command[0]) == s {
+			isShell = true
+			break
+		}
+	}
+	if !isShell {
+		return false
+	}
+	for _, arg := range command[1:] {
+		if shellScriptFlag.MatchString(arg) {
+			return true
+		}
+	}
+	return false
+}
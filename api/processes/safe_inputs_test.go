@@ -0,0 +1,112 @@
+package processes
+
+import (
+	"strings"
+	"testing"
+)
+
+func safeInputsProcess() Process {
+	p := Process{Inputs: []Inputs{{ID: "name"}, {ID: "options"}, {ID: "file", Stage: true}}}
+	p.Config.SafeInputs = true
+	return p
+}
+
+func TestVerifySafeInputs(t *testing.T) {
+	tests := []struct {
+		name    string
+		inputs  map[string]interface{}
+		wantErr bool
+	}{
+		{"plain values", map[string]interface{}{"name": "dem_v1.2", "options": map[string]interface{}{"crs": "EPSG:4326", "scale": 0.5}}, false},
+		{"url", map[string]interface{}{"name": "s3://bucket/key/a+b=c@d%20"}, false},
+		{"undeclared inputs are ignored", map[string]interface{}{"other": "; rm -rf /"}, false},
+		{"staged inputs are skipped", map[string]interface{}{"file": "https://example.com/a b"}, false},
+		{"command separator", map[string]interface{}{"name": "a; rm -rf /"}, true},
+		{"pipe", map[string]interface{}{"name": "a|sh"}, true},
+		{"and", map[string]interface{}{"name": "a&&b"}, true},
+		{"command substitution", map[string]interface{}{"name": "$(id)"}, true},
+		{"backticks", map[string]interface{}{"name": "`id`"}, true},
+		{"variable", map[string]interface{}{"name": "${HOME}"}, true},
+		{"redirection", map[string]interface{}{"name": "a>/etc/passwd"}, true},
+		{"glob", map[string]interface{}{"name": "*"}, true},
+		{"quotes", map[string]interface{}{"name": `a' "b`}, true},
+		{"whitespace splits arguments", map[string]interface{}{"name": "a b"}, true},
+		{"newline", map[string]interface{}{"name": "a\nid"}, true},
+		{"option injection", map[string]interface{}{"name": "--output=/etc/passwd"}, true},
+		{"nested value", map[string]interface{}{"options": map[string]interface{}{"list": []interface{}{"ok", "$(id)"}}}, true},
+		{"object key", map[string]interface{}{"options": map[string]interface{}{"a;id": "b"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := safeInputsProcess().VerifySafeInputs(tt.inputs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifySafeInputs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifySafeInputsDisabled(t *testing.T) {
+	p := safeInputsProcess()
+	p.Config.SafeInputs = false
+	if err := p.VerifySafeInputs(map[string]interface{}{"name": "a; rm -rf /"}); err != nil {
+		t.Errorf("VerifySafeInputs() without safeInputs error = %v, want nil", err)
+	}
+}
+
+func TestInvokesShell(t *testing.T) {
+	tests := []struct {
+		command []string
+		want    bool
+	}{
+		{nil, false},
+		{[]string{"python", "run.py"}, false},
+		{[]string{"sh", "run.sh"}, false},
+		{[]string{"sh", "-c", "python run.py"}, true},
+		{[]string{"/bin/bash", "-lc", "python run.py"}, true},
+		{[]string{"/usr/bin/env"}, false},
+		{[]string{"python", "-c", "print(1)"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := invokesShell(tt.command); got != tt.want {
+			t.Errorf("invokesShell(%q) = %v, want %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+// Without a shell each template argument is passed to the process as one argv entry,
+// shell metacharacters in inputs end up inside that argument and can not add or change arguments
+func TestCommandTemplateKeepsInputsInOneArgument(t *testing.T) {
+	p := Process{
+		Command:         []string{"python", "run.py"},
+		CommandTemplate: []string{"--name", "{{.inputs.name}}", "--out", "{{.jobID}}"},
+		Inputs:          []Inputs{{ID: "name"}},
+	}
+	value := `a"; rm -rf / #$(id) && echo 'b' | sh`
+
+	args, err := p.RenderCommandTemplate("job1", map[string]interface{}{"name": value})
+	if err != nil {
+		t.Fatalf("RenderCommandTemplate() error = %v", err)
+	}
+	want := []string{"--name", value, "--out", "job1"}
+	if len(args) != len(want) {
+		t.Fatalf("RenderCommandTemplate() = %q, want %q", args, want)
+	}
+	for i := range want {
+		if args[i] != want[i] {
+			t.Errorf("args[%d] = %q, want %q", i, args[i], want[i])
+		}
+	}
+}
+
+func TestValidateRejectsShellCommandWithSafeInputs(t *testing.T) {
+	p := safeInputsProcess()
+	p.Info = Info{ID: "run", Title: "Run", Version: "1.0.0"}
+	p.Host.Type = "docker"
+	p.Command = []string{"sh", "-c", "python run.py"}
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "shell") {
+		t.Errorf("Validate() of a safeInputs process running a shell error = %v, want shell error", err)
+	}
+}
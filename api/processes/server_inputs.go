@@ -0,0 +1,62 @@
+package processes
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Handling of client values for server inputs
+const (
+	// Requests providing a server input are rejected
+	ServerInputReject = "reject"
+	// Values of server inputs provided by clients are dropped
+	ServerInputIgnore = "ignore"
+)
+
+// ApplyServerInputs sets inputs declared with serverEnv to the value of their env variable, values provided by the
+// client are rejected or dropped based on serverInputOverride of the process. Env values are parsed as JSON,
+// values that are not valid JSON are used as strings.
+func (p Process) ApplyServerInputs(inputs map[string]interface{}) error {
+	for _, i := range p.Inputs {
+		if i.ServerEnv == "" {
+			continue
+		}
+		if _, ok := inputs[i.ID]; ok {
+			if p.Config.ServerInputOverride != ServerInputIgnore {
+				return fmt.Errorf("input %s is set by the server and can not be provided", i.ID)
+			}
+			delete(inputs, i.ID)
+		}
+
+		val, ok := os.LookupEnv(i.ServerEnv)
+		if !ok {
+			continue
+		}
+		inputs[i.ID] = parseServerInput(val)
+	}
+	return nil
+}
+
+// Numbers are decoded as json.Number, same as inputs of execution requests
+func parseServerInput(val string) interface{} {
+	dec := json.NewDecoder(strings.NewReader(val))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return val
+	}
+	return v
+}
+
+// Inputs clients can provide, server inputs are left out
+func (p Process) clientInputs() []Inputs {
+	inputs := make([]Inputs, 0, len(p.Inputs))
+	for _, i := range p.Inputs {
+		if i.ServerEnv == "" {
+			inputs = append(inputs, i)
+		}
+	}
+	return inputs
+}
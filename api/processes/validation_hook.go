@@ -0,0 +1,101 @@
+package processes
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Seconds to wait for a response of a validation hook when its timeout is not set
+const defaultValidationHookTimeout = 10
+
+// Maximum bytes of a validation hook response read for the rejection detail
+const maxValidationHookResponse = 4096
+
+// ValidationHook is an external service that validates inputs before a job is created, for checks across inputs
+// that can not be expressed by input definitions. The hook receives a POST with a JSON body of processID and inputs,
+// a non 2xx response rejects the execution.
+type ValidationHook struct {
+	URL string `yaml:"url" json:"url"`
+	// Seconds to wait for a response, nil means default of 10 seconds
+	Timeout *int `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// InputsRejectedError is returned when the validation hook responds with a non 2xx status.
+// Detail is the `detail` or `message` field of a JSON response, or the response body otherwise.
+type InputsRejectedError struct {
+	Detail string
+}
+
+func (e *InputsRejectedError) Error() string {
+	return "inputs rejected by validation hook: " + e.Detail
+}
+
+func (vh ValidationHook) validate() error {
+	u, err := url.Parse(vh.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL, got '%s'", vh.URL)
+	}
+	if vh.Timeout != nil && *vh.Timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	return nil
+}
+
+// CallValidationHook sends inputs of a job to the validation hook of the process.
+// Returns *InputsRejectedError if the hook rejects the inputs, or another error if the hook could not be called.
+// Returns nil if the process has no validation hook.
+func (p Process) CallValidationHook(inputs map[string]interface{}) error {
+	if p.ValidationHook == nil {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"processID": p.Info.ID,
+		"inputs":    inputs,
+	})
+	if err != nil {
+		return err
+	}
+
+	timeout := defaultValidationHookTimeout
+	if p.ValidationHook.Timeout != nil {
+		timeout = *p.ValidationHook.Timeout
+	}
+	client := http.Client{Timeout: time.Duration(timeout) * time.Second}
+
+	resp, err := client.Post(p.ValidationHook.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("could not call validation hook: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, maxValidationHookResponse))
+	detail := strings.TrimSpace(string(b))
+
+	var doc struct {
+		Message string `json:"message"`
+		Detail  string `json:"detail"`
+	}
+	if json.Unmarshal(b, &doc) == nil {
+		if doc.Detail != "" {
+			detail = doc.Detail
+		} else if doc.Message != "" {
+			detail = doc.Message
+		}
+	}
+	if detail == "" {
+		detail = resp.Status
+	}
+	return &InputsRejectedError{Detail: detail}
+}
@@ -0,0 +1,169 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+type memoryObject struct {
+	data        []byte
+	contentType string
+}
+
+// memoryStore serves S3 requests from memory instead of sending them over the network
+type memoryStore struct {
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+// NewMemoryS3 returns an S3 client backed by memory, objects are lost on restart.
+// Only PutObject, HeadObject and GetObject (with single byte ranges) are supported, these are the operations used by storage helpers.
+// Intended for testing and demos where no storage service is available.
+func NewMemoryS3() (*s3.S3, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Region:           aws.String("us-east-1"),
+		Endpoint:         aws.String("http://memory"),
+		Credentials:      credentials.AnonymousCredentials,
+		S3ForcePathStyle: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	store := &memoryStore{objects: make(map[string]memoryObject)}
+	svc := s3.New(sess)
+	svc.Handlers.Send.Clear()
+	svc.Handlers.Send.PushBack(store.send)
+	return svc, nil
+}
+
+func (ms *memoryStore) send(r *request.Request) {
+	switch in := r.Params.(type) {
+	case *s3.PutObjectInput:
+		var data []byte
+		if in.Body != nil {
+			if _, err := in.Body.Seek(0, io.SeekStart); err != nil {
+				r.Error = err
+				return
+			}
+			b, err := io.ReadAll(in.Body)
+			if err != nil {
+				r.Error = err
+				return
+			}
+			data = b
+		}
+
+		ms.mu.Lock()
+		ms.objects[aws.StringValue(in.Bucket)+"/"+aws.StringValue(in.Key)] = memoryObject{data: data, contentType: aws.StringValue(in.ContentType)}
+		ms.mu.Unlock()
+		r.HTTPResponse = memoryResponse(http.StatusOK, nil, "")
+
+	case *s3.HeadObjectInput:
+		obj, ok := ms.get(aws.StringValue(in.Bucket) + "/" + aws.StringValue(in.Key))
+		if !ok {
+			r.HTTPResponse = memoryResponse(http.StatusNotFound, nil, "")
+			return
+		}
+		resp := memoryResponse(http.StatusOK, nil, obj.contentType)
+		resp.Header.Set("Content-Length", strconv.Itoa(len(obj.data)))
+		r.HTTPResponse = resp
+
+	case *s3.GetObjectInput:
+		obj, ok := ms.get(aws.StringValue(in.Bucket) + "/" + aws.StringValue(in.Key))
+		if !ok {
+			body := []byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`)
+			r.HTTPResponse = memoryResponse(http.StatusNotFound, body, "application/xml")
+			return
+		}
+		if in.Range == nil {
+			r.HTTPResponse = memoryResponse(http.StatusOK, obj.data, obj.contentType)
+			return
+		}
+		start, end, ok := parseByteRange(aws.StringValue(in.Range), len(obj.data))
+		if !ok {
+			body := []byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>InvalidRange</Code><Message>The requested range is not satisfiable</Message></Error>`)
+			r.HTTPResponse = memoryResponse(http.StatusRequestedRangeNotSatisfiable, body, "application/xml")
+			return
+		}
+		resp := memoryResponse(http.StatusPartialContent, obj.data[start:end+1], obj.contentType)
+		resp.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(obj.data)))
+		r.HTTPResponse = resp
+
+	default:
+		r.Error = awserr.New("NotImplemented", r.Operation.Name+" is not supported by in-memory storage", nil)
+	}
+}
+
+func (ms *memoryStore) get(key string) (memoryObject, bool) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	obj, ok := ms.objects[key]
+	return obj, ok
+}
+
+// Parse a single byte range "bytes=start-end", "bytes=start-" or "bytes=-suffix" into inclusive offsets
+func parseByteRange(byteRange string, size int) (int, int, bool) {
+	spec := strings.TrimPrefix(byteRange, "bytes=")
+	if spec == byteRange || size == 0 {
+		return 0, 0, false
+	}
+	first, last, found := strings.Cut(spec, "-")
+	if !found {
+		return 0, 0, false
+	}
+
+	if first == "" {
+		n, err := strconv.Atoi(last)
+		if err != nil || n <= 0 {
+			return 0, 0, false
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, true
+	}
+
+	start, err := strconv.Atoi(first)
+	if err != nil || start >= size {
+		return 0, 0, false
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.Atoi(last)
+		if err != nil || end < start {
+			return 0, 0, false
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return start, end, true
+}
+
+func memoryResponse(status int, body []byte, contentType string) *http.Response {
+	header := http.Header{}
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	return &http.Response{
+		StatusCode:    status,
+		Status:        http.StatusText(status),
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+}
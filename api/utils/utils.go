@@ -3,7 +3,10 @@ package utils
 import (
 	"bufio"
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"time"
@@ -13,11 +16,35 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3"
 )
 
+// ErrStorageUnconfigured is returned by storage operations when no storage service is configured
+var ErrStorageUnconfigured = errors.New("storage service is not configured, set STORAGE_SERVICE to enable it")
+
 // Given bytes and an S3 location write a file on S3 with expiration policy
 // 0 value for expDays means no expiry
 // If failure occurs append error message to the logs stream
 // This function does not panic to safeguard server
 func WriteToS3(svc *s3.S3, b []byte, key string, contType string, expDays int) error {
+	return WriteEncodedToS3(svc, b, key, contType, "", expDays)
+}
+
+// Gzip compresses b, GetS3JsonData and GetS3LinesData decompress such objects transparently
+func Gzip(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteEncodedToS3 writes b as is with the given Content-Encoding, empty contEncoding sets none
+func WriteEncodedToS3(svc *s3.S3, b []byte, key, contType, contEncoding string, expDays int) error {
+	if svc == nil {
+		return ErrStorageUnconfigured
+	}
 
 	var expirationDate *time.Time
 	if expDays != 0 {
@@ -25,13 +52,19 @@ func WriteToS3(svc *s3.S3, b []byte, key string, contType string, expDays int) e
 		expirationDate = &expDate
 	}
 
+	var encoding *string
+	if contEncoding != "" {
+		encoding = aws.String(contEncoding)
+	}
+
 	// Upload the data to S3
 	_, err := svc.PutObject(&s3.PutObjectInput{
-		Bucket:      aws.String(os.Getenv("STORAGE_BUCKET")),
-		Key:         aws.String(key),
-		Body:        bytes.NewReader(b),
-		Expires:     expirationDate,
-		ContentType: &contType,
+		Bucket:          aws.String(os.Getenv("STORAGE_BUCKET")),
+		Key:             aws.String(key),
+		Body:            bytes.NewReader(b),
+		Expires:         expirationDate,
+		ContentType:     &contType,
+		ContentEncoding: encoding,
 	})
 
 	if err != nil {
@@ -44,6 +77,9 @@ func WriteToS3(svc *s3.S3, b []byte, key string, contType string, expDays int) e
 
 // Check if an S3 Key exists
 func KeyExists(key string, svc *s3.S3) (bool, error) {
+	if svc == nil {
+		return false, ErrStorageUnconfigured
+	}
 	_, err := svc.HeadObject(&s3.HeadObjectInput{
 		Bucket: aws.String(os.Getenv("STORAGE_BUCKET")),
 		Key:    aws.String(key),
@@ -76,6 +112,9 @@ func StringInSlice(a string, list []string) bool {
 
 // Assumes file exist
 func GetS3JsonData(key string, svc *s3.S3) (interface{}, error) {
+	if svc == nil {
+		return nil, ErrStorageUnconfigured
+	}
 	// Create a new S3GetObjectInput object to specify the file you want to read
 	params := &s3.GetObjectInput{
 		Bucket: aws.String(os.Getenv("STORAGE_BUCKET")),
@@ -89,8 +128,13 @@ func GetS3JsonData(key string, svc *s3.S3) (interface{}, error) {
 	}
 	defer resp.Body.Close()
 
+	body, err := decodedBody(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
 	// Read the file contents into a byte slice
-	jsonBytes, err := io.ReadAll(resp.Body)
+	jsonBytes, err := io.ReadAll(body)
 	if err != nil {
 		return nil, err
 	}
@@ -109,6 +153,9 @@ func GetS3JsonData(key string, svc *s3.S3) (interface{}, error) {
 
 // Assumes file exist
 func GetS3LinesData(key string, svc *s3.S3) ([]string, error) {
+	if svc == nil {
+		return nil, ErrStorageUnconfigured
+	}
 	// Create a new S3GetObjectInput object to specify the file you want to read
 	params := &s3.GetObjectInput{
 		Bucket: aws.String(os.Getenv("STORAGE_BUCKET")),
@@ -122,8 +169,13 @@ func GetS3LinesData(key string, svc *s3.S3) ([]string, error) {
 	}
 	defer resp.Body.Close()
 
+	body, err := decodedBody(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
 	var lines []string
-	scanner := bufio.NewScanner(resp.Body)
+	scanner := bufio.NewScanner(body)
 	for scanner.Scan() {
 		lines = append(lines, scanner.Text())
 	}
@@ -134,3 +186,80 @@ func GetS3LinesData(key string, svc *s3.S3) ([]string, error) {
 
 	return lines, nil
 }
+
+// Reader of an object body that decompresses gzip content.
+// Content is detected by its magic bytes since the HTTP client may already have decoded it based on Content-Encoding.
+func decodedBody(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+		// short or empty bodies are returned as is
+		return br, nil
+	}
+	return gzip.NewReader(br)
+}
+
+// Get an object as a stream, byteRange is an HTTP Range header value such as "bytes=0-1023", empty string gets the whole object.
+// Caller must close the body of the output
+func GetS3Object(key, byteRange string, svc *s3.S3) (*s3.GetObjectOutput, error) {
+	if svc == nil {
+		return nil, ErrStorageUnconfigured
+	}
+	params := &s3.GetObjectInput{
+		Bucket: aws.String(os.Getenv("STORAGE_BUCKET")),
+		Key:    aws.String(key),
+	}
+	if byteRange != "" {
+		params.Range = aws.String(byteRange)
+	}
+
+	return svc.GetObject(params)
+}
+
+// List keys of all objects under prefix
+func ListS3Keys(prefix string, svc *s3.S3) ([]string, error) {
+	if svc == nil {
+		return nil, ErrStorageUnconfigured
+	}
+	var keys []string
+	err := svc.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(os.Getenv("STORAGE_BUCKET")),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			keys = append(keys, aws.StringValue(obj.Key))
+		}
+		return true
+	})
+	return keys, err
+}
+
+// Delete objects, in batches of 1000 which is the maximum allowed per request
+func DeleteS3Keys(keys []string, svc *s3.S3) error {
+	if svc == nil {
+		return ErrStorageUnconfigured
+	}
+	for start := 0; start < len(keys); start += 1000 {
+		end := start + 1000
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		objects := make([]*s3.ObjectIdentifier, 0, end-start)
+		for _, k := range keys[start:end] {
+			objects = append(objects, &s3.ObjectIdentifier{Key: aws.String(k)})
+		}
+
+		out, err := svc.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: aws.String(os.Getenv("STORAGE_BUCKET")),
+			Delete: &s3.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return err
+		}
+		if len(out.Errors) > 0 {
+			return fmt.Errorf("could not delete %d objects, first error: %s %s", len(out.Errors), aws.StringValue(out.Errors[0].Key), aws.StringValue(out.Errors[0].Message))
+		}
+	}
+	return nil
+}